@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// APIKeyHeaders headers detected as `auth:apikey`
+var APIKeyHeaders = []string{"X-Api-Key", "Api-Key", "Apikey", "X-Api-Token", "X-Auth-Token"}
+
+// RequestAuth auth detected from the request headers
+type RequestAuth struct {
+	// Mode value of `auth:` in the method block:
+	// bearer, basic, digest, apikey or inherit
+	Mode string
+	// Header carrying credentials, omitted from the headers block
+	Header string
+	// Values entries of the `auth:<mode>` block
+	Values Pairs
+	// Vars env variables which must be created for Values
+	Vars Pairs
+}
+
+var digestParamRe = regexp.MustCompile(`(\w+)=("([^"]*)"|[^,\s]*)`)
+
+// DetectAuth detects bearer, basic, digest or api key auth of the request.
+// Secrets are replaced with env variables, missing variables are returned
+// in Vars. If the folder or collection defines identical auth,
+// the mode is inherit. Returns nil if the request has no supported auth
+// or the auth header is inherited from collection.bru/folder.bru headers.
+func DetectAuth(rd RequestData, inherited *RequestAuth) *RequestAuth {
+	if rd.HTTPReq == nil {
+		return nil
+	}
+
+	auth := detectAuthHeader(rd)
+	if auth == nil {
+		return nil
+	}
+
+	if inherited != nil && inherited.Mode == auth.Mode && authValuesEqual(inherited.Values, auth.Values) {
+		return &RequestAuth{Mode: "inherit", Header: auth.Header}
+	}
+	return auth
+}
+
+func detectAuthHeader(rd RequestData) *RequestAuth {
+	header := rd.HTTPReq.Header.Get("Authorization")
+	if header != "" {
+		if _, ok := rd.InheritedHeaders["Authorization"]; ok {
+			return nil
+		}
+		scheme, value, _ := strings.Cut(strings.TrimSpace(header), " ")
+		value = strings.TrimSpace(value)
+
+		switch strings.ToLower(scheme) {
+		case "bearer":
+			auth := &RequestAuth{Mode: "bearer", Header: "Authorization"}
+			auth.Values.Add("token", authSecretVar(auth, rd.Env, "token", value))
+			return auth
+		case "basic":
+			decoded, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return nil
+			}
+			username, password, _ := strings.Cut(string(decoded), ":")
+			auth := &RequestAuth{Mode: "basic", Header: "Authorization"}
+			auth.Values.Add("username", EnvToBody(username, rd.Env))
+			auth.Values.Add("password", authSecretVar(auth, rd.Env, "password", password))
+			return auth
+		case "digest":
+			username := ""
+			for _, m := range digestParamRe.FindAllStringSubmatch(value, -1) {
+				if m[1] == "username" {
+					username = m[3]
+				}
+			}
+			// password can't be restored from digest response
+			auth := &RequestAuth{Mode: "digest", Header: "Authorization"}
+			auth.Values.Add("username", EnvToBody(username, rd.Env))
+			auth.Values.Add("password", authSecretVar(auth, rd.Env, "password", ""))
+			return auth
+		}
+		return nil
+	}
+
+	for _, name := range APIKeyHeaders {
+		value := rd.HTTPReq.Header.Get(name)
+		if value == "" {
+			continue
+		}
+		if _, ok := rd.InheritedHeaders[http.CanonicalHeaderKey(name)]; ok {
+			return nil
+		}
+		key := headerSourceName(rd, name)
+		auth := &RequestAuth{Mode: "apikey", Header: http.CanonicalHeaderKey(name)}
+		auth.Values.Add("key", key)
+		auth.Values.Add("value", authSecretVar(auth, rd.Env, "api_key", value))
+		auth.Values.Add("placement", "header")
+		return auth
+	}
+
+	return nil
+}
+
+// headerSourceName returns the header name as written in the request
+func headerSourceName(rd RequestData, name string) string {
+	for _, h := range rd.HeaderOrder {
+		if strings.EqualFold(h, name) {
+			return h
+		}
+	}
+	return http.CanonicalHeaderKey(name)
+}
+
+// authSecretVar returns {{var}} placeholder for the secret.
+// Existing env variable with the same value is reused, otherwise
+// a new variable named base (base_2, base_3... if taken) is added to auth.Vars.
+// Without env or if the secret is {{var}} reference it's returned as is.
+func authSecretVar(auth *RequestAuth, env *BrunoEnv, base, secret string) string {
+	if env == nil || pathVarRe.MatchString(secret) {
+		return secret
+	}
+	if secret != "" {
+		if name, ok := env.ReverseVars[secret]; ok {
+			return "{{" + name + "}}"
+		}
+	}
+	if secret == "" {
+		if _, ok := env.Vars[base]; ok {
+			return "{{" + base + "}}"
+		}
+	}
+
+	name := uniqueVarName(env, auth.Vars, base)
+	auth.Vars.Add(name, secret)
+	return "{{" + name + "}}"
+}
+
+func authValuesEqual(a, b Pairs) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, pair := range a {
+		if value, ok := b.Get(pair.Key); !ok || value != pair.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// InheritedAuth returns auth defined in the nearest folder.bru from dir up
+// to basedir or in basedir/collection.bru. Folders with `mode: inherit`
+// are skipped. Returns nil if no auth is defined.
+func InheritedAuth(basedir, dir string) *RequestAuth {
+	if basedir == "" {
+		basedir = "."
+	}
+
+	files := []string{filepath.Join(basedir, "collection.bru")}
+	rel, err := filepath.Rel(basedir, dir)
+	if err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+		current := basedir
+		for _, part := range strings.Split(rel, string(filepath.Separator)) {
+			current = filepath.Join(current, part)
+			files = append(files, filepath.Join(current, "folder.bru"))
+		}
+	}
+
+	for i := len(files) - 1; i >= 0; i-- {
+		doc, err := BruFromFile(files[i])
+		if err != nil {
+			continue
+		}
+		block := doc.Block("auth")
+		if block == nil {
+			continue
+		}
+		mode, _ := block.Get("mode")
+		if mode == "" || mode == "inherit" {
+			continue
+		}
+		auth := &RequestAuth{Mode: mode}
+		if values := doc.Block("auth:" + mode); values != nil {
+			auth.Values = values.Pairs()
+		}
+		return auth
+	}
+
+	return nil
+}
+
+// AuthGenerate returns `auth:<mode>` block,
+// empty for none and inherit modes
+func AuthGenerate(auth *RequestAuth) string {
+	if auth == nil || auth.Mode == "inherit" || auth.Mode == "none" {
+		return ""
+	}
+	return NameBlockMap("auth:"+auth.Mode, auth.Values)
+}
+
+// SetAuthBlocks sets `auth` mode and `auth:<mode>` blocks
+// of collection.bru or folder.bru
+func SetAuthBlocks(doc *BruDoc, auth *RequestAuth) {
+	for _, block := range doc.BlocksWithPrefix("auth:") {
+		doc.RemoveBlock(block.Name)
+	}
+	mode := NewBruDict("auth", nil)
+	mode.Add("mode", auth.Mode)
+	doc.SetBlock(mode)
+	if len(auth.Values) > 0 {
+		doc.SetBlock(NewBruDict("auth:"+auth.Mode, auth.Values))
+	}
+}
+
+// createAuthVars adds missing auth variables to the env file,
+// the loaded env already has them
+func createAuthVars(rd RequestData) {
+	if rd.Auth == nil || len(rd.Auth.Vars) == 0 || rd.Env == nil || rd.EnvFile == "" {
+		return
+	}
+	if err := EnvSetVars(rd.EnvFile, rd.Auth.Vars); err != nil {
+		fmt.Fprintf(os.Stderr, "[W] write auth vars to env file: %s\n", err)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDetectAuth(t *testing.T) {
+	newEnv := func() *BrunoEnv {
+		return &BrunoEnv{
+			Vars:        map[string]string{"host": "a.com", "token": "old", "user": "bob"},
+			ReverseVars: map[string]string{"a.com": "host", "old": "token", "bob": "user"},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		header    http.Header
+		order     []string
+		inherited map[string]string
+		parent    *RequestAuth
+		env       *BrunoEnv
+		expected  *RequestAuth
+	}{
+		{
+			name:     "no auth",
+			header:   http.Header{"Accept": {"*/*"}},
+			env:      newEnv(),
+			expected: nil,
+		},
+		{
+			name:   "bearer with new token",
+			header: http.Header{"Authorization": {"Bearer abc.def"}},
+			env:    newEnv(),
+			expected: &RequestAuth{
+				Mode:   "bearer",
+				Header: "Authorization",
+				Values: Pairs{{"token", "{{token_2}}"}},
+				Vars:   Pairs{{"token_2", "abc.def"}},
+			},
+		},
+		{
+			name:   "bearer with known token",
+			header: http.Header{"Authorization": {"bearer old"}},
+			env:    newEnv(),
+			expected: &RequestAuth{
+				Mode:   "bearer",
+				Header: "Authorization",
+				Values: Pairs{{"token", "{{token}}"}},
+			},
+		},
+		{
+			name:   "basic",
+			header: http.Header{"Authorization": {"Basic Ym9iOnNlY3JldA=="}},
+			env:    newEnv(),
+			expected: &RequestAuth{
+				Mode:   "basic",
+				Header: "Authorization",
+				Values: Pairs{{"username", "{{user}}"}, {"password", "{{password}}"}},
+				Vars:   Pairs{{"password", "secret"}},
+			},
+		},
+		{
+			name:     "invalid basic is kept as header",
+			header:   http.Header{"Authorization": {"Basic !!!"}},
+			env:      newEnv(),
+			expected: nil,
+		},
+		{
+			name:   "digest",
+			header: http.Header{"Authorization": {`Digest username="alice", realm="api", nonce="x", response="y"`}},
+			env:    newEnv(),
+			expected: &RequestAuth{
+				Mode:   "digest",
+				Header: "Authorization",
+				Values: Pairs{{"username", "alice"}, {"password", "{{password}}"}},
+				Vars:   Pairs{{"password", ""}},
+			},
+		},
+		{
+			name:   "api key header",
+			header: http.Header{"X-Api-Key": {"k123"}},
+			order:  []string{"X-API-KEY"},
+			env:    newEnv(),
+			expected: &RequestAuth{
+				Mode:   "apikey",
+				Header: "X-Api-Key",
+				Values: Pairs{{"key", "X-API-KEY"}, {"value", "{{api_key}}"}, {"placement", "header"}},
+				Vars:   Pairs{{"api_key", "k123"}},
+			},
+		},
+		{
+			name:     "unknown scheme is kept as header",
+			header:   http.Header{"Authorization": {"AWS4-HMAC-SHA256 Credential=x"}},
+			env:      newEnv(),
+			expected: nil,
+		},
+		{
+			name:      "authorization inherited as header",
+			header:    http.Header{"Authorization": {"Bearer abc"}},
+			inherited: map[string]string{"Authorization": "Bearer {{token}}"},
+			env:       newEnv(),
+			expected:  nil,
+		},
+		{
+			name:     "identical parent auth is inherited",
+			header:   http.Header{"Authorization": {"Bearer old"}},
+			parent:   &RequestAuth{Mode: "bearer", Values: Pairs{{"token", "{{token}}"}}},
+			env:      newEnv(),
+			expected: &RequestAuth{Mode: "inherit", Header: "Authorization"},
+		},
+		{
+			name:   "different parent auth is not inherited",
+			header: http.Header{"Authorization": {"Bearer old"}},
+			parent: &RequestAuth{Mode: "basic", Values: Pairs{{"username", "x"}, {"password", "y"}}},
+			env:    newEnv(),
+			expected: &RequestAuth{
+				Mode:   "bearer",
+				Header: "Authorization",
+				Values: Pairs{{"token", "{{token}}"}},
+			},
+		},
+		{
+			name:   "without env secret is kept",
+			header: http.Header{"Authorization": {"Bearer abc"}},
+			expected: &RequestAuth{
+				Mode:   "bearer",
+				Header: "Authorization",
+				Values: Pairs{{"token", "abc"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rd := RequestData{
+				HTTPReq:          &http.Request{Header: tt.header},
+				HeaderOrder:      tt.order,
+				InheritedHeaders: tt.inherited,
+				Env:              tt.env,
+			}
+			got := DetectAuth(rd, tt.parent)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("DetectAuth() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestInheritedAuth(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "api", "v1"), 0o755)
+	os.WriteFile(filepath.Join(tmpDir, "collection.bru"), []byte("auth {\n  mode: bearer\n}\n\nauth:bearer {\n  token: {{token}}\n}\n"), 0o644)
+	os.WriteFile(filepath.Join(tmpDir, "api", "folder.bru"), []byte("meta {\n  name: api\n}\n\nauth {\n  mode: basic\n}\n\nauth:basic {\n  username: u\n  password: {{password}}\n}\n"), 0o644)
+	os.WriteFile(filepath.Join(tmpDir, "api", "v1", "folder.bru"), []byte("auth {\n  mode: inherit\n}\n"), 0o644)
+
+	tests := []struct {
+		name     string
+		dir      string
+		expected *RequestAuth
+	}{
+		{"collection auth", tmpDir, &RequestAuth{Mode: "bearer", Values: Pairs{{"token", "{{token}}"}}}},
+		{"folder auth", filepath.Join(tmpDir, "api"), &RequestAuth{Mode: "basic", Values: Pairs{{"username", "u"}, {"password", "{{password}}"}}}},
+		{"inherit folder is skipped", filepath.Join(tmpDir, "api", "v1"), &RequestAuth{Mode: "basic", Values: Pairs{{"username", "u"}, {"password", "{{password}}"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := InheritedAuth(tmpDir, tt.dir)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("InheritedAuth() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+
+	if got := InheritedAuth(t.TempDir(), "."); got != nil {
+		t.Errorf("InheritedAuth() without auth = %+v, want nil", got)
+	}
+}
+
+func TestAuthGenerate(t *testing.T) {
+	if got := AuthGenerate(nil); got != "" {
+		t.Errorf("AuthGenerate(nil) = %q", got)
+	}
+	if got := AuthGenerate(&RequestAuth{Mode: "inherit"}); got != "" {
+		t.Errorf("AuthGenerate(inherit) = %q", got)
+	}
+	got := AuthGenerate(&RequestAuth{Mode: "bearer", Values: Pairs{{"token", "{{token}}"}}})
+	if got != "auth:bearer {\n  token: {{token}}\n}\n" {
+		t.Errorf("AuthGenerate(bearer) = %q", got)
+	}
+}
+
+func TestDoRequestAuthCreatesEnvVar(t *testing.T) {
+	tmpDir := t.TempDir()
+	basedir := filepath.Join(tmpDir, "api.example.com")
+	os.MkdirAll(filepath.Join(basedir, "environments"), 0o755)
+	os.WriteFile(filepath.Join(basedir, "bruno.json"), []byte(DefaultBrunoJSON("api.example.com")), 0o644)
+	os.WriteFile(filepath.Join(basedir, "environments", "base.bru"), []byte(DefaultEnvBru("api.example.com")), 0o644)
+
+	req, order, err := ParseRequest([]byte("GET /me HTTP/1.1\r\nHost: api.example.com\r\nAuthorization: Bearer tok-123\r\nAccept: */*\r\n\r\n"))
+	if err != nil {
+		t.Fatalf("ParseRequest() error = %v", err)
+	}
+	fp, err := ConvertRequest(req, order, basedir, "environments/base.bru", RequestOptions{})
+	if err != nil {
+		t.Fatalf("ConvertRequest() error = %v", err)
+	}
+
+	data, _ := os.ReadFile(fp)
+	content := string(data)
+	for _, want := range []string{"auth: bearer", "auth:bearer {\n  token: {{token}}\n}"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("request file should contain %q\ngot:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "tok-123") || strings.Contains(content, "Authorization:") {
+		t.Errorf("request file should not contain the secret header\ngot:\n%s", content)
+	}
+
+	env, err := EnvFromFile(filepath.Join(basedir, "environments", "base.bru"))
+	if err != nil {
+		t.Fatalf("EnvFromFile() error = %v", err)
+	}
+	if env.Vars["token"] != "tok-123" {
+		t.Errorf("env token = %q, want tok-123", env.Vars["token"])
+	}
+	if env.Vars["host"] != "api.example.com" {
+		t.Errorf("env host should be kept, got %q", env.Vars["host"])
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// textMediaTypes non text/* media types of text bodies
+var textMediaTypes = []string{
+	"application/javascript",
+	"application/x-javascript",
+	"application/ecmascript",
+	"application/graphql",
+	"application/sql",
+	"application/yaml",
+	"application/x-yaml",
+	"application/x-ndjson",
+	"application/jsonl",
+	"application/csp-report",
+}
+
+// DetectBodyType returns the body type of the Content-Type header.
+// The body is sniffed if the header is missing, invalid or doesn't
+// match the content: JSON sent as text/plain is json, text sent as
+// octet-stream is text and so on. Binary content is file.
+func DetectBodyType(ct, body string) string {
+	if body == "" {
+		return "none"
+	}
+
+	bt, err := BodyTypeFromContentType(ct)
+	switch {
+	case err != nil || bt == "none":
+		return sniffBodyType(body)
+	case bt == "multipartForm" || bt == "formUrlEncoded":
+		return bt
+	case bt == "json" && !json.Valid([]byte(body)):
+		return sniffBodyType(body)
+	case bt == "xml" && !isXMLBody(body):
+		return sniffBodyType(body)
+	case bt == "text" && isBinaryBody(body):
+		return "file"
+	case bt == "text" && isJSONBody(body):
+		// fetch() and sendBeacon() post JSON strings as text/plain
+		// to skip CORS preflight
+		if mediaType, _, _ := mime.ParseMediaType(ct); mediaType == "text/plain" {
+			return "json"
+		}
+	case bt == "file" && !isBinaryBody(body):
+		return sniffBodyType(body)
+	}
+	return bt
+}
+
+// sniffBodyType returns the body type of the content:
+// JSON objects and arrays are json, XML documents are xml,
+// other text is text and binary content is file
+func sniffBodyType(body string) string {
+	switch {
+	case isBinaryBody(body):
+		return "file"
+	case isJSONBody(body):
+		return "json"
+	case isXMLBody(body):
+		return "xml"
+	default:
+		return "text"
+	}
+}
+
+// isJSONBody reports whether the body is a JSON object or array
+func isJSONBody(body string) bool {
+	trimmed := strings.TrimSpace(body)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return false
+	}
+	return json.Valid([]byte(trimmed))
+}
+
+// isXMLBody reports whether the body is an XML document, HTML is not
+func isXMLBody(body string) bool {
+	ct := http.DetectContentType([]byte(body))
+	return strings.HasPrefix(ct, "text/xml")
+}
+
+// isBinaryBody reports whether the body isn't UTF-8 text
+// or contains control characters other than whitespace
+func isBinaryBody(body string) bool {
+	if !utf8.ValidString(body) {
+		return true
+	}
+	for _, r := range body {
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' && r != '\f' {
+			return true
+		}
+	}
+	return false
+}
+
+// bodyFile returns the binary body as file part of `body:file`.
+// The file is named after the request, the extension is taken
+// from the request path or the media type.
+func bodyFile(rd RequestData) FormPart {
+	var mediaType string
+	if rd.HTTPReq != nil {
+		mediaType, _, _ = mime.ParseMediaType(rd.HTTPReq.Header.Get("Content-Type"))
+	}
+	if mediaType == "" {
+		mediaType = "application/octet-stream"
+	}
+
+	ext := path.Ext(rd.Path)
+	if ext == "" && mediaType != "application/octet-stream" {
+		if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+	if ext == "" {
+		ext = ".bin"
+	}
+
+	return FormPart{
+		Name:        "file",
+		Value:       rd.Body,
+		FileName:    rd.Name + ext,
+		ContentType: mediaType,
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectBodyType(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{"empty body", "application/json", "", "none"},
+		{"json", "application/json", `{"a":1}`, "json"},
+		{"vnd.api+json", "application/vnd.api+json", `{"data":{}}`, "json"},
+		{"soap+xml", "application/soap+xml", `<?xml version="1.0"?><Envelope/>`, "xml"},
+		{"csv", "text/csv", "a,b\n1,2\n", "text"},
+		{"json as text/plain", "text/plain;charset=UTF-8", `{"event":"click"}`, "json"},
+		{"text/plain", "text/plain", "hello", "text"},
+		{"json in text/html", "text/html", `{"a":1}`, "text"},
+		{"missing header json", "", `[1, 2]`, "json"},
+		{"missing header xml", "", `<?xml version="1.0"?><a/>`, "xml"},
+		{"missing header html", "", `<!DOCTYPE html><html></html>`, "text"},
+		{"missing header text", "", "hello", "text"},
+		{"missing header binary", "", "\x89PNG\r\n\x1a\n\x00", "file"},
+		{"invalid header", "invalid/;/type", `{"a":1}`, "json"},
+		{"invalid json", "application/json", "a=1&b=2", "text"},
+		{"invalid xml", "application/xml", `{"a":1}`, "json"},
+		{"octet-stream binary", "application/octet-stream", "\x00\x01\x02", "file"},
+		{"octet-stream json", "application/octet-stream", `{"a":1}`, "json"},
+		{"binary text/plain", "text/plain", "\xff\xfe\x00", "file"},
+		{"form", "application/x-www-form-urlencoded", "a=1", "formUrlEncoded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectBodyType(tt.contentType, tt.body); got != tt.want {
+				t.Errorf("DetectBodyType(%q, %q) = %q, want %q", tt.contentType, tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertRequestBodyFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := DoCollection("api.example.com", tmpDir); err != nil {
+		t.Fatalf("DoCollection() error = %v", err)
+	}
+
+	body := "\x00\x01binary\xff"
+	raw := fmt.Sprintf("PUT /blobs HTTP/1.1\r\nHost: api.example.com\r\nContent-Type: application/octet-stream\r\nContent-Length: %d\r\n\r\n%s", len(body), body)
+	req, order, err := ParseRequest([]byte(raw))
+	if err != nil {
+		t.Fatalf("ParseRequest() error = %v", err)
+	}
+	fp, err := ConvertRequest(req, order, tmpDir, "environments/base.bru", RequestOptions{Scheme: "https"})
+	if err != nil {
+		t.Fatalf("ConvertRequest() error = %v", err)
+	}
+
+	collDir := filepath.Join(tmpDir, "api.example.com")
+	data, _ := os.ReadFile(fp)
+	for _, want := range []string{
+		"body: file",
+		"body:file {\n  file: @file(files/blobs-PUT.bin) @contentType(application/octet-stream)\n}",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("request file should contain %q\ngot:\n%s", want, data)
+		}
+	}
+	saved, err := os.ReadFile(filepath.Join(collDir, "files", "blobs-PUT.bin"))
+	if err != nil || string(saved) != body {
+		t.Errorf("saved body = %q, %v", saved, err)
+	}
+
+	var buf bytes.Buffer
+	if err := DoExport(fp, "curl", "environments/base.bru", &buf); err != nil {
+		t.Fatalf("DoExport() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "--data-binary '@"+filepath.Join(collDir, "files", "blobs-PUT.bin")+"'") {
+		t.Errorf("curl should send the body file\ngot:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := DoExport(fp, "raw", "environments/base.bru", &buf); err != nil {
+		t.Fatalf("DoExport() error = %v", err)
+	}
+	if !strings.HasSuffix(buf.String(), "\r\n\r\n"+body) {
+		t.Errorf("raw request should end with the body\ngot:\n%q", buf.String())
+	}
+}
@@ -0,0 +1,474 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// BruKind type of the .bru block content
+type BruKind int
+
+const (
+	// BruDict `key: value` entries, e.g. meta, headers, vars
+	BruDict BruKind = iota
+	// BruText free text indented by 2 spaces, e.g. body:json, docs, tests
+	BruText
+	// BruList comma separated items in square brackets, e.g. vars:secret
+	BruList
+)
+
+// BruEntry single entry of a dictionary or list block.
+// Disabled entries are written with `~` prefix.
+type BruEntry struct {
+	Key      string
+	Value    string
+	Disabled bool
+}
+
+// BruBlock named block of the .bru file
+type BruBlock struct {
+	Name    string
+	Kind    BruKind
+	Entries []BruEntry
+	Text    string
+	// Leading text before the block header, usually empty lines
+	Leading string
+
+	// raw original block text, written back while the block is unchanged
+	raw  string
+	orig *BruBlock
+}
+
+// BruDoc parsed .bru file
+type BruDoc struct {
+	Blocks []*BruBlock
+	// Tail text after the last block
+	Tail string
+}
+
+var (
+	bruHeaderRe = regexp.MustCompile(`^([A-Za-z][\w:.\-]*)\s*([{\[])\s*$`)
+
+	// httpMethodBlocks names of the bruno request method blocks
+	httpMethodBlocks = []string{"get", "post", "put", "delete", "patch", "options", "head", "connect", "trace"}
+
+	// bruDictBodies body blocks with key: value content
+	bruDictBodies = map[string]bool{
+		"body:form-urlencoded": true,
+		"body:multipart-form":  true,
+		"body:file":            true,
+	}
+)
+
+// bruTextBlock reports whether the block content is free text
+func bruTextBlock(name string) bool {
+	switch {
+	case name == "docs", name == "tests":
+		return true
+	case strings.HasPrefix(name, "script:"):
+		return true
+	case strings.HasPrefix(name, "body:"):
+		return !bruDictBodies[name]
+	}
+	return false
+}
+
+// NewBruDict creates dictionary block from pairs
+func NewBruDict(name string, pairs Pairs) *BruBlock {
+	b := &BruBlock{Name: name, Kind: BruDict}
+	for _, p := range pairs {
+		b.Add(p.Key, p.Value)
+	}
+	return b
+}
+
+// NewBruText creates text block
+func NewBruText(name, text string) *BruBlock {
+	return &BruBlock{Name: name, Kind: BruText, Text: text}
+}
+
+// BruFromFile reads and parses .bru file
+func BruFromFile(path string) (*BruDoc, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %q file error %w", path, err)
+	}
+	return ParseBru(string(data)), nil
+}
+
+// ParseBru parses .bru file content. Parsing is lenient: text outside
+// of blocks and malformed lines are kept for serialization, but not
+// exposed in the model. String() of the unchanged document returns
+// exactly the parsed content.
+func ParseBru(content string) *BruDoc {
+	doc := &BruDoc{}
+	lines := strings.SplitAfter(content, "\n")
+
+	var pending strings.Builder
+	for i := 0; i < len(lines); i++ {
+		match := bruHeaderRe.FindStringSubmatch(trimEOL(lines[i]))
+		if match == nil {
+			pending.WriteString(lines[i])
+			continue
+		}
+
+		b := &BruBlock{Name: match[1], Kind: BruDict, Leading: pending.String()}
+		pending.Reset()
+		closing := "}"
+		switch {
+		case match[2] == "[":
+			b.Kind = BruList
+			closing = "]"
+		case bruTextBlock(b.Name):
+			b.Kind = BruText
+		}
+
+		start := i
+		var body []string
+		multiline := false
+		for i++; i < len(lines); i++ {
+			line := trimEOL(lines[i])
+			if b.Kind == BruDict {
+				// a closing brace inside a ''' value belongs to the value
+				trimmed := strings.TrimSpace(line)
+				if multiline {
+					multiline = trimmed != "'''"
+					body = append(body, line)
+					continue
+				}
+				_, value, found := strings.Cut(trimmed, ":")
+				multiline = found && strings.TrimSpace(value) == "'''"
+			}
+			if line == closing || (b.Kind != BruText && strings.TrimSpace(line) == closing) {
+				break
+			}
+			body = append(body, line)
+		}
+		end := min(i+1, len(lines))
+
+		switch b.Kind {
+		case BruText:
+			b.Text = parseBruText(body)
+		case BruList:
+			b.Entries = parseBruList(body)
+		default:
+			b.Entries = parseBruDict(body)
+		}
+		b.raw = strings.Join(lines[start:end], "")
+		b.orig = b.snapshot()
+		doc.Blocks = append(doc.Blocks, b)
+	}
+	doc.Tail = pending.String()
+
+	return doc
+}
+
+func trimEOL(line string) string {
+	return strings.TrimRight(line, "\r\n")
+}
+
+func parseBruText(lines []string) string {
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		out = append(out, strings.TrimPrefix(line, "  "))
+	}
+	return strings.Join(out, "\n")
+}
+
+func parseBruDict(lines []string) []BruEntry {
+	var entries []BruEntry
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+
+		entry := BruEntry{}
+		if strings.HasPrefix(line, "~") {
+			entry.Disabled = true
+			line = line[1:]
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		entry.Key = strings.TrimSpace(key)
+		entry.Value = strings.TrimSpace(value)
+
+		// multiline value
+		//   key: '''
+		//     line
+		//   '''
+		if entry.Value == "'''" {
+			var value []string
+			for i++; i < len(lines) && strings.TrimSpace(lines[i]) != "'''"; i++ {
+				value = append(value, strings.TrimPrefix(lines[i], "    "))
+			}
+			entry.Value = strings.Join(value, "\n")
+		}
+
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func parseBruList(lines []string) []BruEntry {
+	var entries []BruEntry
+	for _, line := range lines {
+		item := strings.TrimSuffix(strings.TrimSpace(line), ",")
+		if item == "" {
+			continue
+		}
+		entry := BruEntry{Key: item}
+		if strings.HasPrefix(item, "~") {
+			entry.Key = item[1:]
+			entry.Disabled = true
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// String serializes the document. Unchanged blocks are written
+// as they were parsed, changed and new blocks in canonical format.
+func (d *BruDoc) String() string {
+	var sb strings.Builder
+	for _, b := range d.Blocks {
+		out := sb.String()
+		if out != "" && !strings.HasSuffix(out, "\n") {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(b.Leading)
+		sb.WriteString(b.String())
+	}
+	sb.WriteString(d.Tail)
+	return sb.String()
+}
+
+// WriteFile writes the serialized document to the path
+func (d *BruDoc) WriteFile(path string) error {
+	if err := os.WriteFile(path, []byte(d.String()), 0o644); err != nil {
+		return fmt.Errorf("write %q file error %w", path, err)
+	}
+	return nil
+}
+
+// Block returns the first block with the name or nil
+func (d *BruDoc) Block(name string) *BruBlock {
+	for _, b := range d.Blocks {
+		if b.Name == name {
+			return b
+		}
+	}
+	return nil
+}
+
+// BlocksWithPrefix returns blocks with names starting with the prefix,
+// e.g. "body:" or "auth:"
+func (d *BruDoc) BlocksWithPrefix(prefix string) []*BruBlock {
+	var blocks []*BruBlock
+	for _, b := range d.Blocks {
+		if strings.HasPrefix(b.Name, prefix) {
+			blocks = append(blocks, b)
+		}
+	}
+	return blocks
+}
+
+// SetBlock replaces the block with the same name,
+// or appends it separated by an empty line
+func (d *BruDoc) SetBlock(block *BruBlock) {
+	for i, b := range d.Blocks {
+		if b.Name == block.Name {
+			block.Leading = b.Leading
+			d.Blocks[i] = block
+			return
+		}
+	}
+	d.AddBlock(block)
+}
+
+// AddBlock appends the block separated by an empty line
+func (d *BruDoc) AddBlock(block *BruBlock) {
+	if len(d.Blocks) > 0 && block.Leading == "" {
+		block.Leading = "\n"
+	}
+	d.Blocks = append(d.Blocks, block)
+}
+
+// InsertBlock inserts the block after the last block
+// which goes before it in bruno files (meta, method, params, headers,
+// auth, body, vars, assert, script, tests, settings, docs)
+func (d *BruDoc) InsertBlock(block *BruBlock) {
+	rank := bruBlockRank(block.Name)
+	pos := -1
+	for i, b := range d.Blocks {
+		if bruBlockRank(b.Name) <= rank {
+			pos = i
+		}
+	}
+	if pos < 0 {
+		pos = len(d.Blocks) - 1
+	}
+	if pos == len(d.Blocks)-1 {
+		d.AddBlock(block)
+		return
+	}
+
+	if block.Leading == "" {
+		block.Leading = "\n"
+	}
+	d.Blocks = append(d.Blocks[:pos+1], append([]*BruBlock{block}, d.Blocks[pos+1:]...)...)
+}
+
+func bruBlockRank(name string) int {
+	for _, method := range httpMethodBlocks {
+		if name == method {
+			return 1
+		}
+	}
+	switch {
+	case name == "meta":
+		return 0
+	case name == "params:query":
+		return 2
+	case name == "params:path":
+		return 3
+	case name == "headers":
+		return 4
+	case strings.HasPrefix(name, "auth"):
+		return 5
+	case strings.HasPrefix(name, "body"):
+		return 6
+	case strings.HasPrefix(name, "vars"):
+		return 7
+	case name == "assert":
+		return 8
+	case strings.HasPrefix(name, "script"):
+		return 9
+	case name == "tests":
+		return 10
+	case name == "docs":
+		return 12
+	}
+	return 11
+}
+
+// RemoveBlock removes all blocks with the name
+func (d *BruDoc) RemoveBlock(name string) {
+	blocks := d.Blocks[:0]
+	for _, b := range d.Blocks {
+		if b.Name != name {
+			blocks = append(blocks, b)
+		}
+	}
+	d.Blocks = blocks
+}
+
+// Get returns the value of the first enabled entry with the key
+func (b *BruBlock) Get(key string) (string, bool) {
+	for _, e := range b.Entries {
+		if e.Key == key && !e.Disabled {
+			return e.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set replaces the value of the first entry with the key
+// and enables it, or appends a new entry
+func (b *BruBlock) Set(key, value string) {
+	for i, e := range b.Entries {
+		if e.Key == key {
+			b.Entries[i].Value = value
+			b.Entries[i].Disabled = false
+			return
+		}
+	}
+	b.Add(key, value)
+}
+
+// Add appends the entry to the end
+func (b *BruBlock) Add(key, value string) {
+	b.Entries = append(b.Entries, BruEntry{Key: key, Value: value})
+}
+
+// Pairs returns enabled entries
+func (b *BruBlock) Pairs() Pairs {
+	var pairs Pairs
+	for _, e := range b.Entries {
+		if !e.Disabled {
+			pairs.Add(e.Key, e.Value)
+		}
+	}
+	return pairs
+}
+
+// String serializes the block with trailing new line
+func (b *BruBlock) String() string {
+	if b.raw != "" && reflect.DeepEqual(b.snapshot(), b.orig) {
+		return b.raw
+	}
+
+	var sb strings.Builder
+	switch b.Kind {
+	case BruText:
+		sb.WriteString(b.Name + " {\n")
+		if b.Text != "" {
+			for _, line := range strings.Split(b.Text, "\n") {
+				if line != "" {
+					sb.WriteString("  ")
+				}
+				sb.WriteString(line + "\n")
+			}
+		}
+		sb.WriteString("}\n")
+	case BruList:
+		sb.WriteString(b.Name + " [\n")
+		for i, e := range b.Entries {
+			sb.WriteString("  ")
+			if e.Disabled {
+				sb.WriteString("~")
+			}
+			sb.WriteString(e.Key)
+			if i < len(b.Entries)-1 {
+				sb.WriteString(",")
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString("]\n")
+	default:
+		sb.WriteString(b.Name + " {\n")
+		for _, e := range b.Entries {
+			sb.WriteString("  ")
+			if e.Disabled {
+				sb.WriteString("~")
+			}
+			sb.WriteString(e.Key + ": ")
+			if !strings.Contains(e.Value, "\n") {
+				sb.WriteString(e.Value + "\n")
+				continue
+			}
+			sb.WriteString("'''\n")
+			for _, line := range strings.Split(e.Value, "\n") {
+				sb.WriteString("    " + line + "\n")
+			}
+			sb.WriteString("  '''\n")
+		}
+		sb.WriteString("}\n")
+	}
+	return sb.String()
+}
+
+// snapshot copy of the block content for change detection
+func (b *BruBlock) snapshot() *BruBlock {
+	return &BruBlock{
+		Name:    b.Name,
+		Kind:    b.Kind,
+		Entries: append([]BruEntry(nil), b.Entries...),
+		Text:    b.Text,
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const bruRequestFixture = `meta {
+  name: login
+  type: http
+  seq: 3
+}
+
+post {
+  url: {{proto}}://{{host}}/api/login?x=1
+  body: json
+  auth: none
+}
+
+params:query {
+  x: 1
+  ~debug: true
+}
+
+headers {
+  Content-Type: application/json
+}
+
+body:json {
+  {
+    "user": "{{user}}",
+    "nested": {
+      "a": 1
+    }
+  }
+}
+
+vars:post-response {
+  token: res.body.token
+}
+
+assert {
+  res.status: eq 200
+}
+
+script:pre-request {
+  const ts = Date.now();
+  if (ts) {
+    req.setHeader("X-Ts", ts);
+  }
+}
+
+tests {
+  test("ok", function() {
+    expect(res.status).to.equal(200);
+  });
+
+}
+
+settings {
+  encodeUrl: false
+}
+
+docs {
+  # Login
+
+  - [ ] methods
+}
+`
+
+func TestParseBruRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"request file", bruRequestFixture},
+		{"generated request", requestContent(RequestData{Name: "a", Method: "GET", Path: "/a", BodyType: "none"})},
+		{"env file with secrets", "vars {\n  host: a.com\n}\nvars:secret [\n  token,\n  ~password\n]\n"},
+		{"crlf line endings", "meta {\r\n  name: a\r\n}\r\n\r\nget {\r\n  url: x\r\n}\r\n"},
+		{"no trailing newline", "headers {\n  a: b\n}"},
+		{"text outside blocks", "garbage line\nheaders {\n  a: b\n}\ntrailing\n\n"},
+		{"odd spacing", "headers {\n    a:b\n  c :  d  \n}\n"},
+		{"multiline value", "vars {\n  json: '''\n    {\n      \"a\": 1\n    }\n  '''\n}\n"},
+		{"unterminated block", "headers {\n  a: b\n"},
+		{"empty", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseBru(tt.content).String()
+			if got != tt.content {
+				t.Errorf("ParseBru().String() =\n%q\nwant:\n%q", got, tt.content)
+			}
+		})
+	}
+}
+
+func TestParseBruModel(t *testing.T) {
+	doc := ParseBru(bruRequestFixture)
+
+	names := []string{}
+	for _, b := range doc.Blocks {
+		names = append(names, b.Name)
+	}
+	expectedNames := []string{
+		"meta", "post", "params:query", "headers", "body:json", "vars:post-response",
+		"assert", "script:pre-request", "tests", "settings", "docs",
+	}
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Fatalf("blocks = %v, want %v", names, expectedNames)
+	}
+
+	if v, _ := doc.Block("meta").Get("seq"); v != "3" {
+		t.Errorf("meta seq = %q, want 3", v)
+	}
+	if v, _ := doc.Block("assert").Get("res.status"); v != "eq 200" {
+		t.Errorf("assert res.status = %q, want %q", v, "eq 200")
+	}
+
+	query := doc.Block("params:query")
+	expectedEntries := []BruEntry{{Key: "x", Value: "1"}, {Key: "debug", Value: "true", Disabled: true}}
+	if !reflect.DeepEqual(query.Entries, expectedEntries) {
+		t.Errorf("params:query entries = %v, want %v", query.Entries, expectedEntries)
+	}
+	if _, ok := query.Get("debug"); ok {
+		t.Errorf("disabled entry should not be returned by Get")
+	}
+	if !reflect.DeepEqual(query.Pairs(), Pairs{{"x", "1"}}) {
+		t.Errorf("params:query pairs = %v", query.Pairs())
+	}
+
+	body := doc.Block("body:json")
+	if body.Kind != BruText {
+		t.Errorf("body:json kind = %v, want text", body.Kind)
+	}
+	expectedBody := "{\n  \"user\": \"{{user}}\",\n  \"nested\": {\n    \"a\": 1\n  }\n}"
+	if body.Text != expectedBody {
+		t.Errorf("body:json text = %q, want %q", body.Text, expectedBody)
+	}
+
+	expectedTests := "test(\"ok\", function() {\n  expect(res.status).to.equal(200);\n});\n"
+	if got := doc.Block("tests").Text; got != expectedTests {
+		t.Errorf("tests text = %q, want %q", got, expectedTests)
+	}
+
+	if got := len(doc.BlocksWithPrefix("body:")); got != 1 {
+		t.Errorf("BlocksWithPrefix(body:) = %d blocks, want 1", got)
+	}
+}
+
+func TestParseBruList(t *testing.T) {
+	doc := ParseBru("vars:secret [\n  token,\n  ~password\n]\n")
+	b := doc.Block("vars:secret")
+	if b == nil || b.Kind != BruList {
+		t.Fatalf("vars:secret block = %v", b)
+	}
+	expected := []BruEntry{{Key: "token"}, {Key: "password", Disabled: true}}
+	if !reflect.DeepEqual(b.Entries, expected) {
+		t.Errorf("entries = %v, want %v", b.Entries, expected)
+	}
+
+	b.Add("api_key", "")
+	want := "vars:secret [\n  token,\n  ~password,\n  api_key\n]\n"
+	if got := doc.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestBruDocModify(t *testing.T) {
+	doc := ParseBru(bruRequestFixture)
+
+	doc.Block("headers").Set("X-New", "1")
+	doc.Block("params:query").Set("debug", "false")
+	doc.Block("body:json").Text = "{}"
+	doc.RemoveBlock("assert")
+	doc.SetBlock(NewBruDict("auth:bearer", Pairs{{"token", "{{token}}"}}))
+
+	got := ParseBru(doc.String())
+	if v, _ := got.Block("headers").Get("X-New"); v != "1" {
+		t.Errorf("headers X-New = %q, want 1", v)
+	}
+	if v, ok := got.Block("params:query").Get("debug"); !ok || v != "false" {
+		t.Errorf("params:query debug = %q, %v, want enabled false", v, ok)
+	}
+	if got.Block("body:json").Text != "{}" {
+		t.Errorf("body:json text = %q, want {}", got.Block("body:json").Text)
+	}
+	if got.Block("assert") != nil {
+		t.Errorf("assert block should be removed")
+	}
+	if v, _ := got.Block("auth:bearer").Get("token"); v != "{{token}}" {
+		t.Errorf("auth:bearer token = %q", v)
+	}
+
+	// untouched blocks are kept byte for byte
+	original := ParseBru(bruRequestFixture)
+	for _, name := range []string{"script:pre-request", "tests", "docs", "meta"} {
+		if got.Block(name).String() != original.Block(name).String() {
+			t.Errorf("block %q changed:\n%s\nwant:\n%s", name, got.Block(name), original.Block(name))
+		}
+	}
+}
+
+func TestBruMultilineClosingBrace(t *testing.T) {
+	content := "vars {\n  json: '''\n    {\n      \"a\": 1\n    }\n  '''\n  next: 2\n}\n\nheaders {\n  a: b\n}\n"
+	json := "{\n  \"a\": 1\n}"
+
+	doc := ParseBru(content)
+	if got := doc.String(); got != content {
+		t.Errorf("String() =\n%q\nwant:\n%q", got, content)
+	}
+	if v, _ := doc.Block("vars").Get("json"); v != json {
+		t.Errorf("vars json = %q, want %q", v, json)
+	}
+	if v, _ := doc.Block("vars").Get("next"); v != "2" {
+		t.Errorf("vars next = %q, want 2", v)
+	}
+	if v, _ := doc.Block("headers").Get("a"); v != "b" {
+		t.Errorf("headers a = %q, want b", v)
+	}
+
+	doc.Block("vars").Set("next", "3")
+	got := ParseBru(doc.String())
+	if v, _ := got.Block("vars").Get("json"); v != json {
+		t.Errorf("re-parsed vars json = %q, want %q", v, json)
+	}
+	if v, _ := got.Block("vars").Get("next"); v != "3" {
+		t.Errorf("re-parsed vars next = %q, want 3", v)
+	}
+	if got.Block("headers").String() != doc.Block("headers").String() {
+		t.Errorf("headers block changed:\n%s", got.Block("headers"))
+	}
+}
+
+func TestBruBlockString(t *testing.T) {
+	tests := []struct {
+		name     string
+		block    *BruBlock
+		expected string
+	}{
+		{
+			name:     "dict block",
+			block:    NewBruDict("headers", Pairs{{"A", "1"}, {"B", ""}}),
+			expected: "headers {\n  A: 1\n  B: \n}\n",
+		},
+		{
+			name:     "disabled entry",
+			block:    &BruBlock{Name: "headers", Entries: []BruEntry{{Key: "A", Value: "1", Disabled: true}}},
+			expected: "headers {\n  ~A: 1\n}\n",
+		},
+		{
+			name:     "multiline value",
+			block:    NewBruDict("vars", Pairs{{"a", "x\ny"}}),
+			expected: "vars {\n  a: '''\n    x\n    y\n  '''\n}\n",
+		},
+		{
+			name:     "text block indents every line",
+			block:    NewBruText("body:json", "{\n  \"a\": 1\n\n}"),
+			expected: "body:json {\n  {\n    \"a\": 1\n\n  }\n}\n",
+		},
+		{
+			name:     "empty text block",
+			block:    NewBruText("docs", ""),
+			expected: "docs {\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.block.String(); got != tt.expected {
+				t.Errorf("String() = %q, want %q", got, tt.expected)
+			}
+			// serialized block parses back to the same content
+			parsed := ParseBru(tt.expected).Blocks[0]
+			if !reflect.DeepEqual(parsed.Entries, tt.block.Entries) || parsed.Text != tt.block.Text {
+				t.Errorf("parsed block = %+v, want %+v", parsed, tt.block)
+			}
+		})
+	}
+}
+
+func TestBruFromFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "req.bru")
+	os.WriteFile(path, []byte(bruRequestFixture), 0o644)
+
+	doc, err := BruFromFile(path)
+	if err != nil {
+		t.Fatalf("BruFromFile() error = %v", err)
+	}
+	doc.AddBlock(NewBruText("docs", "extra"))
+	if err := doc.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != bruRequestFixture+"\ndocs {\n  extra\n}\n" {
+		t.Errorf("written file = %q", data)
+	}
+
+	if _, err := BruFromFile(filepath.Join(tmpDir, "missing.bru")); err == nil {
+		t.Errorf("BruFromFile() with missing file should return error")
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// BruRequest request defined by an imported spec or collection
+// (OpenAPI, Postman...). Unlike captured requests, values are written
+// as is, {{vars}} of the source are kept.
+type BruRequest struct {
+	Name string
+	// Type meta type, http or graphql
+	Type   string
+	Method string
+	URL    string
+	// Query params:query entries, disabled ones with `~` prefix
+	Query      Pairs
+	PathParams Pairs
+	// Headers entries, disabled ones with `~` prefix
+	Headers Pairs
+	Auth    *RequestAuth
+	// BodyType bruno body mode: none, json, text, xml, graphql,
+	// formUrlEncoded, multipartForm or file
+	BodyType string
+	Body     string
+	// Form entries of form-urlencoded, multipart-form and file bodies
+	Form Pairs
+	// GraphQLVars content of body:graphql:vars
+	GraphQLVars string
+	// Vars entries of vars:pre-request
+	Vars         Pairs
+	PreRequest   string
+	PostResponse string
+	Tests        string
+	Docs         []string
+}
+
+// Content returns .bru file content of the request
+func (r BruRequest) Content(seq int) string {
+	var sb strings.Builder
+
+	kind := r.Type
+	if kind == "" {
+		kind = "http"
+	}
+	var meta Pairs
+	meta.Add("name", r.Name)
+	meta.Add("type", kind)
+	meta.Add("seq", strconv.Itoa(seq))
+	sb.WriteString(MetaGenerate(meta))
+	sb.WriteString("\n")
+
+	bodyType := r.BodyType
+	if bodyType == "" {
+		bodyType = "none"
+	}
+	auth := r.Auth
+	if auth == nil {
+		auth = &RequestAuth{Mode: "none"}
+	}
+	var rvars Pairs
+	rvars.Add("url", r.URL)
+	rvars.Add("body", bodyType)
+	rvars.Add("auth", auth.Mode)
+	sb.WriteString(NameBlockMap(strings.ToLower(r.Method), rvars))
+	sb.WriteString("\n")
+
+	writeBlock := func(block string) {
+		if block != "" {
+			sb.WriteString(block)
+			sb.WriteString("\n")
+		}
+	}
+	textBlock := func(name, text string) string {
+		text = strings.Trim(text, "\n")
+		if strings.TrimSpace(text) == "" {
+			return ""
+		}
+		return NameBlockStrings(name, strings.Split(text, "\n"))
+	}
+
+	writeBlock(QueryParamsGenerate(r.Query))
+	writeBlock(NameBlockMap("params:path", r.PathParams))
+	writeBlock(HeadersGenerate(r.Headers))
+	writeBlock(AuthGenerate(auth))
+
+	var setts Pairs
+	setts.Add("encodeUrl", "false")
+	writeBlock(NameBlockMap("settings", setts))
+
+	switch bodyType {
+	case "none":
+	case "formUrlEncoded", "multipartForm", "file":
+		writeBlock(NameBlockMap("body:"+BodyTypeName(bodyType), r.Form))
+	default:
+		writeBlock(textBlock("body:"+bodyType, r.Body))
+	}
+	if bodyType == "graphql" {
+		writeBlock(textBlock("body:graphql:vars", r.GraphQLVars))
+	}
+
+	writeBlock(NameBlockMap("vars:pre-request", r.Vars))
+	writeBlock(textBlock("script:pre-request", r.PreRequest))
+	writeBlock(textBlock("script:post-response", r.PostResponse))
+	writeBlock(textBlock("tests", r.Tests))
+	sb.WriteString(NameBlockStrings("docs", r.Docs))
+
+	return strings.TrimRight(sb.String(), "\n") + "\n"
+}
+
+// WriteBruRequest writes the request to dir/<name>.bru with seq after
+// existing files. Existing file is merged with -merge, written as
+// name-2.bru with -suffix, otherwise it's an error.
+func WriteBruRequest(dir string, r BruRequest, opts RequestOptions) (string, error) {
+	fp := filepath.Join(dir, r.Name+".bru")
+	merge := false
+	if _, err := os.Stat(fp); err == nil {
+		switch {
+		case opts.Merge:
+			merge = true
+		case opts.Suffix:
+			r.Name, fp = nextFreeName(dir, r.Name)
+		default:
+			return "", fmt.Errorf("file %q already exists", fp)
+		}
+	}
+
+	content := r.Content(DirFilesCount(dir) + 1)
+	if merge {
+		if err := mergeRequestFile(fp, content); err != nil {
+			return "", fmt.Errorf("merge request to file %q error %w", fp, err)
+		}
+		return fp, nil
+	}
+	if err := os.WriteFile(fp, []byte(content), 0o644); err != nil {
+		return "", fmt.Errorf("write request to file %q error %w", fp, err)
+	}
+	return fp, nil
+}
+
+// enabledPairs returns entries without `~` prefix
+func enabledPairs(pairs Pairs) Pairs {
+	var enabled Pairs
+	for _, p := range pairs {
+		if !strings.HasPrefix(p.Key, "~") {
+			enabled = append(enabled, p)
+		}
+	}
+	return enabled
+}
+
+// importFolder creates dir/name folder with DoFolder if missing and
+// returns the folder dir and its folder.bru. Default Cookie and
+// Authorization headers of created folders are removed, imported
+// folders define auth with auth blocks.
+func importFolder(dir, name string) (string, *BruDoc, error) {
+	name = requestFileName(name)
+	if name == "" {
+		name = "folder"
+	}
+	sub := filepath.Join(dir, name)
+	fp := filepath.Join(sub, "folder.bru")
+
+	_, err := os.Stat(fp)
+	created := err != nil
+	if created {
+		if err := DoFolder(name, dir); err != nil {
+			return "", nil, err
+		}
+	}
+
+	doc, err := BruFromFile(fp)
+	if err != nil {
+		return "", nil, err
+	}
+	if created {
+		doc.RemoveBlock("headers")
+	}
+	return sub, doc, nil
+}
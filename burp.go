@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// exampleBodyLimit max response body size kept in the docs example
+const exampleBodyLimit = 8 << 10
+
+// BurpItems Burp Suite "Save items" XML export
+type BurpItems struct {
+	Items []BurpItem `xml:"item"`
+}
+
+type BurpItem struct {
+	URL      string      `xml:"url"`
+	Host     string      `xml:"host"`
+	Port     string      `xml:"port"`
+	Protocol string      `xml:"protocol"`
+	Method   string      `xml:"method"`
+	Path     string      `xml:"path"`
+	Request  BurpMessage `xml:"request"`
+	Status   string      `xml:"status"`
+	Response BurpMessage `xml:"response"`
+}
+
+// BurpMessage raw request or response, base64 encoded
+// when "Base64-encode requests and responses" is checked
+type BurpMessage struct {
+	Base64 bool   `xml:"base64,attr"`
+	Data   string `xml:",chardata"`
+}
+
+// Bytes returns the decoded message
+func (m BurpMessage) Bytes() ([]byte, error) {
+	if !m.Base64 {
+		return []byte(m.Data), nil
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(m.Data))
+	if err != nil {
+		return nil, fmt.Errorf("decode base64 error %w", err)
+	}
+	return data, nil
+}
+
+// DoBurp converts every item of the Burp XML export into .bru request.
+// Items are routed to the collection named after the item host,
+// missing collections are created in basedir. Created files are printed
+// to stdout, skipped items and summary to stderr.
+func DoBurp(input, basedir, envfile string, opts RequestOptions) error {
+	if input == "" {
+		return fmt.Errorf("-i input file is required")
+	}
+
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return fmt.Errorf("read %q file error %w", input, err)
+	}
+
+	var burp BurpItems
+	if err := xml.Unmarshal(data, &burp); err != nil {
+		return fmt.Errorf("parse burp file error %w", err)
+	}
+
+	created, skipped := 0, 0
+	for i, item := range burp.Items {
+		fp, err := convertBurpItem(item, basedir, envfile, opts)
+		if err != nil {
+			skipped++
+			fmt.Fprintf(os.Stderr, "[W] skip item %d %s %s: %s\n", i, item.Method, item.URL, err)
+			continue
+		}
+		created++
+		fmt.Println(fp)
+	}
+
+	fmt.Fprintf(os.Stderr, "[I] burp items: %d, created: %d, skipped: %d\n", len(burp.Items), created, skipped)
+	return nil
+}
+
+func convertBurpItem(item BurpItem, basedir, envfile string, opts RequestOptions) (string, error) {
+	raw, err := item.Request.Bytes()
+	if err != nil {
+		return "", fmt.Errorf("request %w", err)
+	}
+	req, order, err := ParseRequest(raw)
+	if err != nil {
+		return "", fmt.Errorf("parse request error %w", err)
+	}
+
+	host := strings.ToLower(strings.TrimSpace(item.Host))
+	if host == "" {
+		host = strings.ToLower(req.Host)
+	}
+	if req.Host == "" {
+		req.Host = host
+	}
+	if proto := strings.ToLower(item.Protocol); req.URL.Scheme == "" && (proto == "http" || proto == "https") {
+		req.URL.Scheme = proto
+		req.URL.Host = req.Host
+	}
+
+	dir, err := burpCollectionDir(item, host, basedir, envfile)
+	if err != nil {
+		return "", err
+	}
+
+	meta := ParseRequestMeta(RawMetaLines(raw))
+	if opts.Examples {
+		if resp, err := item.Response.Bytes(); err == nil {
+			meta.Example = responseExample(resp)
+		}
+	}
+
+	return convertRequest(req, order, meta, dir, envfile, opts)
+}
+
+// burpCollectionDir returns basedir if it's a collection, otherwise
+// basedir/host. The host collection is created if missing, http proto
+// of the item is written to its env file. Non default ports are kept
+// on request urls. An existing host dir without bruno.json is an error.
+func burpCollectionDir(item BurpItem, host, basedir, envfile string) (string, error) {
+	if _, err := os.Stat(filepath.Join(basedir, "bruno.json")); err == nil {
+		return basedir, nil
+	}
+	if host == "" {
+		return "", fmt.Errorf("item host is missing")
+	}
+
+	dir := filepath.Join(basedir, host)
+	if _, err := os.Stat(dir); err == nil {
+		if _, err := os.Stat(filepath.Join(dir, "bruno.json")); err != nil {
+			return "", fmt.Errorf("collection dir %q found but missing bruno.json", dir)
+		}
+		return dir, nil
+	}
+
+	if err := DoCollection(host, basedir); err != nil {
+		return "", err
+	}
+	fmt.Fprintf(os.Stderr, "[I] created collection %s\n", dir)
+
+	var vars Pairs
+	proto := strings.ToLower(item.Protocol)
+	if proto == "http" {
+		vars.Add("proto", proto)
+	}
+	if len(vars) > 0 {
+		if err := EnvSetVars(filepath.Join(dir, envfile), vars); err != nil {
+			fmt.Fprintf(os.Stderr, "[W] write env file: %s\n", err)
+		}
+	}
+
+	return dir, nil
+}
+
+// responseExample returns the raw response for the docs example.
+// Binary bodies are omitted and long bodies are truncated.
+func responseExample(resp []byte) string {
+	resp = bytes.ReplaceAll(resp, []byte("\r\n"), []byte("\n"))
+	head, body, _ := bytes.Cut(resp, []byte("\n\n"))
+
+	example := strings.TrimRight(string(head), "\n")
+	if len(body) == 0 {
+		return example
+	}
+
+	switch {
+	case bytes.IndexByte(body, 0) >= 0 || !utf8.Valid(body):
+		return example + fmt.Sprintf("\n\n[binary body %d bytes]", len(body))
+	case len(body) > exampleBodyLimit:
+		// cut on the line end to keep utf8 runes
+		cut := bytes.LastIndexByte(body[:exampleBodyLimit], '\n')
+		if cut < 0 {
+			cut = exampleBodyLimit
+		}
+		return example + "\n\n" + strings.ToValidUTF8(string(body[:cut]), "") + fmt.Sprintf("\n[truncated, %d bytes total]", len(body))
+	}
+	return example + "\n\n" + strings.TrimRight(string(body), "\n")
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBurpMessageBytes(t *testing.T) {
+	plain := BurpMessage{Data: "GET / HTTP/1.1\r\n\r\n"}
+	if got, err := plain.Bytes(); err != nil || string(got) != plain.Data {
+		t.Errorf("Bytes() = %q, %v", got, err)
+	}
+
+	encoded := BurpMessage{Base64: true, Data: "\n" + base64.StdEncoding.EncodeToString([]byte("GET / HTTP/1.1")) + "\n"}
+	if got, err := encoded.Bytes(); err != nil || string(got) != "GET / HTTP/1.1" {
+		t.Errorf("Bytes() = %q, %v", got, err)
+	}
+
+	if _, err := (BurpMessage{Base64: true, Data: "!!"}).Bytes(); err == nil {
+		t.Error("Bytes() expected error for invalid base64")
+	}
+}
+
+func TestResponseExample(t *testing.T) {
+	tests := []struct {
+		name     string
+		resp     string
+		expected string
+	}{
+		{
+			name:     "text body",
+			resp:     "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\n\r\n{\"id\": 1}\n",
+			expected: "HTTP/1.1 200 OK\nContent-Type: application/json\n\n{\"id\": 1}",
+		},
+		{
+			name:     "no body",
+			resp:     "HTTP/1.1 204 No Content\r\n\r\n",
+			expected: "HTTP/1.1 204 No Content",
+		},
+		{
+			name:     "binary body",
+			resp:     "HTTP/1.1 200 OK\r\nContent-Type: image/png\r\n\r\n\x89PNG\x00\x01",
+			expected: "HTTP/1.1 200 OK\nContent-Type: image/png\n\n[binary body 6 bytes]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := responseExample([]byte(tt.resp)); got != tt.expected {
+				t.Errorf("responseExample() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	long := "HTTP/1.1 200 OK\r\n\r\n" + strings.Repeat("0123456789\n", 1000)
+	got := responseExample([]byte(long))
+	if len(got) > exampleBodyLimit+100 || !strings.HasSuffix(got, "[truncated, 11000 bytes total]") {
+		t.Errorf("responseExample() long body not truncated, len %d, tail %q", len(got), got[len(got)-40:])
+	}
+}
+
+func TestDoBurp(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	reqGet := "GET /users/list?page=2 HTTP/1.1\r\nHost: api.example.com\r\nAccept: application/json\r\n\r\n"
+	respGet := "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\n\r\n[{\"id\": 1}]"
+	reqPost := "POST /login HTTP/1.1\r\nHost: dev.local:8080\r\nContent-Type: application/json\r\nContent-Length: 7\r\n\r\n{\"u\":1}"
+
+	xmlData := `<?xml version="1.0"?>
+<!DOCTYPE items [
+<!ELEMENT items (item*)>
+]>
+<items burpVersion="2024.1" exportTime="Mon Jan 01 00:00:00 UTC 2024">
+  <item>
+    <url><![CDATA[https://api.example.com/users/list?page=2]]></url>
+    <host ip="10.0.0.1">api.example.com</host>
+    <port>443</port>
+    <protocol>https</protocol>
+    <method><![CDATA[GET]]></method>
+    <path><![CDATA[/users/list?page=2]]></path>
+    <request base64="true"><![CDATA[` + base64.StdEncoding.EncodeToString([]byte(reqGet)) + `]]></request>
+    <status>200</status>
+    <response base64="true"><![CDATA[` + base64.StdEncoding.EncodeToString([]byte(respGet)) + `]]></response>
+  </item>
+  <item>
+    <url><![CDATA[http://dev.local:8080/login]]></url>
+    <host ip="127.0.0.1">dev.local</host>
+    <port>8080</port>
+    <protocol>http</protocol>
+    <method><![CDATA[POST]]></method>
+    <path><![CDATA[/login]]></path>
+    <request base64="false"><![CDATA[` + reqPost + `]]></request>
+    <status></status>
+    <response base64="false"></response>
+  </item>
+  <item>
+    <url><![CDATA[https://api.example.com/broken]]></url>
+    <host>api.example.com</host>
+    <request base64="true"><![CDATA[not base64!]]></request>
+  </item>
+</items>`
+	input := filepath.Join(tmpDir, "items.xml")
+	os.WriteFile(input, []byte(xmlData), 0o644)
+
+	opts := RequestOptions{SkipHeaders: DefaultSkipHeaders, Examples: true}
+	if err := DoBurp(input, tmpDir, "environments/base.bru", opts); err != nil {
+		t.Fatalf("DoBurp() error = %v", err)
+	}
+
+	for _, fp := range []string{
+		"api.example.com/bruno.json",
+		"dev.local/bruno.json",
+		"api.example.com/users-list-GET.bru",
+		"dev.local/login-POST.bru",
+	} {
+		if _, err := os.Stat(filepath.Join(tmpDir, fp)); err != nil {
+			t.Errorf("expected file %q was not created", fp)
+		}
+	}
+
+	data, _ := os.ReadFile(filepath.Join(tmpDir, "api.example.com", "users-list-GET.bru"))
+	for _, want := range []string{"page: 2", "## Example response", "  HTTP/1.1 200 OK", `  [{"id": 1}]`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("request file should contain %q\ngot:\n%s", want, data)
+		}
+	}
+
+	env, err := EnvFromFile(filepath.Join(tmpDir, "dev.local", "environments", "base.bru"))
+	if err != nil {
+		t.Fatalf("EnvFromFile() error = %v", err)
+	}
+	if env.Vars["proto"] != "http" || env.Vars["host"] != "dev.local" {
+		t.Errorf("dev.local env proto = %q, host = %q", env.Vars["proto"], env.Vars["host"])
+	}
+	data, _ = os.ReadFile(filepath.Join(tmpDir, "dev.local", "login-POST.bru"))
+	if !strings.Contains(string(data), "url: {{proto}}://{{host}}:8080/login") {
+		t.Errorf("login request should use env host with the item port\ngot:\n%s", data)
+	}
+	if strings.Contains(string(data), "Example response") {
+		t.Errorf("empty response should not be kept as example\ngot:\n%s", data)
+	}
+}
+
+func TestBurpCollectionDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "plain.example.com"), 0o755)
+	if err := DoCollection("api.example.com", tmpDir); err != nil {
+		t.Fatalf("DoCollection() error = %v", err)
+	}
+
+	item := BurpItem{Protocol: "https"}
+	dir, err := burpCollectionDir(item, "api.example.com", tmpDir, "environments/base.bru")
+	if err != nil || dir != filepath.Join(tmpDir, "api.example.com") {
+		t.Errorf("burpCollectionDir() = %q, %v", dir, err)
+	}
+	if _, err := burpCollectionDir(item, "plain.example.com", tmpDir, "environments/base.bru"); err == nil || !strings.Contains(err.Error(), "missing bruno.json") {
+		t.Errorf("burpCollectionDir() for dir without bruno.json error = %v", err)
+	}
+	dir, err = burpCollectionDir(item, "new.example.com", tmpDir, "environments/base.bru")
+	if err != nil {
+		t.Fatalf("burpCollectionDir() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "bruno.json")); err != nil {
+		t.Errorf("missing collection was not created")
+	}
+}
@@ -43,8 +43,8 @@ func DefaultBrunoJSON(name string) string {
 //	  User-Agent: {{ua}}
 //	}
 func DefaultCollectionBru() string {
-	mp := make(map[string]string)
-	mp["User-Agent"] = "{{ua}}"
+	var heads Pairs
+	heads.Add("User-Agent", "{{ua}}")
 
-	return HeadersGenerate(mp)
+	return HeadersGenerate(heads)
 }
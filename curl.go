@@ -0,0 +1,578 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// curlNoArgFlags curl options without argument which don't change the request
+var curlNoArgFlags = map[string]bool{
+	"-s": true, "--silent": true,
+	"-S": true, "--show-error": true,
+	"-k": true, "--insecure": true,
+	"-L": true, "--location": true,
+	"-v": true, "--verbose": true,
+	"-i": true, "--include": true,
+	"-g": true, "--globoff": true,
+	"-f": true, "--fail": true,
+	"--compressed":  true,
+	"--http1.0":     true,
+	"--http1.1":     true,
+	"--http2":       true,
+	"--http3":       true,
+	"--path-as-is":  true,
+	"--no-buffer":   true,
+	"-N":            true,
+	"--tr-encoding": true,
+}
+
+// curlArgFlags curl options with argument which don't change the request
+var curlArgFlags = map[string]bool{
+	"-o": true, "--output": true,
+	"-m": true, "--max-time": true,
+	"-x": true, "--proxy": true,
+	"-w": true, "--write-out": true,
+	"-E": true, "--cert": true,
+	"--connect-timeout": true,
+	"--retry":           true,
+	"--cacert":          true,
+	"--key":             true,
+	"--resolve":         true,
+	"--proxy-user":      true,
+}
+
+// curlRequest options collected from the curl command line
+type curlRequest struct {
+	method    string
+	url       string
+	headers   []string
+	data      []string
+	form      []curlFormField
+	get       bool
+	head      bool
+	user      string
+	cookie    string
+	userAgent string
+	referer   string
+	// noFiles rejects options reading local files,
+	// set for commands which don't come from the user
+	noFiles bool
+}
+
+// curlFormField -F value, literal for --form-string values
+// which are never file references
+type curlFormField struct {
+	spec    string
+	literal bool
+}
+
+// IsCurlCommand reports whether the input is a curl command line.
+// Leading empty and `#` lines are skipped.
+func IsCurlCommand(raw []byte) bool {
+	for _, line := range strings.Split(string(raw), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "$ ")
+		return line == "curl" || strings.HasPrefix(line, "curl ") || strings.HasPrefix(line, "curl.exe ")
+	}
+	return false
+}
+
+// ParseCurlCommand parses a "Copy as cURL" command line into *http.Request
+// and returns header names in command line order.
+func ParseCurlCommand(cmd string) (*http.Request, []string, error) {
+	return parseCurlCommand(cmd, false)
+}
+
+// parseCurlCommand parses the curl command, with noFiles options
+// reading local files (-d @file, -F name=@file...) are errors
+func parseCurlCommand(cmd string, noFiles bool) (*http.Request, []string, error) {
+	var lines []string
+	for _, line := range strings.Split(cmd, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	args, err := splitShellWords(strings.Join(lines, "\n"))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(args) > 0 && args[0] == "$" {
+		args = args[1:]
+	}
+	if len(args) == 0 || (args[0] != "curl" && args[0] != "curl.exe") {
+		return nil, nil, fmt.Errorf("not a curl command")
+	}
+
+	cr, err := parseCurlArgs(args[1:], noFiles)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cr.build()
+}
+
+func parseCurlArgs(args []string, noFiles bool) (*curlRequest, error) {
+	cr := &curlRequest{noFiles: noFiles}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		name, value, hasValue := arg, "", false
+		switch {
+		case strings.HasPrefix(arg, "--"):
+			if n, v, found := strings.Cut(arg, "="); found {
+				name, value, hasValue = n, v, true
+			}
+		case strings.HasPrefix(arg, "-") && len(arg) > 2:
+			// -XPOST, -H'X: 1' or combined flags -sSL
+			if curlTakesArg(arg[:2]) {
+				name, value, hasValue = arg[:2], arg[2:], true
+			} else {
+				for _, ch := range arg[1:] {
+					if !curlNoArgFlags["-"+string(ch)] {
+						return nil, fmt.Errorf("unsupported curl option %q", arg)
+					}
+				}
+				continue
+			}
+		case !strings.HasPrefix(arg, "-"):
+			if cr.url != "" {
+				return nil, fmt.Errorf("multiple urls in curl command")
+			}
+			cr.url = arg
+			continue
+		}
+
+		if curlNoArgFlags[name] {
+			continue
+		}
+		if !curlTakesArg(name) {
+			switch name {
+			case "-G", "--get":
+				cr.get = true
+			case "-I", "--head":
+				cr.head = true
+			default:
+				return nil, fmt.Errorf("unsupported curl option %q", name)
+			}
+			continue
+		}
+
+		if !hasValue {
+			i++
+			if i >= len(args) {
+				return nil, fmt.Errorf("curl option %q requires an argument", name)
+			}
+			value = args[i]
+		}
+
+		if err := cr.setOption(name, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if cr.url == "" {
+		return nil, fmt.Errorf("no url in curl command")
+	}
+	return cr, nil
+}
+
+func curlTakesArg(name string) bool {
+	if curlArgFlags[name] {
+		return true
+	}
+	switch name {
+	case "-X", "--request", "-H", "--header", "-d", "--data", "--data-raw", "--data-ascii",
+		"--data-binary", "--data-urlencode", "-F", "--form", "--form-string", "-u", "--user",
+		"-b", "--cookie", "-A", "--user-agent", "-e", "--referer", "--url":
+		return true
+	}
+	return false
+}
+
+func (cr *curlRequest) setOption(name, value string) error {
+	switch name {
+	case "-X", "--request":
+		cr.method = value
+	case "-H", "--header":
+		cr.headers = append(cr.headers, value)
+	case "-d", "--data", "--data-ascii", "--data-binary":
+		if strings.HasPrefix(value, "@") {
+			data, err := cr.readFile(name, value[1:])
+			if err != nil {
+				return fmt.Errorf("read curl data file error %w", err)
+			}
+			value = string(data)
+			if name != "--data-binary" {
+				value = strings.NewReplacer("\r", "", "\n", "").Replace(value)
+			}
+		}
+		cr.data = append(cr.data, value)
+	case "--data-raw":
+		cr.data = append(cr.data, value)
+	case "--data-urlencode":
+		data, err := cr.urlEncode(value)
+		if err != nil {
+			return err
+		}
+		cr.data = append(cr.data, data)
+	case "-F", "--form":
+		cr.form = append(cr.form, curlFormField{spec: value})
+	case "--form-string":
+		cr.form = append(cr.form, curlFormField{spec: value, literal: true})
+	case "-u", "--user":
+		cr.user = value
+	case "-b", "--cookie":
+		if !strings.Contains(value, "=") {
+			fmt.Fprintf(os.Stderr, "[W] unsupported curl cookie jar %q, cookie is skipped\n", value)
+			break
+		}
+		if cr.cookie != "" {
+			cr.cookie += "; "
+		}
+		cr.cookie += value
+	case "-A", "--user-agent":
+		cr.userAgent = value
+	case "-e", "--referer":
+		cr.referer = value
+	case "--url":
+		cr.url = value
+	}
+	return nil
+}
+
+// readFile reads the file of the curl option
+func (cr *curlRequest) readFile(option, path string) ([]byte, error) {
+	if cr.noFiles {
+		return nil, fmt.Errorf("curl option %s reads local file %q, not allowed", option, path)
+	}
+	return os.ReadFile(path)
+}
+
+// urlEncode implements --data-urlencode formats:
+// content, =content, name=content, @file, name@file
+func (cr *curlRequest) urlEncode(value string) (string, error) {
+	if i := strings.IndexAny(value, "=@"); i >= 0 {
+		name, content := value[:i], value[i+1:]
+		if value[i] == '@' {
+			data, err := cr.readFile("--data-urlencode", content)
+			if err != nil {
+				return "", fmt.Errorf("read curl data file error %w", err)
+			}
+			content = string(data)
+		}
+		if name == "" {
+			return url.QueryEscape(content), nil
+		}
+		return name + "=" + url.QueryEscape(content), nil
+	}
+	return url.QueryEscape(value), nil
+}
+
+func (cr *curlRequest) build() (*http.Request, []string, error) {
+	rawURL := cr.url
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+
+	var body []byte
+	contentType := ""
+	data := strings.Join(cr.data, "&")
+	switch {
+	case len(cr.form) > 0:
+		var err error
+		body, contentType, err = cr.multipartBody()
+		if err != nil {
+			return nil, nil, err
+		}
+	case cr.get && len(cr.data) > 0:
+		sep := "?"
+		if strings.Contains(rawURL, "?") {
+			sep = "&"
+		}
+		rawURL += sep + data
+	case len(cr.data) > 0:
+		body = []byte(data)
+		contentType = "application/x-www-form-urlencoded"
+	}
+
+	method := cr.method
+	if method == "" {
+		switch {
+		case cr.head:
+			method = http.MethodHead
+		case len(body) > 0:
+			method = http.MethodPost
+		default:
+			method = http.MethodGet
+		}
+	}
+
+	req, err := http.NewRequest(method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, fmt.Errorf("create request error %w", err)
+	}
+
+	var order []string
+	add := func(key, value string) {
+		key = http.CanonicalHeaderKey(key)
+		if key == "Host" {
+			req.Host = value
+			return
+		}
+		req.Header.Add(key, value)
+		order = append(order, key)
+	}
+
+	for _, h := range cr.headers {
+		key, value, found := strings.Cut(h, ":")
+		if !found {
+			// -H 'X-Empty;' sends empty header
+			if k, ok := strings.CutSuffix(h, ";"); ok {
+				add(strings.TrimSpace(k), "")
+			}
+			continue
+		}
+		if strings.TrimSpace(value) == "" {
+			// -H 'X-Remove:' removes the header in curl
+			continue
+		}
+		add(strings.TrimSpace(key), strings.TrimSpace(value))
+	}
+	if cr.user != "" && req.Header.Get("Authorization") == "" {
+		add("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(cr.user)))
+	}
+	if cr.cookie != "" {
+		add("Cookie", cr.cookie)
+	}
+	if cr.userAgent != "" && req.Header.Get("User-Agent") == "" {
+		add("User-Agent", cr.userAgent)
+	}
+	if cr.referer != "" && req.Header.Get("Referer") == "" {
+		add("Referer", cr.referer)
+	}
+	if contentType != "" && req.Header.Get("Content-Type") == "" {
+		add("Content-Type", contentType)
+	}
+
+	return req, order, nil
+}
+
+// multipartBody builds multipart/form-data body from -F values:
+// name=value, name=@file, name=<file, name=@file;type=text/plain;filename=a.txt
+func (cr *curlRequest) multipartBody() ([]byte, string, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	for _, field := range cr.form {
+		name, value, found := strings.Cut(field.spec, "=")
+		if !found {
+			return nil, "", fmt.Errorf("invalid curl form field %q", field.spec)
+		}
+
+		if field.literal || (!strings.HasPrefix(value, "@") && !strings.HasPrefix(value, "<")) {
+			if err := mw.WriteField(name, value); err != nil {
+				return nil, "", err
+			}
+			continue
+		}
+
+		parts := strings.Split(value[1:], ";")
+		path := parts[0]
+		filename := path[strings.LastIndexAny(path, `/\`)+1:]
+		ctype := "application/octet-stream"
+		for _, p := range parts[1:] {
+			k, v, _ := strings.Cut(p, "=")
+			switch strings.TrimSpace(k) {
+			case "type":
+				ctype = v
+			case "filename":
+				filename = strings.Trim(v, `"`)
+			}
+		}
+
+		content, err := cr.readFile("-F", path)
+		if err != nil {
+			return nil, "", fmt.Errorf("read curl form file error %w", err)
+		}
+
+		if value[0] == '<' {
+			// content of the file as a plain field
+			if err := mw.WriteField(name, string(content)); err != nil {
+				return nil, "", err
+			}
+			continue
+		}
+
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name=%s; filename=%s`, strconv.Quote(name), strconv.Quote(filename)))
+		h.Set("Content-Type", ctype)
+		w, err := mw.CreatePart(h)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := w.Write(content); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), mw.FormDataContentType(), nil
+}
+
+// splitShellWords splits the command line into arguments using
+// POSIX shell quoting rules: single and double quotes, $'...' strings,
+// backslash escapes and line continuations.
+func splitShellWords(s string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inWord := false
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\':
+			if i+1 < len(runes) && runes[i+1] == '\n' {
+				i++
+				continue
+			}
+			if i+2 < len(runes) && runes[i+1] == '\r' && runes[i+2] == '\n' {
+				i += 2
+				continue
+			}
+			if i+1 < len(runes) {
+				i++
+				cur.WriteRune(runes[i])
+				inWord = true
+			}
+		case r == '\'':
+			end := indexRune(runes, i+1, '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			cur.WriteString(string(runes[i+1 : end]))
+			inWord = true
+			i = end
+		case r == '$' && i+1 < len(runes) && runes[i+1] == '\'':
+			end, err := ansiCString(runes, i+2, &cur)
+			if err != nil {
+				return nil, err
+			}
+			inWord = true
+			i = end
+		case r == '"':
+			j := i + 1
+			for ; j < len(runes) && runes[j] != '"'; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) && strings.ContainsRune("$`\"\\\n", runes[j+1]) {
+					j++
+					if runes[j] != '\n' {
+						cur.WriteRune(runes[j])
+					}
+					continue
+				}
+				cur.WriteRune(runes[j])
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inWord = true
+			i = j
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inWord {
+				args = append(args, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		args = append(args, cur.String())
+	}
+
+	return args, nil
+}
+
+func indexRune(runes []rune, from int, r rune) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// ansiCString decodes $'...' string starting at from (after the quote),
+// returns the index of the closing quote
+func ansiCString(runes []rune, from int, sb *strings.Builder) (int, error) {
+	escapes := map[rune]string{
+		'n': "\n", 't': "\t", 'r': "\r", 'a': "\a", 'b': "\b", 'f': "\f", 'v': "\v",
+		'e': "\x1b", '\\': "\\", '\'': "'", '"': "\"", '?': "?",
+	}
+
+	for i := from; i < len(runes); i++ {
+		r := runes[i]
+		if r == '\'' {
+			return i, nil
+		}
+		if r != '\\' || i+1 >= len(runes) {
+			sb.WriteRune(r)
+			continue
+		}
+
+		i++
+		if esc, ok := escapes[runes[i]]; ok {
+			sb.WriteString(esc)
+			continue
+		}
+
+		size := 0
+		switch runes[i] {
+		case 'x':
+			size = 2
+		case 'u':
+			size = 4
+		case 'U':
+			size = 8
+		}
+		if size > 0 {
+			j := i + 1
+			for j < len(runes) && j < i+1+size && isHexRune(runes[j]) {
+				j++
+			}
+			if j > i+1 {
+				n, _ := strconv.ParseUint(string(runes[i+1:j]), 16, 32)
+				if runes[i] == 'x' {
+					sb.WriteByte(byte(n))
+				} else {
+					sb.WriteRune(rune(n))
+				}
+				i = j - 1
+				continue
+			}
+		}
+		sb.WriteRune('\\')
+		sb.WriteRune(runes[i])
+	}
+	return 0, fmt.Errorf("unterminated $' quote")
+}
+
+func isHexRune(r rune) bool {
+	return (r >= '0' && r <= '9') || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitShellWords(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+		wantErr  bool
+	}{
+		{"plain words", "curl -X POST url", []string{"curl", "-X", "POST", "url"}, false},
+		{"single quotes", `curl -H 'X-A: b c'`, []string{"curl", "-H", "X-A: b c"}, false},
+		{"double quotes with escapes", `curl -d "{\"a\": \"\$x\"}"`, []string{"curl", "-d", `{"a": "$x"}`}, false},
+		{"backslash outside quotes", `curl a\ b`, []string{"curl", "a b"}, false},
+		{"line continuation", "curl \\\n  -X PUT \\\r\n  url", []string{"curl", "-X", "PUT", "url"}, false},
+		{"ansi c quoting", `curl --data-raw $'{"a":"b\n"}\x41é'`, []string{"curl", "--data-raw", "{\"a\":\"b\n\"}Aé"}, false},
+		{"empty quoted word", `curl -d '' url`, []string{"curl", "-d", "", "url"}, false},
+		{"adjacent quotes joined", `curl 'a'"b"c`, []string{"curl", "abc"}, false},
+		{"unterminated single quote", `curl 'abc`, nil, true},
+		{"unterminated double quote", `curl "abc`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitShellWords(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitShellWords() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("splitShellWords() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIsCurlCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected bool
+	}{
+		{"curl command", "curl https://a.com", true},
+		{"curl with prompt", "$ curl https://a.com", true},
+		{"curl after comments", "# copied\n\ncurl 'https://a.com'", true},
+		{"raw request", "GET / HTTP/1.1\r\nHost: a.com\r\n\r\n", false},
+		{"curlish word", "curly / HTTP/1.1", false},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsCurlCommand([]byte(tt.input)); got != tt.expected {
+				t.Errorf("IsCurlCommand(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseCurlCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		cmd         string
+		wantMethod  string
+		wantHost    string
+		wantPath    string
+		wantQuery   string
+		wantBody    string
+		wantHeaders map[string]string
+		wantOrder   []string
+		wantErr     bool
+	}{
+		{
+			name:       "simple GET",
+			cmd:        "curl https://api.example.com/users?page=1",
+			wantMethod: "GET",
+			wantHost:   "api.example.com",
+			wantPath:   "/users",
+			wantQuery:  "page=1",
+		},
+		{
+			name: "chrome copy as curl",
+			cmd: `curl 'https://api.example.com/api/login' \
+  -H 'accept: application/json' \
+  -H 'content-type: application/json' \
+  -b 'session=abc; theme=dark' \
+  --data-raw '{"user":"bob"}' \
+  --compressed`,
+			wantMethod: "POST",
+			wantHost:   "api.example.com",
+			wantPath:   "/api/login",
+			wantBody:   `{"user":"bob"}`,
+			wantHeaders: map[string]string{
+				"Accept":       "application/json",
+				"Content-Type": "application/json",
+				"Cookie":       "session=abc; theme=dark",
+			},
+			wantOrder: []string{"Accept", "Content-Type", "Cookie"},
+		},
+		{
+			name:       "explicit method and attached option values",
+			cmd:        `curl -XDELETE -H'X-Token: 1' http://localhost:8080/items/5`,
+			wantMethod: "DELETE",
+			wantHost:   "localhost:8080",
+			wantPath:   "/items/5",
+			wantHeaders: map[string]string{
+				"X-Token": "1",
+			},
+			wantOrder: []string{"X-Token"},
+		},
+		{
+			name:       "multiple data joined with default content type",
+			cmd:        `curl -sSL -d a=1 --data b=2 --data-urlencode 'c=x y' api.example.com/form`,
+			wantMethod: "POST",
+			wantHost:   "api.example.com",
+			wantPath:   "/form",
+			wantBody:   "a=1&b=2&c=x+y",
+			wantHeaders: map[string]string{
+				"Content-Type": "application/x-www-form-urlencoded",
+			},
+		},
+		{
+			name:       "get flag moves data to query",
+			cmd:        `curl -G --data-urlencode 'q=a&b' https://a.com/search?x=1`,
+			wantMethod: "GET",
+			wantHost:   "a.com",
+			wantPath:   "/search",
+			wantQuery:  "x=1&q=a%26b",
+		},
+		{
+			name:       "basic auth user agent and referer",
+			cmd:        `curl -u bob:secret -A 'my-agent' -e https://ref.com https://a.com/`,
+			wantMethod: "GET",
+			wantHost:   "a.com",
+			wantPath:   "/",
+			wantHeaders: map[string]string{
+				"Authorization": "Basic Ym9iOnNlY3JldA==",
+				"User-Agent":    "my-agent",
+				"Referer":       "https://ref.com",
+			},
+			wantOrder: []string{"Authorization", "User-Agent", "Referer"},
+		},
+		{
+			name:       "host header overrides request host",
+			cmd:        `curl -H 'Host: internal.example.com' --url http://10.0.0.1/health`,
+			wantMethod: "GET",
+			wantHost:   "internal.example.com",
+			wantPath:   "/health",
+		},
+		{
+			name:       "repeated cookies joined",
+			cmd:        `curl -b a=1 --cookie 'b=2; c=3' https://a.com/`,
+			wantMethod: "GET",
+			wantHost:   "a.com",
+			wantPath:   "/",
+			wantHeaders: map[string]string{
+				"Cookie": "a=1; b=2; c=3",
+			},
+			wantOrder: []string{"Cookie"},
+		},
+		{
+			name:       "cookie jar skipped",
+			cmd:        `curl -b cookies.txt https://a.com/`,
+			wantMethod: "GET",
+			wantHost:   "a.com",
+			wantPath:   "/",
+			wantHeaders: map[string]string{
+				"Cookie": "",
+			},
+		},
+		{
+			name:       "head request",
+			cmd:        `curl -I https://a.com/`,
+			wantMethod: "HEAD",
+			wantHost:   "a.com",
+			wantPath:   "/",
+		},
+		{
+			name:    "missing url",
+			cmd:     `curl -X POST`,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported option",
+			cmd:     `curl --unknown-flag https://a.com`,
+			wantErr: true,
+		},
+		{
+			name:    "not curl",
+			cmd:     `wget https://a.com`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, order, err := ParseCurlCommand(tt.cmd)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCurlCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if req.Method != tt.wantMethod {
+				t.Errorf("method = %q, want %q", req.Method, tt.wantMethod)
+			}
+			if req.Host != tt.wantHost {
+				t.Errorf("host = %q, want %q", req.Host, tt.wantHost)
+			}
+			if req.URL.Path != tt.wantPath {
+				t.Errorf("path = %q, want %q", req.URL.Path, tt.wantPath)
+			}
+			if req.URL.RawQuery != tt.wantQuery {
+				t.Errorf("query = %q, want %q", req.URL.RawQuery, tt.wantQuery)
+			}
+			body, _ := io.ReadAll(req.Body)
+			if string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+			for k, v := range tt.wantHeaders {
+				if got := req.Header.Get(k); got != v {
+					t.Errorf("header %q = %q, want %q", k, got, v)
+				}
+			}
+			if tt.wantOrder != nil && !reflect.DeepEqual(order, tt.wantOrder) {
+				t.Errorf("order = %v, want %v", order, tt.wantOrder)
+			}
+		})
+	}
+}
+
+func TestParseCurlCommandMultipart(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "avatar.png")
+	os.WriteFile(file, []byte("PNGDATA"), 0o644)
+
+	cmd := `curl -F 'name=bob' -F 'avatar=@` + file + `;type=image/png' --form-string 'note=@literal' https://a.com/upload`
+	req, _, err := ParseCurlCommand(cmd)
+	if err != nil {
+		t.Fatalf("ParseCurlCommand() error = %v", err)
+	}
+	if req.Method != "POST" {
+		t.Errorf("method = %q, want POST", req.Method)
+	}
+
+	if err := req.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatalf("ParseMultipartForm() error = %v", err)
+	}
+	if got := req.FormValue("name"); got != "bob" {
+		t.Errorf("name = %q, want bob", got)
+	}
+	if got := req.FormValue("note"); got != "@literal" {
+		t.Errorf("note = %q, want @literal", got)
+	}
+	files := req.MultipartForm.File["avatar"]
+	if len(files) != 1 {
+		t.Fatalf("avatar files = %d, want 1", len(files))
+	}
+	if files[0].Filename != "avatar.png" || files[0].Header.Get("Content-Type") != "image/png" {
+		t.Errorf("avatar file = %q %q", files[0].Filename, files[0].Header.Get("Content-Type"))
+	}
+
+	if _, _, err := ParseCurlCommand(`curl -F 'f=@/missing/file' https://a.com/`); err == nil {
+		t.Errorf("ParseCurlCommand() with missing form file should return error")
+	}
+
+	// commands posted over the network can't read local files
+	for _, cmd := range []string{
+		`curl -d @` + file + ` https://a.com/`,
+		`curl --data-urlencode q@` + file + ` https://a.com/`,
+		`curl -F f=@` + file + ` https://a.com/`,
+		`curl -F 'f=<` + file + `' https://a.com/`,
+	} {
+		if _, _, err := parseCurlCommand(cmd, true); err == nil || !strings.Contains(err.Error(), "not allowed") {
+			t.Errorf("parseCurlCommand(%q, true) error = %v, want not allowed", cmd, err)
+		}
+	}
+}
+
+func TestParseRequestDetectsCurl(t *testing.T) {
+	req, _, err := ParseRequest([]byte("curl -X PUT https://api.example.com/x -d '{}'"))
+	if err != nil {
+		t.Fatalf("ParseRequest() error = %v", err)
+	}
+	if req.Method != "PUT" || req.Host != "api.example.com" {
+		t.Errorf("ParseRequest() = %s %s, want PUT api.example.com", req.Method, req.Host)
+	}
+
+	req, order, err := ParseRequest([]byte("GET /a HTTP/1.1\r\nHost: b.com\r\nAccept: */*\r\n\r\n"))
+	if err != nil {
+		t.Fatalf("ParseRequest() error = %v", err)
+	}
+	if req.Host != "b.com" || !strings.EqualFold(strings.Join(order, ","), "Host,Accept") {
+		t.Errorf("ParseRequest() host = %q, order = %v", req.Host, order)
+	}
+}
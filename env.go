@@ -30,6 +30,9 @@ func EnvToBody(body string, env *BrunoEnv) string {
 		keys = append(keys, k)
 	}
 	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) == len(keys[j]) {
+			return keys[i] < keys[j]
+		}
 		return len(keys[i]) > len(keys[j])
 	})
 
@@ -126,21 +129,74 @@ func ParseBrunoEnv(content string) (*BrunoEnv, error) {
 	return env, nil
 }
 
-// EnvGenerate generate file content from map
+// EnvSetVars sets variables in the vars block of the env file,
+// other content of the file is kept as is
+func EnvSetVars(path string, vars Pairs) error {
+	doc, err := BruFromFile(path)
+	if err != nil {
+		return err
+	}
+
+	block := doc.Block("vars")
+	if block == nil {
+		block = NewBruDict("vars", nil)
+		doc.AddBlock(block)
+	}
+	for _, v := range vars {
+		block.Set(v.Key, v.Value)
+	}
+
+	return doc.WriteFile(path)
+}
+
+// envAddVars adds vars to the loaded env
+func envAddVars(env *BrunoEnv, vars Pairs) {
+	if env == nil {
+		return
+	}
+	if env.Vars == nil {
+		env.Vars = make(map[string]string)
+	}
+	if env.ReverseVars == nil {
+		env.ReverseVars = make(map[string]string)
+	}
+	for _, v := range vars {
+		env.Vars[v.Key] = v.Value
+		if v.Value != "" {
+			env.ReverseVars[v.Value] = v.Key
+		}
+	}
+}
+
+// uniqueVarName returns base, or base_2, base_3... if the name is taken
+// by env or pending variables
+func uniqueVarName(env *BrunoEnv, pending Pairs, base string) string {
+	name := base
+	for i := 2; ; i++ {
+		_, inEnv := env.Vars[name]
+		_, inPending := pending.Get(name)
+		if !inEnv && !inPending {
+			return name
+		}
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+}
+
+// EnvGenerate generate file content from ordered vars
 // env block in format
 //
 //	vars {
 //	  User-Agent: go1.1
 //	}
-func EnvGenerate(vars map[string]string) string {
+func EnvGenerate(vars Pairs) string {
 	return NameBlockMap("vars", vars)
 }
 
 func DefaultEnvBru(name string) string {
-	vars := make(map[string]string)
-	vars["host"] = name
-	vars["proto"] = "https"
-	vars["ua"] = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/143.0.0.0 Safari/537.36"
+	var vars Pairs
+	vars.Add("host", name)
+	vars.Add("proto", "https")
+	vars.Add("ua", "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/143.0.0.0 Safari/537.36")
 
 	return EnvGenerate(vars)
 }
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -492,17 +494,17 @@ func TestEnvToBody(t *testing.T) {
 func TestEnvGenerate(t *testing.T) {
 	tests := []struct {
 		name     string
-		vars     map[string]string
+		vars     Pairs
 		contains []string
 	}{
 		{
 			name:     "empty map returns empty",
-			vars:     map[string]string{},
+			vars:     Pairs{},
 			contains: []string{},
 		},
 		{
 			name: "single variable",
-			vars: map[string]string{"host": "example.com"},
+			vars: Pairs{{"host", "example.com"}},
 			contains: []string{
 				"vars {",
 				"host: example.com",
@@ -511,9 +513,9 @@ func TestEnvGenerate(t *testing.T) {
 		},
 		{
 			name: "multiple variables",
-			vars: map[string]string{
-				"host":  "example.com",
-				"proto": "https",
+			vars: Pairs{
+				{"host", "example.com"},
+				{"proto", "https"},
 			},
 			contains: []string{
 				"vars {",
@@ -603,3 +605,31 @@ func TestBrunoEnvReverseMapping(t *testing.T) {
 		t.Errorf("ReverseVars = %v, want %v", env.ReverseVars, expectedReverse)
 	}
 }
+
+func TestEnvSetVars(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "base.bru")
+	content := "vars {\n  host: a.com\n  ~token: old\n}\n\nvars:secret [\n  key\n]\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := EnvSetVars(path, Pairs{{"token", "new"}, {"user", "bob"}}); err != nil {
+		t.Fatalf("EnvSetVars() error = %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	expected := "vars {\n  host: a.com\n  token: new\n  user: bob\n}\n\nvars:secret [\n  key\n]\n"
+	if string(data) != expected {
+		t.Errorf("EnvSetVars() content = %q, want %q", string(data), expected)
+	}
+
+	missing := filepath.Join(t.TempDir(), "empty.bru")
+	os.WriteFile(missing, nil, 0o644)
+	if err := EnvSetVars(missing, Pairs{{"token", "x"}}); err != nil {
+		t.Fatalf("EnvSetVars() error = %v", err)
+	}
+	data, _ = os.ReadFile(missing)
+	if string(data) != "vars {\n  token: x\n}\n" {
+		t.Errorf("EnvSetVars() new block = %q", string(data))
+	}
+}
@@ -0,0 +1,674 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ExportRequest request of the .bru file with inherited headers and auth
+// applied and variables resolved, ready to be sent
+type ExportRequest struct {
+	Name    string
+	Method  string
+	URL     string
+	Headers Pairs
+	Body    string
+	// FormParts parts of multipart body, file parts have absolute Path
+	FormParts []FormPart
+	// BodyPath absolute path of the `body:file` body file
+	BodyPath string
+}
+
+// ExportFormats supported -format values of -o export
+var ExportFormats = []string{"raw", "curl", "har"}
+
+var bruPathParamRe = regexp.MustCompile(`/:([A-Za-z_][\w\-]*)`)
+
+// bodyContentTypes Content-Type of text body modes,
+// used when the request has no Content-Type header
+var bodyContentTypes = map[string]string{
+	"json":    "application/json",
+	"xml":     "application/xml",
+	"text":    "text/plain",
+	"sparql":  "application/sparql-query",
+	"graphql": "application/json",
+}
+
+// DoExport prints the .bru request as raw HTTP request, curl command or HAR.
+// Headers and auth inherited from collection.bru and folder.bru files are
+// applied, {{vars}} are resolved with the env file relative to the collection
+// dir. HAR format accepts a directory, all requests below it are exported.
+func DoExport(input, format, envfile string, w io.Writer) error {
+	if input == "" {
+		return fmt.Errorf("-i input file is required")
+	}
+	if !headerInList(format, ExportFormats) {
+		return fmt.Errorf("invalid -format flag: %q, expected %s", format, strings.Join(ExportFormats, "|"))
+	}
+
+	info, err := os.Stat(input)
+	if err != nil {
+		return fmt.Errorf("read %q file error %w", input, err)
+	}
+
+	var files []string
+	switch {
+	case info.IsDir() && format != "har":
+		return fmt.Errorf("-format %s exports a single .bru file, %q is a directory", format, input)
+	case info.IsDir():
+		if files, err = requestFiles(input); err != nil {
+			return err
+		}
+	default:
+		files = []string{input}
+	}
+
+	collDir, err := findCollectionRoot(input)
+	if err != nil {
+		return err
+	}
+	env, err := EnvFromFile(filepath.Join(collDir, envfile))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[W] env file isn't loaded, variables aren't resolved: %s\n", err)
+		env = &BrunoEnv{}
+	}
+
+	var requests []*ExportRequest
+	for _, fp := range files {
+		req, err := LoadExportRequest(fp, collDir, env)
+		if err != nil {
+			if format != "har" {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "[W] skip %s: %s\n", fp, err)
+			continue
+		}
+		requests = append(requests, req)
+	}
+
+	switch format {
+	case "raw":
+		raw, err := requests[0].Raw()
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(raw)
+		return err
+	case "curl":
+		_, err := fmt.Fprintln(w, requests[0].Curl())
+		return err
+	}
+
+	data, err := json.MarshalIndent(ExportHAR(requests), "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal har error %w", err)
+	}
+	if _, err := fmt.Fprintf(w, "%s\n", data); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "[I] exported requests: %d, skipped: %d\n", len(requests), len(files)-len(requests))
+	return nil
+}
+
+// findCollectionRoot returns the nearest directory with bruno.json
+// containing the path
+func findCollectionRoot(path string) (string, error) {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "bruno.json")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("%q is not inside a bruno collection", path)
+		}
+		dir = parent
+	}
+}
+
+// requestFiles returns request .bru files below dir, ordered by
+// directory and seq. Environments, folder.bru and collection.bru are skipped.
+func requestFiles(dir string) ([]string, error) {
+	type requestFile struct {
+		path string
+		seq  int
+	}
+	var files []requestFile
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != dir && (name == "environments" || name == "node_modules" || strings.HasPrefix(name, ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(name) != ".bru" || name == "folder.bru" || name == "collection.bru" {
+			return nil
+		}
+		seq := 0
+		if doc, err := BruFromFile(path); err == nil {
+			if meta := doc.Block("meta"); meta != nil {
+				value, _ := meta.Get("seq")
+				seq, _ = strconv.Atoi(value)
+			}
+		}
+		files = append(files, requestFile{path, seq})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read %q dir error %w", dir, err)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		di, dj := filepath.Dir(files[i].path), filepath.Dir(files[j].path)
+		if di != dj {
+			return di < dj
+		}
+		return files[i].seq < files[j].seq
+	})
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.path
+	}
+	return paths, nil
+}
+
+// LoadExportRequest reads the .bru request and applies collection.bru
+// and folder.bru headers, auth and vars. Variables are resolved in
+// bruno precedence: request, folder, env, collection.
+func LoadExportRequest(path, collDir string, env *BrunoEnv) (*ExportRequest, error) {
+	doc, err := BruFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var method *BruBlock
+	for _, name := range httpMethodBlocks {
+		if method = doc.Block(name); method != nil {
+			break
+		}
+	}
+	if method == nil {
+		return nil, fmt.Errorf("%q has no http method block", path)
+	}
+
+	// parents from collection.bru to the nearest folder.bru
+	parents := bruParents(collDir, filepath.Dir(path))
+
+	vars := map[string]string{}
+	addBruVars(vars, parents[0])
+	for k, v := range env.Vars {
+		vars[k] = v
+	}
+	for _, parent := range parents[1:] {
+		addBruVars(vars, parent)
+	}
+	addBruVars(vars, doc)
+	resolver := newVarResolver(vars)
+
+	req := &ExportRequest{Method: strings.ToUpper(method.Name)}
+	if meta := doc.Block("meta"); meta != nil {
+		req.Name, _ = meta.Get("name")
+	}
+	if req.Name == "" {
+		req.Name = strings.TrimSuffix(filepath.Base(path), ".bru")
+	}
+
+	u, _ := method.Get("url")
+	req.URL = resolver.resolve(u)
+	if params := doc.Block("params:path"); params != nil {
+		req.URL = resolvePathParams(req.URL, params.Pairs(), resolver)
+	}
+
+	for _, layer := range append(parents, doc) {
+		if headers := layer.Block("headers"); headers != nil {
+			for _, h := range headers.Pairs() {
+				setHeader(&req.Headers, h.Key, resolver.resolve(h.Value))
+			}
+		}
+	}
+
+	mode, _ := method.Get("auth")
+	authDoc := doc
+	if mode == "inherit" {
+		mode, authDoc = inheritedAuthMode(parents)
+	}
+	if err := req.applyAuth(mode, authDoc, resolver); err != nil {
+		fmt.Fprintf(os.Stderr, "[W] %s: %s\n", path, err)
+	}
+
+	bodyMode, _ := method.Get("body")
+	if err := req.setBody(doc, bodyMode, collDir, resolver); err != nil {
+		return nil, err
+	}
+
+	if len(resolver.missing) > 0 {
+		fmt.Fprintf(os.Stderr, "[W] %s: unresolved variables: %s\n", path, strings.Join(resolver.missing, ", "))
+	}
+	return req, nil
+}
+
+// bruParents returns parsed collection.bru and folder.bru files
+// from the collection dir down to dir. The first doc is always
+// collection.bru, empty if missing, missing folder.bru files are skipped.
+func bruParents(collDir, dir string) []*BruDoc {
+	collection, err := BruFromFile(filepath.Join(collDir, "collection.bru"))
+	if err != nil {
+		collection = &BruDoc{}
+	}
+	docs := []*BruDoc{collection}
+
+	dir, _ = filepath.Abs(dir)
+	rel, err := filepath.Rel(collDir, dir)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return docs
+	}
+	current := collDir
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		if doc, err := BruFromFile(filepath.Join(current, "folder.bru")); err == nil {
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
+
+// inheritedAuthMode returns auth mode of the nearest parent
+// which doesn't inherit auth itself
+func inheritedAuthMode(parents []*BruDoc) (string, *BruDoc) {
+	for i := len(parents) - 1; i >= 0; i-- {
+		block := parents[i].Block("auth")
+		if block == nil {
+			continue
+		}
+		if mode, _ := block.Get("mode"); mode != "" && mode != "inherit" {
+			return mode, parents[i]
+		}
+	}
+	return "none", nil
+}
+
+func addBruVars(vars map[string]string, doc *BruDoc) {
+	if block := doc.Block("vars:pre-request"); block != nil {
+		for _, v := range block.Pairs() {
+			vars[v.Key] = v.Value
+		}
+	}
+}
+
+// varResolver resolves {{vars}} and remembers unknown variables
+type varResolver struct {
+	vars    Pairs
+	missing []string
+}
+
+func newVarResolver(vars map[string]string) *varResolver {
+	r := &varResolver{}
+	for k, v := range vars {
+		r.vars.Add(k, v)
+	}
+	return r
+}
+
+func (r *varResolver) resolve(s string) string {
+	s = ResolveHTTPFileVars(s, r.vars)
+	for _, m := range httpFileRefRe.FindAllStringSubmatch(s, -1) {
+		if !headerInList(m[1], r.missing) {
+			r.missing = append(r.missing, m[1])
+		}
+	}
+	return s
+}
+
+// resolvePathParams replaces :name path segments with params:path values
+func resolvePathParams(u string, params Pairs, resolver *varResolver) string {
+	path, query, hasQuery := strings.Cut(u, "?")
+	path = bruPathParamRe.ReplaceAllStringFunc(path, func(segment string) string {
+		if value, ok := params.Get(segment[2:]); ok {
+			return "/" + resolver.resolve(value)
+		}
+		return segment
+	})
+	if hasQuery {
+		return path + "?" + query
+	}
+	return path
+}
+
+// setHeader replaces the header case insensitive, or appends it
+func setHeader(headers *Pairs, key, value string) {
+	for i := range *headers {
+		if strings.EqualFold((*headers)[i].Key, key) {
+			(*headers)[i].Value = value
+			return
+		}
+	}
+	headers.Add(key, value)
+}
+
+func hasHeader(headers Pairs, key string) bool {
+	for _, h := range headers {
+		if strings.EqualFold(h.Key, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyAuth adds credentials of the `auth:<mode>` block
+// as header or query param
+func (r *ExportRequest) applyAuth(mode string, doc *BruDoc, resolver *varResolver) error {
+	if mode == "" || mode == "none" || doc == nil {
+		return nil
+	}
+	block := doc.Block("auth:" + mode)
+	if block == nil {
+		return fmt.Errorf("auth:%s block is missing", mode)
+	}
+	get := func(key string) string {
+		value, _ := block.Get(key)
+		return resolver.resolve(value)
+	}
+
+	switch mode {
+	case "bearer":
+		setHeader(&r.Headers, "Authorization", "Bearer "+get("token"))
+	case "basic":
+		creds := base64.StdEncoding.EncodeToString([]byte(get("username") + ":" + get("password")))
+		setHeader(&r.Headers, "Authorization", "Basic "+creds)
+	case "apikey":
+		if get("placement") == "queryparams" {
+			sep := "?"
+			if strings.Contains(r.URL, "?") {
+				sep = "&"
+			}
+			r.URL += sep + get("key") + "=" + get("value")
+			return nil
+		}
+		setHeader(&r.Headers, get("key"), get("value"))
+	default:
+		return fmt.Errorf("auth mode %s can't be exported, credentials are omitted", mode)
+	}
+	return nil
+}
+
+// setBody sets the body of the bruno body mode and Content-Type,
+// if the request has no Content-Type header
+func (r *ExportRequest) setBody(doc *BruDoc, mode, collDir string, resolver *varResolver) error {
+	contentType := ""
+	switch mode {
+	case "", "none":
+		return nil
+	case "formUrlEncoded":
+		var parts []string
+		if block := doc.Block("body:form-urlencoded"); block != nil {
+			for _, p := range block.Pairs() {
+				parts = append(parts, p.Key+"="+resolver.resolve(p.Value))
+			}
+		}
+		r.Body = strings.Join(parts, "&")
+		contentType = "application/x-www-form-urlencoded"
+	case "multipartForm":
+		if block := doc.Block("body:multipart-form"); block != nil {
+			for _, p := range block.Pairs() {
+				part := ParseFormPartValue(p.Key, resolver.resolve(p.Value))
+				if part.Path != "" {
+					part.Path = collectionFilePath(collDir, part.Path)
+				}
+				r.FormParts = append(r.FormParts, part)
+			}
+		}
+		body, ct, err := multipartBody(r.FormParts, collDir)
+		if err != nil {
+			return err
+		}
+		r.Body, contentType = body, ct
+		setHeader(&r.Headers, "Content-Type", contentType)
+	case "file":
+		var part FormPart
+		if block := doc.Block("body:file"); block != nil {
+			for _, p := range block.Pairs() {
+				if part = ParseFormPartValue(p.Key, resolver.resolve(p.Value)); part.Path != "" {
+					break
+				}
+			}
+		}
+		if part.Path == "" {
+			return fmt.Errorf("body:file block has no file")
+		}
+		fp := collectionFilePath(collDir, part.Path)
+		data, err := os.ReadFile(fp)
+		if err != nil {
+			return fmt.Errorf("read body file error %w", err)
+		}
+		r.Body, r.BodyPath = string(data), fp
+		contentType = part.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+	case "graphql":
+		body := map[string]any{"query": resolver.resolve(bruBlockText(doc, "body:graphql"))}
+		if vars := strings.TrimSpace(resolver.resolve(bruBlockText(doc, "body:graphql:vars"))); vars != "" {
+			body["variables"] = json.RawMessage(vars)
+		}
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("graphql variables aren't valid json: %w", err)
+		}
+		r.Body, contentType = string(data), bodyContentTypes[mode]
+	default:
+		r.Body = resolver.resolve(bruBlockText(doc, "body:"+mode))
+		contentType = bodyContentTypes[mode]
+	}
+
+	if contentType != "" && !hasHeader(r.Headers, "Content-Type") {
+		r.Headers.Add("Content-Type", contentType)
+	}
+	return nil
+}
+
+// collectionFilePath returns absolute path of the file referenced
+// relative to the collection dir, exported curl commands run from
+// any dir
+func collectionFilePath(collDir, path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(collDir, path)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+func bruBlockText(doc *BruDoc, name string) string {
+	if block := doc.Block(name); block != nil {
+		return block.Text
+	}
+	return ""
+}
+
+// multipartBody builds multipart/form-data body, file parts
+// are read relative to the collection dir
+func multipartBody(parts []FormPart, collDir string) (string, string, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for _, p := range parts {
+		h := make(textproto.MIMEHeader)
+		disposition := fmt.Sprintf(`form-data; name=%q`, p.Name)
+		value := []byte(p.Value)
+		if p.Path != "" {
+			fp := p.Path
+			if !filepath.IsAbs(fp) {
+				fp = filepath.Join(collDir, fp)
+			}
+			data, err := os.ReadFile(fp)
+			if err != nil {
+				return "", "", fmt.Errorf("read form file error %w", err)
+			}
+			value = data
+			disposition += fmt.Sprintf(`; filename=%q`, p.FileName)
+		}
+		h.Set("Content-Disposition", disposition)
+		if p.ContentType != "" {
+			h.Set("Content-Type", p.ContentType)
+		}
+		pw, err := mw.CreatePart(h)
+		if err != nil {
+			return "", "", err
+		}
+		pw.Write(value)
+	}
+	if err := mw.Close(); err != nil {
+		return "", "", err
+	}
+	return buf.String(), mw.FormDataContentType(), nil
+}
+
+// splitURL splits the url into scheme, host and request target without
+// re-encoding, urls are written with `encodeUrl: false`
+func splitURL(u string) (string, string, string) {
+	scheme, rest, ok := strings.Cut(u, "://")
+	if !ok {
+		scheme, rest = "http", u
+	}
+	i := strings.IndexAny(rest, "/?")
+	if i < 0 {
+		return scheme, rest, "/"
+	}
+	target := rest[i:]
+	if strings.HasPrefix(target, "?") {
+		target = "/" + target
+	}
+	return scheme, rest[:i], target
+}
+
+// Raw returns HTTP/1.1 request, Host and Content-Length are added
+// if missing
+func (r *ExportRequest) Raw() ([]byte, error) {
+	_, host, target := splitURL(r.URL)
+	if host == "" {
+		return nil, fmt.Errorf("request %q has no host", r.Name)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s HTTP/1.1\r\n", r.Method, target)
+	if !hasHeader(r.Headers, "Host") {
+		fmt.Fprintf(&buf, "Host: %s\r\n", host)
+	}
+	for _, h := range r.Headers {
+		fmt.Fprintf(&buf, "%s: %s\r\n", h.Key, h.Value)
+	}
+	if r.Body != "" && !hasHeader(r.Headers, "Content-Length") {
+		fmt.Fprintf(&buf, "Content-Length: %d\r\n", len(r.Body))
+	}
+	buf.WriteString("\r\n")
+	buf.WriteString(r.Body)
+	return buf.Bytes(), nil
+}
+
+// Curl returns curl command, multipart parts are written as -F options
+func (r *ExportRequest) Curl() string {
+	// one option per line
+	args := []string{"curl"}
+	// curl sends POST if the request has body, GET otherwise
+	implicit := "GET"
+	if r.Body != "" {
+		implicit = "POST"
+	}
+	switch {
+	case r.Method == "HEAD":
+		// -X HEAD waits for the body which never comes
+		args = append(args, "-I")
+	case r.Method != implicit:
+		args = append(args, "-X "+r.Method)
+	}
+	args = append(args, shellQuote(r.URL))
+	for _, h := range r.Headers {
+		if len(r.FormParts) > 0 && strings.EqualFold(h.Key, "Content-Type") {
+			continue
+		}
+		args = append(args, "-H "+shellQuote(h.Key+": "+h.Value))
+	}
+
+	switch {
+	case len(r.FormParts) > 0:
+		for _, p := range r.FormParts {
+			value := p.Value
+			if p.Path != "" {
+				value = "@" + p.Path
+			}
+			if p.ContentType != "" {
+				value += ";type=" + p.ContentType
+			}
+			args = append(args, "-F "+shellQuote(p.Name+"="+value))
+		}
+	case r.BodyPath != "":
+		args = append(args, "--data-binary "+shellQuote("@"+r.BodyPath))
+	case r.Body != "":
+		args = append(args, "--data-raw "+shellQuote(r.Body))
+	}
+	return strings.Join(args, " \\\n  ")
+}
+
+// shellQuote quotes the argument for POSIX shells
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ExportHAR returns HAR 1.2 archive of the requests without responses
+func ExportHAR(requests []*ExportRequest) HAR {
+	har := HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: &HARCreator{Name: "http2bruno", Version: "1.0"},
+		Entries: []HAREntry{},
+	}}
+	for _, r := range requests {
+		hr := HARRequest{
+			Method:      r.Method,
+			URL:         r.URL,
+			HTTPVersion: "HTTP/1.1",
+			Headers:     []HARNameValue{},
+			QueryString: []HARNameValue{},
+		}
+		for _, h := range r.Headers {
+			hr.Headers = append(hr.Headers, HARNameValue{Name: h.Key, Value: h.Value})
+		}
+		if _, query, ok := strings.Cut(r.URL, "?"); ok {
+			for _, q := range ParseQuery(query) {
+				hr.QueryString = append(hr.QueryString, HARNameValue{Name: q.Key, Value: q.Value})
+			}
+		}
+		if r.Body != "" {
+			ct := ""
+			for _, h := range r.Headers {
+				if strings.EqualFold(h.Key, "Content-Type") {
+					ct = h.Value
+				}
+			}
+			hr.PostData = &HARPostData{MimeType: ct, Text: r.Body}
+		}
+		har.Log.Entries = append(har.Log.Entries, HAREntry{
+			Request:  hr,
+			Response: HARResponse{Headers: []HARNameValue{}},
+		})
+	}
+	return har
+}
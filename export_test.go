@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testExportCollection writes a collection with inherited headers,
+// auth and vars, returns the collection dir
+func testExportCollection(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"bruno.json": `{"version": "1", "name": "api", "type": "collection"}`,
+		"collection.bru": `headers {
+  User-Agent: {{ua}}
+  X-Trace: collection
+}
+
+auth {
+  mode: bearer
+}
+
+auth:bearer {
+  token: {{token}}
+}
+
+vars:pre-request {
+  version: v0
+}
+`,
+		"environments/base.bru": `vars {
+  proto: https
+  host: api.example.com
+  ua: test-agent
+  token: secret
+  version: v1
+}
+`,
+		"users/folder.bru": `meta {
+  name: users
+}
+
+headers {
+  X-Trace: folder
+  ~X-Disabled: 1
+}
+`,
+		"users/update-user.bru": `meta {
+  name: update-user
+  type: http
+  seq: 2
+}
+
+put {
+  url: {{proto}}://{{host}}/{{version}}/users/:id?notify=true
+  body: json
+  auth: inherit
+}
+
+params:path {
+  id: {{user_id}}
+}
+
+vars:pre-request {
+  user_id: 42
+}
+
+body:json {
+  {"name": "{{name}}"}
+}
+`,
+		"users/list-users.bru": `meta {
+  name: list-users
+  type: http
+  seq: 1
+}
+
+get {
+  url: {{proto}}://{{host}}/users
+  body: none
+  auth: apikey
+}
+
+auth:apikey {
+  key: api_key
+  value: {{token}}
+  placement: queryparams
+}
+`,
+		"upload.bru": `meta {
+  name: upload
+  type: http
+  seq: 1
+}
+
+post {
+  url: {{proto}}://{{host}}/upload
+  body: multipartForm
+  auth: none
+}
+
+body:multipart-form {
+  title: avatar
+  file: @file(files/a.txt) @contentType(text/plain)
+}
+`,
+		"files/a.txt": "hello",
+	}
+	for name, content := range files {
+		fp := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(fp), 0o755)
+		if err := os.WriteFile(fp, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadExportRequest(t *testing.T) {
+	dir := testExportCollection(t)
+	env, _ := EnvFromFile(filepath.Join(dir, "environments", "base.bru"))
+
+	req, err := LoadExportRequest(filepath.Join(dir, "users", "update-user.bru"), dir, env)
+	if err != nil {
+		t.Fatalf("LoadExportRequest() error = %v", err)
+	}
+	if req.Method != "PUT" || req.URL != "https://api.example.com/v1/users/42?notify=true" {
+		t.Errorf("request = %s %s", req.Method, req.URL)
+	}
+	want := Pairs{
+		{"User-Agent", "test-agent"},
+		{"X-Trace", "folder"},
+		{"Authorization", "Bearer secret"},
+		{"Content-Type", "application/json"},
+	}
+	if !reflect.DeepEqual(req.Headers, want) {
+		t.Errorf("headers = %v, want %v", req.Headers, want)
+	}
+	// unknown variables are kept
+	if req.Body != `{"name": "{{name}}"}` {
+		t.Errorf("body = %q", req.Body)
+	}
+
+	req, err = LoadExportRequest(filepath.Join(dir, "users", "list-users.bru"), dir, env)
+	if err != nil {
+		t.Fatalf("LoadExportRequest() error = %v", err)
+	}
+	if req.URL != "https://api.example.com/users?api_key=secret" {
+		t.Errorf("apikey url = %q", req.URL)
+	}
+	if hasHeader(req.Headers, "Authorization") {
+		t.Errorf("apikey request should not inherit bearer auth, headers = %v", req.Headers)
+	}
+}
+
+func TestExportRequestRaw(t *testing.T) {
+	req := &ExportRequest{
+		Method:  "POST",
+		URL:     "https://api.example.com:8443?q=1",
+		Headers: Pairs{{"Content-Type", "application/json"}},
+		Body:    `{"a":1}`,
+	}
+	raw, err := req.Raw()
+	if err != nil {
+		t.Fatalf("Raw() error = %v", err)
+	}
+	want := "POST /?q=1 HTTP/1.1\r\nHost: api.example.com:8443\r\nContent-Type: application/json\r\nContent-Length: 7\r\n\r\n{\"a\":1}"
+	if string(raw) != want {
+		t.Errorf("Raw() = %q, want %q", raw, want)
+	}
+
+	parsed, err := ParseRawRequest(raw)
+	if err != nil {
+		t.Fatalf("ParseRawRequest() error = %v", err)
+	}
+	if parsed.Host != "api.example.com:8443" || parsed.URL.RawQuery != "q=1" {
+		t.Errorf("parsed host = %q, query = %q", parsed.Host, parsed.URL.RawQuery)
+	}
+}
+
+func TestExportRequestCurl(t *testing.T) {
+	req := &ExportRequest{
+		Method:  "PATCH",
+		URL:     "https://api.example.com/users/1",
+		Headers: Pairs{{"Content-Type", "application/json"}},
+		Body:    `{"name":"O'Brien"}`,
+	}
+	cmd := req.Curl()
+	parsed, _, err := ParseCurlCommand(cmd)
+	if err != nil {
+		t.Fatalf("ParseCurlCommand(%q) error = %v", cmd, err)
+	}
+	body, _ := io.ReadAll(parsed.Body)
+	if parsed.Method != "PATCH" || parsed.URL.String() != req.URL || string(body) != req.Body {
+		t.Errorf("curl round trip = %s %s %q\n%s", parsed.Method, parsed.URL, body, cmd)
+	}
+
+	get := &ExportRequest{Method: "GET", URL: "https://api.example.com/"}
+	if got := get.Curl(); got != "curl \\\n  'https://api.example.com/'" {
+		t.Errorf("Curl() = %q", got)
+	}
+
+	head := &ExportRequest{Method: "HEAD", URL: "https://api.example.com/"}
+	if got := head.Curl(); got != "curl \\\n  -I \\\n  'https://api.example.com/'" {
+		t.Errorf("Curl() of HEAD = %q", got)
+	}
+}
+
+func TestDoExport(t *testing.T) {
+	dir := testExportCollection(t)
+
+	var buf bytes.Buffer
+	if err := DoExport(filepath.Join(dir, "upload.bru"), "curl", "environments/base.bru", &buf); err != nil {
+		t.Fatalf("DoExport() error = %v", err)
+	}
+	// file paths are absolute, the command runs from any dir
+	for _, want := range []string{"-F 'title=avatar'", "-F 'file=@" + filepath.Join(dir, "files", "a.txt") + ";type=text/plain'"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("curl should contain %q\ngot:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := DoExport(filepath.Join(dir, "upload.bru"), "raw", "environments/base.bru", &buf); err != nil {
+		t.Fatalf("DoExport() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "Content-Type: multipart/form-data; boundary=") || !strings.Contains(buf.String(), "\r\n\r\nhello\r\n") {
+		t.Errorf("raw multipart request:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := DoExport(dir, "har", "environments/base.bru", &buf); err != nil {
+		t.Fatalf("DoExport() error = %v", err)
+	}
+	var har HAR
+	if err := json.Unmarshal(buf.Bytes(), &har); err != nil {
+		t.Fatalf("har output error = %v", err)
+	}
+	var urls []string
+	for _, e := range har.Log.Entries {
+		urls = append(urls, e.Request.Method+" "+e.Request.URL)
+	}
+	want := "POST https://api.example.com/upload," +
+		"GET https://api.example.com/users?api_key=secret," +
+		"PUT https://api.example.com/v1/users/42?notify=true"
+	if got := strings.Join(urls, ","); got != want {
+		t.Errorf("har entries = %s, want %s", got, want)
+	}
+
+	if err := DoExport(dir, "curl", "environments/base.bru", &buf); err == nil {
+		t.Error("DoExport() expected error for directory with curl format")
+	}
+	if err := DoExport(filepath.Join(dir, "upload.bru"), "wget", "environments/base.bru", &buf); err == nil {
+		t.Error("DoExport() expected error for unknown format")
+	}
+}
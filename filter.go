@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RequestFilter selects requests by host and method.
+// Empty lists match everything.
+type RequestFilter struct {
+	// Hosts matches the host and its subdomains, port is ignored
+	Hosts []string
+	// Methods matches the method case insensitive
+	Methods []string
+}
+
+// NewRequestFilter creates filter from comma separated hosts and methods
+func NewRequestFilter(hosts, methods string) RequestFilter {
+	return RequestFilter{
+		Hosts:   ParseHeadersList(strings.ToLower(hosts)),
+		Methods: ParseHeadersList(methods),
+	}
+}
+
+// Match reports whether the request passes the filter
+func (f RequestFilter) Match(req *http.Request) bool {
+	if len(f.Methods) > 0 && !headerInList(req.Method, f.Methods) {
+		return false
+	}
+	if len(f.Hosts) == 0 {
+		return true
+	}
+
+	host := strings.ToLower(req.Host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	for _, allowed := range f.Hosts {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRequestFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		hosts   string
+		methods string
+		method  string
+		host    string
+		want    bool
+	}{
+		{"empty filter", "", "", "GET", "a.com", true},
+		{"host match", "a.com", "", "GET", "a.com", true},
+		{"subdomain match", "a.com", "", "GET", "api.a.com", true},
+		{"port ignored", "a.com", "", "GET", "a.com:8080", true},
+		{"case insensitive host", "A.com", "", "GET", "API.a.COM", true},
+		{"suffix is not subdomain", "a.com", "", "GET", "evila.com", false},
+		{"other host", "a.com, b.com", "", "GET", "c.com", false},
+		{"method match", "", "get,post", "POST", "a.com", true},
+		{"method mismatch", "", "GET", "DELETE", "a.com", false},
+		{"host and method", "a.com", "GET", "GET", "b.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewRequestFilter(tt.hosts, tt.methods)
+			req := &http.Request{Method: tt.method, Host: tt.host}
+			if got := f.Match(req); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
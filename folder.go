@@ -7,12 +7,12 @@ import "strings"
 //	meta {}
 //	headers {}
 func DefaultFolderBru(name string) string {
-	meta := make(map[string]string)
-	meta["name"] = name
+	var meta Pairs
+	meta.Add("name", name)
 
-	heads := make(map[string]string)
-	heads["Cookie"] = "{{cook}}"
-	heads["Authorization"] = "Bearer {{token}}"
+	var heads Pairs
+	heads.Add("Cookie", "{{cook}}")
+	heads.Add("Authorization", "Bearer {{token}}")
 
 	var sb strings.Builder
 	sb.WriteString(MetaGenerate(meta))
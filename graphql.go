@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// GraphQLRequest GraphQL operation of the captured request
+type GraphQLRequest struct {
+	Query string
+	// Variables indented JSON of the variables, empty if there're none
+	Variables     string
+	OperationName string
+}
+
+var (
+	// graphQLQueryRe matches GraphQL documents, leading comments are skipped
+	graphQLQueryRe = regexp.MustCompile(`^\s*(?:#[^\n]*\n\s*)*(?:(?:query|mutation|subscription|fragment)\b|\{)`)
+	// graphQLOperationRe matches the name of the first operation
+	graphQLOperationRe = regexp.MustCompile(`^\s*(?:#[^\n]*\n\s*)*(?:query|mutation|subscription)\s+([A-Za-z_]\w*)`)
+)
+
+// graphQLFields fields of GraphQL JSON bodies and params of GET requests.
+// Requests with extensions (e.g. persisted query hashes) aren't GraphQL
+// requests here, body:graphql has no place for them, so they are kept
+// as JSON bodies or url params.
+var graphQLFields = []string{"query", "variables", "operationName"}
+
+// DetectGraphQL returns the GraphQL operation of the request with
+// application/graphql body, JSON body of query, variables and
+// operationName fields, or GET request with the query param.
+// Returns nil for other requests.
+func DetectGraphQL(req *http.Request, body string) *GraphQLRequest {
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "application/graphql" && body != "":
+		return newGraphQLRequest(body, "", nil)
+	case req.Method == http.MethodGet && body == "" && req.URL != nil:
+		params := req.URL.Query()
+		if params.Has("extensions") || !graphQLQueryRe.MatchString(params.Get("query")) {
+			return nil
+		}
+		var vars json.RawMessage
+		if v := params.Get("variables"); v != "" && json.Valid([]byte(v)) {
+			vars = json.RawMessage(v)
+		}
+		return newGraphQLRequest(params.Get("query"), params.Get("operationName"), vars)
+	case body != "" && (mediaType == "application/json" || mediaType == ""):
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(body), &fields); err != nil {
+			return nil
+		}
+		for key := range fields {
+			if !slices.Contains(graphQLFields, key) {
+				return nil
+			}
+		}
+		var query, operationName string
+		if err := json.Unmarshal(fields["query"], &query); err != nil || !graphQLQueryRe.MatchString(query) {
+			return nil
+		}
+		json.Unmarshal(fields["operationName"], &operationName)
+		return newGraphQLRequest(query, operationName, fields["variables"])
+	}
+	return nil
+}
+
+func newGraphQLRequest(query, operationName string, vars json.RawMessage) *GraphQLRequest {
+	gql := &GraphQLRequest{
+		Query:         strings.Trim(query, "\r\n"),
+		OperationName: operationName,
+	}
+	if gql.OperationName == "" {
+		if m := graphQLOperationRe.FindStringSubmatch(query); m != nil {
+			gql.OperationName = m[1]
+		}
+	}
+	if len(vars) > 0 && string(vars) != "null" && string(vars) != "{}" {
+		gql.Variables = indentJSON(vars)
+	}
+	return gql
+}
+
+// withoutGraphQLParams removes GraphQL params from the raw query
+func withoutGraphQLParams(rawQuery string) string {
+	var kept []string
+	for _, param := range strings.Split(rawQuery, "&") {
+		key, _, _ := strings.Cut(param, "=")
+		if key, err := url.QueryUnescape(key); err == nil && slices.Contains(graphQLFields, key) {
+			continue
+		}
+		if param != "" {
+			kept = append(kept, param)
+		}
+	}
+	return strings.Join(kept, "&")
+}
+
+// RequestBodyGraphQL returns body:graphql and body:graphql:vars blocks
+func RequestBodyGraphQL(gql *GraphQLRequest) string {
+	block := NameBlockStrings("body:graphql", strings.Split(gql.Query, "\n"))
+	if gql.Variables != "" {
+		block += "\n" + NameBlockStrings("body:graphql:vars", strings.Split(gql.Variables, "\n"))
+	}
+	return block
+}
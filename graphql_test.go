@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectGraphQL(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		url         string
+		contentType string
+		body        string
+		want        *GraphQLRequest
+	}{
+		{
+			name:        "json body",
+			method:      "POST",
+			url:         "https://a.com/graphql",
+			contentType: "application/json",
+			body:        `{"query": "query GetUser($id: ID!) { user(id: $id) { name } }", "variables": {"id": "42"}, "operationName": "GetUser"}`,
+			want: &GraphQLRequest{
+				Query:         "query GetUser($id: ID!) { user(id: $id) { name } }",
+				Variables:     "{\n  \"id\": \"42\"\n}",
+				OperationName: "GetUser",
+			},
+		},
+		{
+			name:        "operation name from query",
+			method:      "POST",
+			url:         "https://a.com/graphql",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"query": "mutation Login { login { token } }", "variables": null}`,
+			want:        &GraphQLRequest{Query: "mutation Login { login { token } }", OperationName: "Login"},
+		},
+		{
+			name:        "application/graphql",
+			method:      "POST",
+			url:         "https://a.com/graphql",
+			contentType: "application/graphql",
+			body:        "{ users { id } }\n",
+			want:        &GraphQLRequest{Query: "{ users { id } }"},
+		},
+		{
+			name:   "get params",
+			method: "GET",
+			url:    "https://a.com/graphql?query=" + url.QueryEscape("query Me { me { id } }") + "&variables=" + url.QueryEscape(`{"a":1}`),
+			want:   &GraphQLRequest{Query: "query Me { me { id } }", Variables: "{\n  \"a\": 1\n}", OperationName: "Me"},
+		},
+		{
+			name:        "json with other fields",
+			method:      "POST",
+			url:         "https://a.com/search",
+			contentType: "application/json",
+			body:        `{"query": "{ a }", "limit": 10}`,
+		},
+		{
+			name:        "search query",
+			method:      "POST",
+			url:         "https://a.com/search",
+			contentType: "application/json",
+			body:        `{"query": "red shoes"}`,
+		},
+		{
+			name:        "json persisted query extensions",
+			method:      "POST",
+			url:         "https://a.com/graphql",
+			contentType: "application/json",
+			body:        `{"query": "query Me { me { id } }", "extensions": {"persistedQuery": {"version": 1, "sha256Hash": "abc"}}}`,
+		},
+		{
+			name:   "get persisted query extensions",
+			method: "GET",
+			url:    "https://a.com/graphql?query=" + url.QueryEscape("query Me { me { id } }") + "&extensions=" + url.QueryEscape(`{"persistedQuery":{"version":1,"sha256Hash":"abc"}}`),
+		},
+		{
+			name:   "get search query",
+			method: "GET",
+			url:    "https://a.com/search?query=shoes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.url, nil)
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			got := DetectGraphQL(req, tt.body)
+			switch {
+			case tt.want == nil && got != nil:
+				t.Errorf("DetectGraphQL() = %+v, want nil", got)
+			case tt.want != nil && (got == nil || *got != *tt.want):
+				t.Errorf("DetectGraphQL() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithoutGraphQLParams(t *testing.T) {
+	if got := withoutGraphQLParams("query=%7B+a+%7D&v=1&operationName=A&variables=%7B%7D"); got != "v=1" {
+		t.Errorf("withoutGraphQLParams() = %q", got)
+	}
+}
+
+func TestConvertGraphQLRequest(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := DoCollection("api.example.com", tmpDir); err != nil {
+		t.Fatalf("DoCollection() error = %v", err)
+	}
+	envPath := filepath.Join(tmpDir, "api.example.com", "environments", "base.bru")
+	EnvSetVars(envPath, Pairs{{"user_id", "12345"}})
+
+	body := `{"operationName": "GetUser", "variables": {"id": "12345"}, "query": "query GetUser($id: ID!) {\n  user(id: $id) { name }\n}"}`
+	raw := fmt.Sprintf("POST /graphql HTTP/1.1\r\nHost: api.example.com\r\nContent-Type: application/json\r\nContent-Length: %d\r\n\r\n%s", len(body), body)
+	req, order, err := ParseRequest([]byte(raw))
+	if err != nil {
+		t.Fatalf("ParseRequest() error = %v", err)
+	}
+	fp, err := ConvertRequest(req, order, tmpDir, "environments/base.bru", RequestOptions{})
+	if err != nil {
+		t.Fatalf("ConvertRequest() error = %v", err)
+	}
+	if filepath.Base(fp) != "GetUser.bru" {
+		t.Errorf("file = %q, want GetUser.bru", fp)
+	}
+	data, _ := os.ReadFile(fp)
+	for _, want := range []string{
+		"type: graphql",
+		"body: graphql",
+		"body:graphql {\n  query GetUser($id: ID!) {\n    user(id: $id) { name }\n  }\n}",
+		"body:graphql:vars {\n  {\n    \"id\": \"{{user_id}}\"\n  }\n}",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("request file should contain %q\ngot:\n%s", want, data)
+		}
+	}
+
+	// GraphQL params of GET requests move to the body blocks
+	raw = "GET /graphql?query=" + url.QueryEscape("{ me { id } }") + "&v=2 HTTP/1.1\r\nHost: api.example.com\r\n\r\n"
+	req, order, _ = ParseRequest([]byte(raw))
+	fp, err = ConvertRequest(req, order, tmpDir, "environments/base.bru", RequestOptions{})
+	if err != nil {
+		t.Fatalf("ConvertRequest() error = %v", err)
+	}
+	data, _ = os.ReadFile(fp)
+	if !strings.Contains(string(data), "url: {{proto}}://{{host}}/graphql?v=2\n") || !strings.Contains(string(data), "body:graphql {\n  { me { id } }\n}") {
+		t.Errorf("GET request file:\n%s", data)
+	}
+}
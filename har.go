@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// HAR http archive file, only fields used for conversion
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+type HARLog struct {
+	Version string      `json:"version,omitempty"`
+	Creator *HARCreator `json:"creator,omitempty"`
+	Entries []HAREntry  `json:"entries"`
+}
+
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type HAREntry struct {
+	Request  HARRequest  `json:"request"`
+	Response HARResponse `json:"response"`
+}
+
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+}
+
+type HARResponse struct {
+	Status  int            `json:"status"`
+	Headers []HARNameValue `json:"headers"`
+	Content HARContent     `json:"content"`
+}
+
+type HARContent struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HARPostData struct {
+	MimeType string         `json:"mimeType"`
+	Text     string         `json:"text"`
+	Params   []HARNameValue `json:"params,omitempty"`
+}
+
+// DoHAR converts every entry of the HAR file into .bru request
+// of the matching collection. Created files are printed to stdout,
+// skipped entries and summary to stderr.
+func DoHAR(input, basedir, envfile string, opts RequestOptions) error {
+	if input == "" {
+		return fmt.Errorf("-i input file is required")
+	}
+
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return fmt.Errorf("read %q file error %w", input, err)
+	}
+
+	var har HAR
+	if err := json.Unmarshal(data, &har); err != nil {
+		return fmt.Errorf("parse har file error %w", err)
+	}
+
+	created, skipped := 0, 0
+	for i, entry := range har.Log.Entries {
+		fp, err := convertHAREntry(entry, basedir, envfile, opts)
+		if err != nil {
+			skipped++
+			fmt.Fprintf(os.Stderr, "[W] skip entry %d %s %s: %s\n", i, entry.Request.Method, entry.Request.URL, err)
+			continue
+		}
+		created++
+		fmt.Println(fp)
+	}
+
+	fmt.Fprintf(os.Stderr, "[I] har entries: %d, created: %d, skipped: %d\n", len(har.Log.Entries), created, skipped)
+	return nil
+}
+
+func convertHAREntry(entry HAREntry, basedir, envfile string, opts RequestOptions) (string, error) {
+	req, order, err := HARRequestToHTTP(entry.Request)
+	if err != nil {
+		return "", err
+	}
+	return ConvertRequest(req, order, basedir, envfile, opts)
+}
+
+// HARRequestToHTTP builds *http.Request from the HAR request
+// and returns header names in HAR order.
+// HTTP/2 pseudo-headers (:authority, :path...) are skipped.
+func HARRequestToHTTP(hr HARRequest) (*http.Request, []string, error) {
+	u, err := url.Parse(hr.URL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse url error %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, nil, fmt.Errorf("unsupported url scheme %q", u.Scheme)
+	}
+
+	body := harBody(hr.PostData)
+	req, err := http.NewRequest(hr.Method, hr.URL, strings.NewReader(body))
+	if err != nil {
+		return nil, nil, fmt.Errorf("create request error %w", err)
+	}
+
+	var order []string
+	for _, h := range hr.Headers {
+		if strings.HasPrefix(h.Name, ":") {
+			continue
+		}
+		key := http.CanonicalHeaderKey(h.Name)
+		if key == "Host" {
+			req.Host = h.Value
+			continue
+		}
+		req.Header.Add(key, h.Value)
+		order = append(order, key)
+	}
+
+	if hr.PostData != nil && hr.PostData.MimeType != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", hr.PostData.MimeType)
+		order = append(order, "Content-Type")
+	}
+
+	return req, order, nil
+}
+
+// harBody returns the request body, form params
+// are encoded when the text is missing
+func harBody(pd *HARPostData) string {
+	if pd == nil {
+		return ""
+	}
+	if pd.Text != "" || len(pd.Params) == 0 {
+		return pd.Text
+	}
+
+	parts := make([]string, 0, len(pd.Params))
+	for _, p := range pd.Params {
+		parts = append(parts, url.QueryEscape(p.Name)+"="+url.QueryEscape(p.Value))
+	}
+	return strings.Join(parts, "&")
+}
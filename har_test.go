@@ -0,0 +1,185 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHARRequestToHTTP(t *testing.T) {
+	tests := []struct {
+		name      string
+		hr        HARRequest
+		wantHost  string
+		wantPath  string
+		wantQuery string
+		wantBody  string
+		wantOrder []string
+		wantCT    string
+		wantErr   bool
+	}{
+		{
+			name: "GET with headers",
+			hr: HARRequest{
+				Method: "GET",
+				URL:    "https://api.example.com/v1/users?page=2&sort=asc",
+				Headers: []HARNameValue{
+					{Name: "accept", Value: "application/json"},
+					{Name: "Host", Value: "api.example.com"},
+					{Name: "x-trace", Value: "1"},
+				},
+			},
+			wantHost:  "api.example.com",
+			wantPath:  "/v1/users",
+			wantQuery: "page=2&sort=asc",
+			wantOrder: []string{"Accept", "X-Trace"},
+		},
+		{
+			name: "HTTP/2 pseudo headers are skipped",
+			hr: HARRequest{
+				Method: "GET",
+				URL:    "https://api.example.com/",
+				Headers: []HARNameValue{
+					{Name: ":authority", Value: "api.example.com"},
+					{Name: ":method", Value: "GET"},
+					{Name: "accept", Value: "*/*"},
+				},
+			},
+			wantHost:  "api.example.com",
+			wantPath:  "/",
+			wantOrder: []string{"Accept"},
+		},
+		{
+			name: "POST with text body and mime type",
+			hr: HARRequest{
+				Method:   "POST",
+				URL:      "https://api.example.com/login",
+				PostData: &HARPostData{MimeType: "application/json", Text: `{"a":1}`},
+			},
+			wantHost:  "api.example.com",
+			wantPath:  "/login",
+			wantBody:  `{"a":1}`,
+			wantOrder: []string{"Content-Type"},
+			wantCT:    "application/json",
+		},
+		{
+			name: "POST with form params only",
+			hr: HARRequest{
+				Method: "POST",
+				URL:    "https://api.example.com/form",
+				Headers: []HARNameValue{
+					{Name: "Content-Type", Value: "application/x-www-form-urlencoded"},
+				},
+				PostData: &HARPostData{
+					MimeType: "application/x-www-form-urlencoded",
+					Params:   []HARNameValue{{Name: "a b", Value: "1&2"}, {Name: "c", Value: ""}},
+				},
+			},
+			wantHost:  "api.example.com",
+			wantPath:  "/form",
+			wantBody:  "a+b=1%262&c=",
+			wantOrder: []string{"Content-Type"},
+			wantCT:    "application/x-www-form-urlencoded",
+		},
+		{
+			name:    "websocket url is unsupported",
+			hr:      HARRequest{Method: "GET", URL: "wss://api.example.com/ws"},
+			wantErr: true,
+		},
+		{
+			name:    "data url is unsupported",
+			hr:      HARRequest{Method: "GET", URL: "data:image/png;base64,AAAA"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, order, err := HARRequestToHTTP(tt.hr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("HARRequestToHTTP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if req.Host != tt.wantHost {
+				t.Errorf("host = %q, want %q", req.Host, tt.wantHost)
+			}
+			if req.URL.Path != tt.wantPath {
+				t.Errorf("path = %q, want %q", req.URL.Path, tt.wantPath)
+			}
+			if req.URL.RawQuery != tt.wantQuery {
+				t.Errorf("query = %q, want %q", req.URL.RawQuery, tt.wantQuery)
+			}
+			body, _ := io.ReadAll(req.Body)
+			if string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+			if !reflect.DeepEqual(order, tt.wantOrder) {
+				t.Errorf("order = %v, want %v", order, tt.wantOrder)
+			}
+			if got := req.Header.Get("Content-Type"); got != tt.wantCT {
+				t.Errorf("content type = %q, want %q", got, tt.wantCT)
+			}
+		})
+	}
+}
+
+func TestDoHAR(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	if err := DoCollection("api.example.com", "."); err != nil {
+		t.Fatalf("DoCollection() error = %v", err)
+	}
+	if err := DoFolder("users", "api.example.com"); err != nil {
+		t.Fatalf("DoFolder() error = %v", err)
+	}
+
+	har := `{"log": {"entries": [
+  {"request": {"method": "GET", "url": "https://api.example.com/users/list", "headers": [{"name": "Accept", "value": "application/json"}]}},
+  {"request": {"method": "POST", "url": "https://api.example.com/login", "headers": [], "postData": {"mimeType": "application/json", "text": "{\"u\":1}"}}},
+  {"request": {"method": "GET", "url": "https://api.example.com/users/list", "headers": []}},
+  {"request": {"method": "GET", "url": "https://cdn.other.com/app.js", "headers": []}}
+]}}`
+	os.WriteFile("session.har", []byte(har), 0o644)
+
+	err := DoHAR("session.har", ".", "environments/base.bru", RequestOptions{SkipHeaders: DefaultSkipHeaders})
+	if err != nil {
+		t.Fatalf("DoHAR() error = %v", err)
+	}
+
+	for _, fp := range []string{"api.example.com/users/list-GET.bru", "api.example.com/login-POST.bru"} {
+		if _, err := os.Stat(fp); err != nil {
+			t.Errorf("expected file %q was not created", fp)
+		}
+	}
+
+	data, _ := os.ReadFile(filepath.Join("api.example.com", "login-POST.bru"))
+	if !strings.Contains(string(data), `{"u":1}`) {
+		t.Errorf("login-POST.bru should contain body\ngot:\n%s", data)
+	}
+}
+
+func TestDoHARErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if err := DoHAR("", tmpDir, "environments/base.bru", RequestOptions{}); err == nil {
+		t.Errorf("DoHAR() without input should return error")
+	}
+	if err := DoHAR(filepath.Join(tmpDir, "missing.har"), tmpDir, "environments/base.bru", RequestOptions{}); err == nil {
+		t.Errorf("DoHAR() with missing file should return error")
+	}
+
+	invalid := filepath.Join(tmpDir, "invalid.har")
+	os.WriteFile(invalid, []byte("not json"), 0o644)
+	if err := DoHAR(invalid, tmpDir, "environments/base.bru", RequestOptions{}); err == nil {
+		t.Errorf("DoHAR() with invalid json should return error")
+	}
+}
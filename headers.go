@@ -1,10 +1,173 @@
 package main
 
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultSkipHeaders hop-by-hop and transport headers
+// which are never written to the request file
+var DefaultSkipHeaders = []string{
+	"Host",
+	"Content-Length",
+	"Connection",
+	"Accept-Encoding",
+	"Keep-Alive",
+	"Proxy-Connection",
+	"Transfer-Encoding",
+}
+
 // heades block in format
 //
 //	headers {
 //	  User-Agent: {{ua}}
 //	}
-func HeadersGenerate(heads map[string]string) string {
+func HeadersGenerate(heads Pairs) string {
 	return NameBlockMap("headers", heads)
 }
+
+// RequestHeaders returns captured request headers for the `headers` block.
+// Headers from the skip list and headers inherited from
+// collection.bru/folder.bru are omitted, values are replaced
+// with {{key}} placeholders using env values.
+// Headers are written in rd.HeaderOrder, unknown ones are appended sorted.
+func RequestHeaders(rd RequestData) Pairs {
+	var heads Pairs
+	if rd.HTTPReq == nil {
+		return heads
+	}
+
+	for _, key := range headerKeys(rd.HTTPReq.Header, rd.HeaderOrder) {
+		values := rd.HTTPReq.Header[key]
+		if headerInList(key, rd.Options.SkipHeaders) {
+			continue
+		}
+		if rd.Auth != nil && rd.Auth.Header == key {
+			continue
+		}
+		if _, ok := rd.InheritedHeaders[http.CanonicalHeaderKey(key)]; ok {
+			continue
+		}
+		// bruno generates own boundary for multipart body
+		if rd.BodyType == "multipartForm" && http.CanonicalHeaderKey(key) == "Content-Type" {
+			continue
+		}
+
+		sep := ", "
+		if http.CanonicalHeaderKey(key) == "Cookie" {
+			sep = "; "
+		}
+		heads.Add(key, EnvToBody(strings.Join(values, sep), rd.Env))
+	}
+
+	return heads
+}
+
+// HeaderOrder returns header names of the raw HTTP request in source order.
+// Names are in canonical format, leading `#` meta lines and HTTP/2
+// pseudo-headers are skipped.
+func HeaderOrder(raw []byte) []string {
+	var names []string
+	requestLine := false
+	for _, line := range strings.Split(string(raw), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if !requestLine {
+			if line != "" && !strings.HasPrefix(line, "#") {
+				requestLine = true
+			}
+			continue
+		}
+		if line == "" {
+			break
+		}
+		name, _, found := strings.Cut(line, ":")
+		if !found || name == "" {
+			// pseudo-headers (:method, :path...) start with a colon
+			continue
+		}
+		names = append(names, http.CanonicalHeaderKey(strings.TrimSpace(name)))
+	}
+	return names
+}
+
+// headerKeys returns keys of the header in the given order,
+// keys missing from the order are appended sorted
+func headerKeys(header http.Header, order []string) []string {
+	keys := make([]string, 0, len(header))
+	seen := make(map[string]bool)
+	for _, name := range order {
+		key := http.CanonicalHeaderKey(name)
+		if _, ok := header[key]; ok && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	var rest []string
+	for key := range header {
+		if !seen[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+
+	return append(keys, rest...)
+}
+
+// InheritedHeaders returns headers defined in basedir/collection.bru
+// and in folder.bru of every folder from basedir down to dir.
+// Keys are in canonical format.
+func InheritedHeaders(basedir, dir string) map[string]string {
+	if basedir == "" {
+		basedir = "."
+	}
+	heads := make(map[string]string)
+	addHeadersFromFile(heads, filepath.Join(basedir, "collection.bru"))
+
+	rel, err := filepath.Rel(basedir, dir)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return heads
+	}
+
+	current := basedir
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		addHeadersFromFile(heads, filepath.Join(current, "folder.bru"))
+	}
+
+	return heads
+}
+
+func addHeadersFromFile(heads map[string]string, path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	for key, value := range ParseBlockMap(string(data), "headers") {
+		heads[http.CanonicalHeaderKey(key)] = value
+	}
+}
+
+// ParseHeadersList parse comma separated list of header names
+func ParseHeadersList(list string) []string {
+	var result []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+func headerInList(key string, list []string) bool {
+	for _, name := range list {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
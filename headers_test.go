@@ -0,0 +1,216 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRequestHeaders(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    http.Header
+		bodyType  string
+		inherited map[string]string
+		skip      []string
+		env       *BrunoEnv
+		expected  Pairs
+	}{
+		{
+			name:     "nil request returns no headers",
+			expected: nil,
+		},
+		{
+			name:     "plain headers are kept",
+			header:   http.Header{"Accept": {"application/json"}, "X-Api-Version": {"2"}},
+			expected: Pairs{{"Accept", "application/json"}, {"X-Api-Version", "2"}},
+		},
+		{
+			name:     "skip list is case insensitive",
+			header:   http.Header{"Accept": {"*/*"}, "Accept-Encoding": {"gzip"}, "Connection": {"close"}},
+			skip:     []string{"accept-encoding", "CONNECTION"},
+			expected: Pairs{{"Accept", "*/*"}},
+		},
+		{
+			name:      "inherited headers are omitted",
+			header:    http.Header{"User-Agent": {"curl"}, "Cookie": {"a=1"}, "Accept": {"*/*"}},
+			inherited: map[string]string{"User-Agent": "{{ua}}", "Cookie": "{{cook}}"},
+			expected:  Pairs{{"Accept", "*/*"}},
+		},
+		{
+			name:     "multiple cookie values joined with semicolon",
+			header:   http.Header{"Cookie": {"a=1", "b=2"}},
+			expected: Pairs{{"Cookie", "a=1; b=2"}},
+		},
+		{
+			name:     "multiple values joined with comma",
+			header:   http.Header{"Accept": {"text/html", "application/json"}},
+			expected: Pairs{{"Accept", "text/html, application/json"}},
+		},
+		{
+			name:   "values replaced with env placeholders",
+			header: http.Header{"X-Tenant": {"42"}},
+			env: &BrunoEnv{
+				Vars:        map[string]string{"tenant": "42"},
+				ReverseVars: map[string]string{"42": "tenant"},
+			},
+			expected: Pairs{{"X-Tenant", "{{tenant}}"}},
+		},
+		{
+			name:     "multipart content type is dropped",
+			header:   http.Header{"Content-Type": {"multipart/form-data; boundary=xyz"}},
+			bodyType: "multipartForm",
+			expected: nil,
+		},
+		{
+			name:     "json content type is kept",
+			header:   http.Header{"Content-Type": {"application/json"}},
+			bodyType: "json",
+			expected: Pairs{{"Content-Type", "application/json"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rd := RequestData{
+				BodyType:         tt.bodyType,
+				Env:              tt.env,
+				InheritedHeaders: tt.inherited,
+				Options:          RequestOptions{SkipHeaders: tt.skip},
+			}
+			if tt.header != nil {
+				rd.HTTPReq = &http.Request{Header: tt.header}
+			}
+			got := RequestHeaders(rd)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("RequestHeaders() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRequestHeadersOrder(t *testing.T) {
+	rd := RequestData{
+		HTTPReq: &http.Request{Header: http.Header{
+			"Accept":       {"*/*"},
+			"X-B":          {"b"},
+			"X-A":          {"a"},
+			"Content-Type": {"application/json"},
+		}},
+		HeaderOrder: []string{"x-b", "Content-Type", "X-Missing"},
+	}
+
+	got := RequestHeaders(rd)
+	expected := Pairs{{"X-B", "b"}, {"Content-Type", "application/json"}, {"Accept", "*/*"}, {"X-A", "a"}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("RequestHeaders() = %v, want %v", got, expected)
+	}
+}
+
+func TestHeaderOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected []string
+	}{
+		{
+			name:     "headers in source order",
+			raw:      "GET / HTTP/1.1\r\nhost: a.com\r\nx-z: 1\r\naccept: */*\r\n\r\nbody: no",
+			expected: []string{"Host", "X-Z", "Accept"},
+		},
+		{
+			name:     "meta lines are skipped",
+			raw:      "# url: https://a.com\nGET / HTTP/1.1\nAccept: */*\n\n",
+			expected: []string{"Accept"},
+		},
+		{
+			name:     "no headers",
+			raw:      "GET / HTTP/1.1\r\n\r\n",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HeaderOrder([]byte(tt.raw))
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("HeaderOrder() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestInheritedHeaders(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "api", "users"), 0o755)
+	os.MkdirAll(filepath.Join(tmpDir, "other"), 0o755)
+
+	os.WriteFile(filepath.Join(tmpDir, "collection.bru"), []byte(DefaultCollectionBru()), 0o644)
+	os.WriteFile(filepath.Join(tmpDir, "api", "folder.bru"), []byte("meta {\n  name: api\n}\n\nheaders {\n  x-api-key: {{key}}\n  ~X-Debug: 1\n}\n"), 0o644)
+	os.WriteFile(filepath.Join(tmpDir, "api", "users", "folder.bru"), []byte(DefaultFolderBru("users")), 0o644)
+
+	tests := []struct {
+		name     string
+		dir      string
+		expected map[string]string
+	}{
+		{
+			name:     "collection root",
+			dir:      tmpDir,
+			expected: map[string]string{"User-Agent": "{{ua}}"},
+		},
+		{
+			name:     "folder headers are canonical and disabled skipped",
+			dir:      filepath.Join(tmpDir, "api"),
+			expected: map[string]string{"User-Agent": "{{ua}}", "X-Api-Key": "{{key}}"},
+		},
+		{
+			name: "nested folders are merged",
+			dir:  filepath.Join(tmpDir, "api", "users"),
+			expected: map[string]string{
+				"User-Agent":    "{{ua}}",
+				"X-Api-Key":     "{{key}}",
+				"Cookie":        "{{cook}}",
+				"Authorization": "Bearer {{token}}",
+			},
+		},
+		{
+			name:     "folder without folder.bru",
+			dir:      filepath.Join(tmpDir, "other"),
+			expected: map[string]string{"User-Agent": "{{ua}}"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := InheritedHeaders(tmpDir, tt.dir)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("InheritedHeaders() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseHeadersList(t *testing.T) {
+	tests := []struct {
+		name     string
+		list     string
+		expected []string
+	}{
+		{"empty list", "", nil},
+		{"single header", "Host", []string{"Host"}},
+		{"spaces are trimmed", " Host , Connection ", []string{"Host", "Connection"}},
+		{"empty items are skipped", "Host,,Connection,", []string{"Host", "Connection"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseHeadersList(tt.list)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ParseHeadersList(%q) = %v, want %v", tt.list, got, tt.expected)
+			}
+		})
+	}
+}
@@ -5,15 +5,52 @@ import (
 	"strings"
 )
 
-func NameBlockMap(name string, heads map[string]string) string {
-	if len(heads) == 0 {
+// Pair single `key: value` entry of a dictionary block
+type Pair struct {
+	Key   string
+	Value string
+}
+
+// Pairs ordered entries of a dictionary block.
+// Keys may repeat (headers, query params, form fields).
+type Pairs []Pair
+
+// Add appends the entry to the end
+func (p *Pairs) Add(key, value string) {
+	*p = append(*p, Pair{Key: key, Value: value})
+}
+
+// Set replaces the value of the first entry with the key,
+// or appends a new entry if the key is missing
+func (p *Pairs) Set(key, value string) {
+	for i := range *p {
+		if (*p)[i].Key == key {
+			(*p)[i].Value = value
+			return
+		}
+	}
+	p.Add(key, value)
+}
+
+// Get returns the value of the first entry with the key
+func (p Pairs) Get(key string) (string, bool) {
+	for _, pair := range p {
+		if pair.Key == key {
+			return pair.Value, true
+		}
+	}
+	return "", false
+}
+
+func NameBlockMap(name string, pairs Pairs) string {
+	if len(pairs) == 0 {
 		return ""
 	}
 
 	var sb strings.Builder
 	sb.WriteString(name)
 	sb.WriteString(" {\n")
-	sb.WriteString(BlockMap(heads))
+	sb.WriteString(BlockMap(pairs))
 	sb.WriteString("}\n")
 	return sb.String()
 }
@@ -31,17 +68,17 @@ func NameBlockStrings(name string, list []string) string {
 	return sb.String()
 }
 
-func BlockMap(m map[string]string) string {
-	if len(m) == 0 {
+func BlockMap(pairs Pairs) string {
+	if len(pairs) == 0 {
 		return ""
 	}
 
 	var sb strings.Builder
-	for key, value := range m {
+	for _, pair := range pairs {
 		sb.WriteString("  ")
-		sb.WriteString(key)
+		sb.WriteString(pair.Key)
 		sb.WriteString(": ")
-		sb.WriteString(value)
+		sb.WriteString(pair.Value)
 		sb.WriteString("\n")
 	}
 	return sb.String()
@@ -54,13 +91,31 @@ func BlockStrings(m []string) string {
 
 	var sb strings.Builder
 	for _, el := range m {
-		sb.WriteString("  ")
+		if el != "" {
+			sb.WriteString("  ")
+		}
 		sb.WriteString(el)
 		sb.WriteString("\n")
 	}
 	return sb.String()
 }
 
+// ParseBlockMap returns enabled entries of the named dictionary block
+// from .bru file content. Disabled entries (`~key: value`) are skipped.
+func ParseBlockMap(content, name string) map[string]string {
+	result := make(map[string]string)
+
+	block := ParseBru(content).Block(name)
+	if block == nil {
+		return result
+	}
+	for _, pair := range block.Pairs() {
+		result[pair.Key] = pair.Value
+	}
+
+	return result
+}
+
 // DirFilesCount returns count file in folder,
 // non recursive, without subfolders
 func DirFilesCount(dir string) int {
@@ -3,6 +3,7 @@ package main
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -11,20 +12,20 @@ func TestNameBlockMap(t *testing.T) {
 	tests := []struct {
 		name     string
 		blockNm  string
-		heads    map[string]string
+		heads    Pairs
 		contains []string
 		isEmpty  bool
 	}{
 		{
 			name:    "empty map returns empty string",
 			blockNm: "headers",
-			heads:   map[string]string{},
+			heads:   Pairs{},
 			isEmpty: true,
 		},
 		{
 			name:    "single entry",
 			blockNm: "headers",
-			heads:   map[string]string{"Content-Type": "application/json"},
+			heads:   Pairs{{"Content-Type", "application/json"}},
 			contains: []string{
 				"headers {",
 				"Content-Type: application/json",
@@ -34,9 +35,9 @@ func TestNameBlockMap(t *testing.T) {
 		{
 			name:    "multiple entries",
 			blockNm: "meta",
-			heads: map[string]string{
-				"name": "test",
-				"type": "http",
+			heads: Pairs{
+				{"name", "test"},
+				{"type", "http"},
 			},
 			contains: []string{
 				"meta {",
@@ -48,7 +49,7 @@ func TestNameBlockMap(t *testing.T) {
 		{
 			name:    "vars block",
 			blockNm: "vars",
-			heads:   map[string]string{"host": "example.com"},
+			heads:   Pairs{{"host", "example.com"}},
 			contains: []string{
 				"vars {",
 				"host: example.com",
@@ -148,27 +149,27 @@ func TestNameBlockStrings(t *testing.T) {
 func TestBlockMap(t *testing.T) {
 	tests := []struct {
 		name     string
-		m        map[string]string
+		m        Pairs
 		contains []string
 		isEmpty  bool
 	}{
 		{
 			name:    "empty map returns empty string",
-			m:       map[string]string{},
+			m:       Pairs{},
 			isEmpty: true,
 		},
 		{
 			name: "single entry with indentation",
-			m:    map[string]string{"key": "value"},
+			m:    Pairs{{"key", "value"}},
 			contains: []string{
 				"  key: value",
 			},
 		},
 		{
 			name: "multiple entries",
-			m: map[string]string{
-				"host":  "example.com",
-				"proto": "https",
+			m: Pairs{
+				{"host", "example.com"},
+				{"proto", "https"},
 			},
 			contains: []string{
 				"  host: example.com",
@@ -197,6 +198,85 @@ func TestBlockMap(t *testing.T) {
 	}
 }
 
+func TestBlockMapKeepsOrder(t *testing.T) {
+	pairs := Pairs{{"z", "1"}, {"a", "2"}, {"z", "3"}}
+	expected := "  z: 1\n  a: 2\n  z: 3\n"
+
+	for i := 0; i < 10; i++ {
+		if got := BlockMap(pairs); got != expected {
+			t.Fatalf("BlockMap() = %q, want %q", got, expected)
+		}
+	}
+}
+
+func TestPairs(t *testing.T) {
+	var pairs Pairs
+	pairs.Add("a", "1")
+	pairs.Add("b", "2")
+	pairs.Add("a", "3")
+
+	if got, ok := pairs.Get("a"); !ok || got != "1" {
+		t.Errorf("Get(a) = %q, %v, want %q, true", got, ok, "1")
+	}
+	if _, ok := pairs.Get("missing"); ok {
+		t.Errorf("Get(missing) should not be found")
+	}
+
+	pairs.Set("a", "4")
+	pairs.Set("c", "5")
+	expected := Pairs{{"a", "4"}, {"b", "2"}, {"a", "3"}, {"c", "5"}}
+	if !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("pairs = %v, want %v", pairs, expected)
+	}
+}
+
+func TestParseBlockMap(t *testing.T) {
+	content := `meta {
+  name: api
+}
+
+headers {
+  User-Agent: {{ua}}
+  Authorization: Bearer {{token}}
+  ~X-Debug: 1
+  malformed
+}
+`
+	tests := []struct {
+		name     string
+		block    string
+		expected map[string]string
+	}{
+		{
+			name:     "meta block",
+			block:    "meta",
+			expected: map[string]string{"name": "api"},
+		},
+		{
+			name:  "headers block skips disabled and malformed lines",
+			block: "headers",
+			expected: map[string]string{
+				"User-Agent":    "{{ua}}",
+				"Authorization": "Bearer {{token}}",
+			},
+		},
+		{
+			name:     "missing block returns empty map",
+			block:    "vars",
+			expected: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseBlockMap(content, tt.block)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ParseBlockMap(%q) = %v, want %v", tt.block, got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestBlockStrings(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -330,7 +410,7 @@ func TestDirFilesCount(t *testing.T) {
 
 func TestBlockMapIndentation(t *testing.T) {
 	// Verify that BlockMap uses exactly 2 spaces for indentation
-	m := map[string]string{"key": "value"}
+	m := Pairs{{"key", "value"}}
 	got := BlockMap(m)
 
 	if !strings.HasPrefix(got, "  ") {
@@ -350,7 +430,7 @@ func TestBlockStringsIndentation(t *testing.T) {
 
 func TestNameBlockMapFormat(t *testing.T) {
 	// Verify the complete format of NameBlockMap
-	m := map[string]string{"key": "value"}
+	m := Pairs{{"key", "value"}}
 	got := NameBlockMap("test", m)
 
 	// Should start with "test {"
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// http2RequestLineRe matches `GET /x HTTP/2` and `GET /x HTTP/3` request lines
+var http2RequestLineRe = regexp.MustCompile(`^(\S+) (\S+) HTTP/[23](\.0)?$`)
+
+// IsHTTP2Request reports whether the request line is an HTTP/2 or HTTP/3
+// one, or the request starts with a pseudo-header (:method, :path...)
+func IsHTTP2Request(line string) bool {
+	line = strings.TrimRight(line, "\r\n")
+	return strings.HasPrefix(line, ":") || http2RequestLineRe.MatchString(line)
+}
+
+// ReadHTTP2Request reads the request copied from HTTP/2 and HTTP/3 views
+// (Chrome DevTools, Burp) which http.ReadRequest rejects. The request
+// starts with pseudo-headers or with `GET /x HTTP/2` request line.
+// :authority is used as Host, :scheme is set as the URL scheme.
+// Body length is taken from Content-Length or chunked encoding,
+// without them the rest of the input is the body if toEOF is set.
+func ReadHTTP2Request(br *bufio.Reader, toEOF bool) (*http.Request, error) {
+	var method, target, authority, scheme string
+	header := make(http.Header)
+
+	for first := true; ; first = false {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+
+		switch m := http2RequestLineRe.FindStringSubmatch(line); {
+		case first && m != nil:
+			method, target = m[1], m[2]
+		case strings.HasPrefix(line, ":"):
+			name, value, _ := strings.Cut(line[1:], ":")
+			value = strings.TrimSpace(value)
+			switch strings.ToLower(strings.TrimSpace(name)) {
+			case "method":
+				method = value
+			case "path":
+				target = value
+			case "authority":
+				authority = value
+			case "scheme":
+				scheme = strings.ToLower(value)
+			}
+		default:
+			name, value, found := strings.Cut(line, ":")
+			if !found {
+				return nil, fmt.Errorf("malformed header line %q", line)
+			}
+			header.Add(http.CanonicalHeaderKey(strings.TrimSpace(name)), strings.TrimSpace(value))
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	if method == "" || target == "" {
+		return nil, fmt.Errorf("request method or path is missing")
+	}
+	u, err := url.ParseRequestURI(target)
+	if err != nil {
+		return nil, fmt.Errorf("parse request path error %w", err)
+	}
+	if authority == "" {
+		authority = header.Get("Host")
+	}
+	if authority == "" {
+		authority = u.Host
+	}
+	header.Del("Host")
+	if scheme != "" {
+		u.Scheme = scheme
+		if u.Host == "" {
+			u.Host = authority
+		}
+	}
+
+	body, err := readHTTP2Body(br, header, toEOF)
+	if err != nil {
+		return nil, fmt.Errorf("read request body error %w", err)
+	}
+
+	return &http.Request{
+		Method:        method,
+		URL:           u,
+		Proto:         "HTTP/2.0",
+		ProtoMajor:    2,
+		Header:        header,
+		Host:          authority,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		RequestURI:    target,
+	}, nil
+}
+
+// readHTTP2Body reads the body by Content-Length, chunked encoding
+// or till EOF. Chunked encoding is removed from the header.
+func readHTTP2Body(br *bufio.Reader, header http.Header, toEOF bool) ([]byte, error) {
+	if strings.EqualFold(header.Get("Transfer-Encoding"), "chunked") {
+		header.Del("Transfer-Encoding")
+		body, err := io.ReadAll(httputil.NewChunkedReader(br))
+		if err != nil {
+			return nil, err
+		}
+		// final CRLF after the last chunk
+		if b, err := br.Peek(2); err == nil && string(b) == "\r\n" {
+			br.Discard(2)
+		}
+		return body, nil
+	}
+
+	if cl := header.Get("Content-Length"); cl != "" {
+		n, err := strconv.ParseInt(cl, 10, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid Content-Length %q", cl)
+		}
+		body := make([]byte, n)
+		if _, err := io.ReadFull(br, body); err != nil {
+			return nil, err
+		}
+		return body, nil
+	}
+
+	if !toEOF {
+		return nil, nil
+	}
+	body, err := io.ReadAll(br)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(body, "\r\n"), nil
+}
+
+// readRequest reads HTTP/1.x request with http.ReadRequest,
+// HTTP/2 and HTTP/3 requests with ReadHTTP2Request
+func readRequest(br *bufio.Reader, toEOF bool) (*http.Request, error) {
+	if IsHTTP2Request(peekLine(br)) {
+		return ReadHTTP2Request(br, toEOF)
+	}
+	return http.ReadRequest(br)
+}
+
+// peekLine returns the next line without consuming it,
+// the line is cut at the buffer size
+func peekLine(br *bufio.Reader) string {
+	for n := 1; n <= br.Size(); n++ {
+		b, err := br.Peek(n)
+		if err != nil || b[n-1] == '\n' {
+			return string(b)
+		}
+	}
+	b, _ := br.Peek(br.Size())
+	return string(b)
+}
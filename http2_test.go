@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadHTTP2Request(t *testing.T) {
+	tests := []struct {
+		name   string
+		raw    string
+		toEOF  bool
+		method string
+		url    string
+		host   string
+		body   string
+		header map[string]string
+	}{
+		{
+			name:   "pseudo-headers",
+			raw:    ":method: POST\n:authority: api.example.com\n:scheme: https\n:path: /login?next=%2F\ncontent-type: application/json\n\n{\"u\":1}\n",
+			toEOF:  true,
+			method: "POST",
+			url:    "https://api.example.com/login?next=%2F",
+			host:   "api.example.com",
+			body:   `{"u":1}`,
+			header: map[string]string{"Content-Type": "application/json"},
+		},
+		{
+			name:   "http/2 request line",
+			raw:    "GET /users HTTP/2\r\nHost: api.example.com:8443\r\nAccept: */*\r\n\r\n",
+			toEOF:  true,
+			method: "GET",
+			url:    "/users",
+			host:   "api.example.com:8443",
+			header: map[string]string{"Accept": "*/*"},
+		},
+		{
+			name:   "content-length",
+			raw:    "PUT /a HTTP/3\r\nHost: a.com\r\nContent-Length: 3\r\n\r\nabcdef",
+			method: "PUT",
+			url:    "/a",
+			host:   "a.com",
+			body:   "abc",
+		},
+		{
+			name:   "chunked",
+			raw:    "POST /a HTTP/2\r\nHost: a.com\r\nTransfer-Encoding: chunked\r\n\r\n3\r\nabc\r\n0\r\n\r\n",
+			method: "POST",
+			url:    "/a",
+			host:   "a.com",
+			body:   "abc",
+		},
+		{
+			name:   "body without length in stream",
+			raw:    "POST /a HTTP/2\r\nHost: a.com\r\n\r\nabc",
+			method: "POST",
+			url:    "/a",
+			host:   "a.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := ReadHTTP2Request(bufio.NewReader(strings.NewReader(tt.raw)), tt.toEOF)
+			if err != nil {
+				t.Fatalf("ReadHTTP2Request() error = %v", err)
+			}
+			body, _ := io.ReadAll(req.Body)
+			if req.Method != tt.method || req.URL.String() != tt.url || req.Host != tt.host || string(body) != tt.body {
+				t.Errorf("request = %s %s host %q body %q", req.Method, req.URL, req.Host, body)
+			}
+			for k, v := range tt.header {
+				if got := req.Header.Get(k); got != v {
+					t.Errorf("header %s = %q, want %q", k, got, v)
+				}
+			}
+			if req.Header.Get("Host") != "" || req.Header.Get("Transfer-Encoding") != "" {
+				t.Errorf("header = %v", req.Header)
+			}
+		})
+	}
+
+	if _, err := ReadHTTP2Request(bufio.NewReader(strings.NewReader(":authority: a.com\n\n")), true); err == nil {
+		t.Error("ReadHTTP2Request() expected error without :method and :path")
+	}
+}
+
+func TestHeaderOrderPseudoHeaders(t *testing.T) {
+	raw := ":method: GET\n:path: /\n:authority: a.com\nx-b: 1\naccept: */*\n\n"
+	if got := HeaderOrder([]byte(raw)); !reflect.DeepEqual(got, []string{"X-B", "Accept"}) {
+		t.Errorf("HeaderOrder() = %v", got)
+	}
+}
+
+func TestConvertHTTP2Request(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := DoCollection("api.example.com", tmpDir); err != nil {
+		t.Fatalf("DoCollection() error = %v", err)
+	}
+	// env without proto gets proto of the request
+	envPath := filepath.Join(tmpDir, "api.example.com", "environments", "base.bru")
+	os.WriteFile(envPath, []byte(EnvGenerate(Pairs{{"host", "api.example.com"}})), 0o644)
+
+	raw := ":method: GET\n:scheme: http\n:authority: api.example.com\n:path: /status\naccept: */*\n\n"
+	req, order, err := ParseRequest([]byte(raw))
+	if err != nil {
+		t.Fatalf("ParseRequest() error = %v", err)
+	}
+	fp, err := ConvertRequest(req, order, tmpDir, "environments/base.bru", RequestOptions{})
+	if err != nil {
+		t.Fatalf("ConvertRequest() error = %v", err)
+	}
+	data, _ := os.ReadFile(fp)
+	if !strings.Contains(string(data), "url: {{proto}}://{{host}}/status") {
+		t.Errorf("request file:\n%s", data)
+	}
+	env, _ := EnvFromFile(envPath)
+	if env.Vars["proto"] != "http" {
+		t.Errorf("env proto = %q, want http", env.Vars["proto"])
+	}
+
+	// HTTP/2 request in the stream, Content-Length separates the requests
+	input := "GET /a HTTP/2\r\nHost: api.example.com\r\n\r\n" +
+		"POST /b HTTP/2\r\nHost: api.example.com\r\nContent-Type: text/plain\r\nContent-Length: 2\r\n\r\nhi" +
+		"GET /c HTTP/1.1\r\nHost: api.example.com\r\n\r\n"
+	var out bytes.Buffer
+	if err := DoRequestStream(strings.NewReader(input), &out, tmpDir, "environments/base.bru", RequestOptions{}); err != nil {
+		t.Fatalf("DoRequestStream() error = %v", err)
+	}
+	if out.String() != input {
+		t.Errorf("DoRequestStream() output = %q", out.String())
+	}
+	for _, name := range []string{"a-GET.bru", "b-POST.bru", "c-GET.bru"} {
+		if _, err := os.Stat(filepath.Join(tmpDir, "api.example.com", name)); err != nil {
+			t.Errorf("expected file %q was not created", name)
+		}
+	}
+}
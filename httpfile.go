@@ -0,0 +1,339 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// HTTPFile parsed VS Code REST Client / JetBrains HTTP Client file
+type HTTPFile struct {
+	// Vars file variables declared with `@name = value`
+	Vars     Pairs
+	Requests []HTTPFileRequest
+}
+
+// HTTPFileRequest single request of the .http file,
+// {{var}} references are not resolved
+type HTTPFileRequest struct {
+	// Name from `# @name` comment or `### name` separator
+	Name    string
+	Method  string
+	URL     string
+	Headers Pairs
+	Body    string
+	// Line number of the request line
+	Line int
+}
+
+var (
+	httpFileVarRe  = regexp.MustCompile(`^@([\w.\-]+)\s*=\s*(.*)$`)
+	httpFileNameRe = regexp.MustCompile(`^(?:#|//)\s*@name\s*=?\s*(.+)$`)
+	httpFileRefRe  = regexp.MustCompile(`\{\{\s*([\w.\-]+)\s*\}\}`)
+
+	httpFileMethods = []string{"GET", "POST", "PUT", "DELETE", "PATCH", "HEAD", "OPTIONS", "CONNECT", "TRACE"}
+)
+
+// ParseHTTPFile splits the .http file on `###` separators
+// and parses every request. Blocks without request line are skipped.
+func ParseHTTPFile(content string) (*HTTPFile, error) {
+	file := &HTTPFile{}
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	start, name := 0, ""
+	for i := 0; i <= len(lines); i++ {
+		if i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "###") {
+			continue
+		}
+		req, err := parseHTTPFileBlock(file, lines[start:i], start+1, name)
+		if err != nil {
+			return nil, err
+		}
+		if req != nil {
+			file.Requests = append(file.Requests, *req)
+		}
+		if i < len(lines) {
+			name = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(lines[i]), "#"))
+		}
+		start = i + 1
+	}
+
+	return file, nil
+}
+
+// parseHTTPFileBlock parses lines between `###` separators, variables
+// are added to the file. Returns nil if the block has no request.
+func parseHTTPFileBlock(file *HTTPFile, lines []string, firstLine int, name string) (*HTTPFileRequest, error) {
+	req := &HTTPFileRequest{Name: name}
+
+	i := 0
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if m := httpFileNameRe.FindStringSubmatch(line); m != nil {
+			req.Name = strings.TrimSpace(m[1])
+			continue
+		}
+		if m := httpFileVarRe.FindStringSubmatch(line); m != nil {
+			file.Vars.Set(m[1], strings.TrimSpace(m[2]))
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		break
+	}
+	if i == len(lines) {
+		return nil, nil
+	}
+
+	req.Line = firstLine + i
+	req.Method, req.URL = parseHTTPFileRequestLine(strings.TrimSpace(lines[i]))
+
+	// multiline query
+	//   GET https://example.com/users
+	//     ?page=1
+	//     &size=10
+	for i++; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(line, "?") && !strings.HasPrefix(line, "&") {
+			break
+		}
+		req.URL += line
+	}
+	req.URL = trimHTTPVersion(req.URL)
+
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			i++
+			break
+		}
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: invalid header %q", firstLine+i, line)
+		}
+		req.Headers.Add(strings.TrimSpace(key), strings.TrimSpace(value))
+	}
+
+	var body []string
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		// JetBrains response handler and response reference
+		//   > {% client.global.set("token", response.body.token) %}
+		//   <> 2024-01-01T000000.200.json
+		if strings.HasPrefix(line, "> ") || strings.HasPrefix(line, "<> ") {
+			break
+		}
+		body = append(body, lines[i])
+	}
+	req.Body = strings.TrimRight(strings.Join(body, "\n"), "\n ")
+
+	return req, nil
+}
+
+// parseHTTPFileRequestLine returns the method and url of the request line
+// `[METHOD] URL [HTTP/version]`, the method is GET if omitted
+func parseHTTPFileRequestLine(line string) (string, string) {
+	fields := strings.Fields(line)
+	method := "GET"
+	if len(fields) > 1 && headerInList(fields[0], httpFileMethods) {
+		method = strings.ToUpper(fields[0])
+		fields = fields[1:]
+	}
+	return method, trimHTTPVersion(strings.Join(fields, " "))
+}
+
+// trimHTTPVersion removes trailing " HTTP/x" of the request line
+func trimHTTPVersion(u string) string {
+	if i := strings.LastIndexByte(u, ' '); i > 0 && strings.HasPrefix(strings.ToUpper(u[i+1:]), "HTTP/") {
+		return strings.TrimSpace(u[:i])
+	}
+	return u
+}
+
+// ResolveHTTPFileVars replaces {{var}} references of the declared
+// variables, references to unknown variables are kept intact
+func ResolveHTTPFileVars(s string, vars Pairs) string {
+	// variables may reference other variables
+	for range 10 {
+		resolved := httpFileRefRe.ReplaceAllStringFunc(s, func(ref string) string {
+			name := httpFileRefRe.FindStringSubmatch(ref)[1]
+			if value, ok := vars.Get(name); ok {
+				return value
+			}
+			return ref
+		})
+		if resolved == s {
+			break
+		}
+		s = resolved
+	}
+	return s
+}
+
+// HTTPRequest builds *http.Request and returns header names in file order.
+// {{var}} references of the url path and query, headers and body are kept,
+// only the scheme and host are resolved to find the collection of the
+// request. Relative urls use the Host header, urls without scheme use https.
+func (r HTTPFileRequest) HTTPRequest(vars Pairs) (*http.Request, []string, error) {
+	var headers Pairs
+	host := ""
+	for _, h := range r.Headers {
+		if http.CanonicalHeaderKey(h.Key) == "Host" {
+			host = ResolveHTTPFileVars(h.Value, vars)
+			continue
+		}
+		headers.Add(h.Key, h.Value)
+	}
+
+	target, rest := splitHTTPFileURL(r.URL)
+	u := ResolveHTTPFileVars(target, vars)
+	switch {
+	case u == "":
+		if host == "" {
+			return nil, nil, fmt.Errorf("line %d: relative url without Host header", r.Line)
+		}
+		u = "https://" + host
+	case !strings.Contains(u, "://"):
+		u = "https://" + u
+	}
+
+	req, err := http.NewRequest(r.Method, u+rest, strings.NewReader(r.Body))
+	if err != nil {
+		return nil, nil, fmt.Errorf("line %d: create request error %w", r.Line, err)
+	}
+	if strings.Contains(req.Host, "{{") {
+		return nil, nil, fmt.Errorf("line %d: unresolved host %q", r.Line, req.Host)
+	}
+	if host != "" {
+		req.Host = host
+	}
+
+	var order []string
+	for _, h := range headers {
+		key := http.CanonicalHeaderKey(h.Key)
+		req.Header.Add(key, h.Value)
+		order = append(order, key)
+	}
+
+	return req, order, nil
+}
+
+// splitHTTPFileURL splits the url into scheme and host part and
+// path with query, e.g. {{base}}/users?page={{page}} is split into
+// {{base}} and /users?page={{page}}. The first part is empty for
+// relative urls.
+func splitHTTPFileURL(u string) (string, string) {
+	from := 0
+	if i := strings.Index(u, "://"); i >= 0 && !strings.ContainsAny(u[:i], "/?") {
+		from = i + len("://")
+	}
+	if i := strings.IndexAny(u[from:], "/?"); i >= 0 {
+		return u[:from+i], u[from+i:]
+	}
+	return u, ""
+}
+
+// DoHTTPFile converts every request of the .http file into .bru request
+// of the matching collection. {{var}} references are kept, file variables
+// are added to the env files of the collections after all requests are
+// converted. Created files are printed to stdout, skipped requests and
+// summary to stderr.
+func DoHTTPFile(input, basedir, envfile string, opts RequestOptions) error {
+	if input == "" {
+		return fmt.Errorf("-i input file is required")
+	}
+
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return fmt.Errorf("read %q file error %w", input, err)
+	}
+
+	file, err := ParseHTTPFile(string(data))
+	if err != nil {
+		return fmt.Errorf("parse http file error %w", err)
+	}
+
+	created, skipped := 0, 0
+	var envPaths []string
+	for _, r := range file.Requests {
+		fp, envPath, err := convertHTTPFileRequest(r, file.Vars, basedir, envfile, opts)
+		if err != nil {
+			skipped++
+			fmt.Fprintf(os.Stderr, "[W] skip request line %d %s %s: %s\n", r.Line, r.Method, r.URL, err)
+			continue
+		}
+		created++
+		fmt.Println(fp)
+		if !slices.Contains(envPaths, envPath) {
+			envPaths = append(envPaths, envPath)
+		}
+	}
+
+	// variables are added after the conversion, otherwise their values
+	// would be replaced in urls and bodies of the next requests
+	for _, envPath := range envPaths {
+		if err := addHTTPFileVars(envPath, file.Vars); err != nil {
+			fmt.Fprintf(os.Stderr, "[W] write file variables: %s\n", err)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "[I] http file requests: %d, created: %d, skipped: %d\n", len(file.Requests), created, skipped)
+	return nil
+}
+
+// convertHTTPFileRequest converts the request, returns the request file
+// and the env file of its collection
+func convertHTTPFileRequest(r HTTPFileRequest, vars Pairs, basedir, envfile string, opts RequestOptions) (string, string, error) {
+	req, order, err := r.HTTPRequest(vars)
+	if err != nil {
+		return "", "", err
+	}
+
+	dir, err := findCollectionDir(basedir, req.Host)
+	if err != nil {
+		return "", "", fmt.Errorf("find collection dir error %w", err)
+	}
+
+	fp, err := convertRequest(req, order, RequestMeta{Name: r.Name}, dir, envfile, opts)
+	if err != nil {
+		return "", "", err
+	}
+	return fp, filepath.Join(dir, envfile), nil
+}
+
+// addHTTPFileVars adds resolved file variables to the env file.
+// Variables defined in env with another value are kept.
+func addHTTPFileVars(path string, vars Pairs) error {
+	if len(vars) == 0 {
+		return nil
+	}
+	env, err := EnvFromFile(path)
+	if err != nil {
+		return err
+	}
+
+	var add Pairs
+	for _, v := range vars {
+		value := ResolveHTTPFileVars(v.Value, vars)
+		existing, ok := env.Vars[v.Key]
+		switch {
+		case ok && existing == value:
+		case ok:
+			fmt.Fprintf(os.Stderr, "[W] file variable %s differs from env value, env value is kept\n", v.Key)
+		default:
+			add.Add(v.Key, value)
+		}
+	}
+	if len(add) == 0 {
+		return nil
+	}
+	return EnvSetVars(path, add)
+}
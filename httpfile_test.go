@@ -0,0 +1,262 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testHTTPFile = `@host = api.example.com
+@base = https://{{host}}/v1
+@token = secret-1
+
+### Get users
+GET {{base}}/users
+    ?page=1
+    &size=10 HTTP/1.1
+Accept: application/json
+# comment between headers
+Authorization: Bearer {{token}}
+
+###
+# @name create-user
+POST /v1/users
+Host: {{host}}
+Content-Type: application/json
+X-Request-Id: {{$uuid}}
+
+{
+  "name": "{{name}}"
+}
+
+> {% client.global.set("id", response.body.id) %}
+
+### only comments
+# nothing here
+
+###
+// @name health
+{{base}}/health
+`
+
+func TestParseHTTPFile(t *testing.T) {
+	file, err := ParseHTTPFile(strings.ReplaceAll(testHTTPFile, "\n", "\r\n"))
+	if err != nil {
+		t.Fatalf("ParseHTTPFile() error = %v", err)
+	}
+
+	expectedVars := Pairs{{"host", "api.example.com"}, {"base", "https://{{host}}/v1"}, {"token", "secret-1"}}
+	if !reflect.DeepEqual(file.Vars, expectedVars) {
+		t.Errorf("Vars = %v, want %v", file.Vars, expectedVars)
+	}
+
+	expected := []HTTPFileRequest{
+		{
+			Name:    "Get users",
+			Method:  "GET",
+			URL:     "{{base}}/users?page=1&size=10",
+			Headers: Pairs{{"Accept", "application/json"}, {"Authorization", "Bearer {{token}}"}},
+			Line:    6,
+		},
+		{
+			Name:    "create-user",
+			Method:  "POST",
+			URL:     "/v1/users",
+			Headers: Pairs{{"Host", "{{host}}"}, {"Content-Type", "application/json"}, {"X-Request-Id", "{{$uuid}}"}},
+			Body:    "{\r\n  \"name\": \"{{name}}\"\r\n}",
+			Line:    15,
+		},
+		{
+			Name:   "health",
+			Method: "GET",
+			URL:    "{{base}}/health",
+			Line:   31,
+		},
+	}
+	// body keeps original line endings only inside, compare normalized
+	for i := range file.Requests {
+		file.Requests[i].Body = strings.ReplaceAll(file.Requests[i].Body, "\r\n", "\n")
+	}
+	expected[1].Body = strings.ReplaceAll(expected[1].Body, "\r\n", "\n")
+	if !reflect.DeepEqual(file.Requests, expected) {
+		t.Errorf("Requests = %+v\nwant %+v", file.Requests, expected)
+	}
+
+	if _, err := ParseHTTPFile("GET https://a.com\nbroken header\n"); err == nil {
+		t.Error("ParseHTTPFile() expected error for invalid header")
+	}
+}
+
+func TestParseHTTPFileRequestLine(t *testing.T) {
+	tests := []struct {
+		line   string
+		method string
+		url    string
+	}{
+		{"GET https://a.com/x HTTP/1.1", "GET", "https://a.com/x"},
+		{"post /x", "POST", "/x"},
+		{"https://a.com/x", "GET", "https://a.com/x"},
+		{"DELETE https://a.com/x HTTP/2", "DELETE", "https://a.com/x"},
+	}
+	for _, tt := range tests {
+		method, url := parseHTTPFileRequestLine(tt.line)
+		if method != tt.method || url != tt.url {
+			t.Errorf("parseHTTPFileRequestLine(%q) = %q, %q, want %q, %q", tt.line, method, url, tt.method, tt.url)
+		}
+	}
+}
+
+func TestResolveHTTPFileVars(t *testing.T) {
+	vars := Pairs{{"host", "a.com"}, {"base", "https://{{host}}"}, {"loop", "{{loop}}"}}
+	tests := map[string]string{
+		"{{base}}/x":           "https://a.com/x",
+		"{{ host }}":           "a.com",
+		"{{unknown}}/{{host}}": "{{unknown}}/a.com",
+		"{{$guid}}":            "{{$guid}}",
+		"{{loop}}":             "{{loop}}",
+	}
+	for in, expected := range tests {
+		if got := ResolveHTTPFileVars(in, vars); got != expected {
+			t.Errorf("ResolveHTTPFileVars(%q) = %q, want %q", in, got, expected)
+		}
+	}
+}
+
+func TestHTTPFileRequestHTTPRequest(t *testing.T) {
+	vars := Pairs{{"host", "a.com"}}
+
+	r := HTTPFileRequest{
+		Method:  "POST",
+		URL:     "/users/{{id}}",
+		Headers: Pairs{{"Host", "{{host}}"}, {"content-type", "text/plain"}},
+		Body:    "hi {{host}}",
+	}
+	req, order, err := r.HTTPRequest(vars)
+	if err != nil {
+		t.Fatalf("HTTPRequest() error = %v", err)
+	}
+	if req.Host != "a.com" || req.URL.Path != "/users/{{id}}" || req.URL.Scheme != "https" {
+		t.Errorf("request host = %q, url = %s", req.Host, req.URL)
+	}
+	if !reflect.DeepEqual(order, []string{"Content-Type"}) {
+		t.Errorf("order = %v", order)
+	}
+	body, _ := io.ReadAll(req.Body)
+	if string(body) != "hi {{host}}" {
+		t.Errorf("body = %q", body)
+	}
+
+	if _, _, err := (HTTPFileRequest{Method: "GET", URL: "/x"}).HTTPRequest(nil); err == nil {
+		t.Error("HTTPRequest() expected error for relative url without Host")
+	}
+	if _, _, err := (HTTPFileRequest{Method: "GET", URL: "https://{{unknown}}/x"}).HTTPRequest(nil); err == nil {
+		t.Error("HTTPRequest() expected error for unresolved host")
+	}
+	if req, _, err := (HTTPFileRequest{Method: "GET", URL: "a.com/x"}).HTTPRequest(nil); err != nil || req.URL.String() != "https://a.com/x" {
+		t.Errorf("HTTPRequest() without scheme = %v, %v", req, err)
+	}
+	r = HTTPFileRequest{Method: "GET", URL: "https://{{host}}/u/{{id}}?page={{page}}"}
+	if req, _, err := r.HTTPRequest(Pairs{{"host", "a.com"}, {"page", "1"}}); err != nil || req.Host != "a.com" || req.URL.Path != "/u/{{id}}" || req.URL.RawQuery != "page={{page}}" {
+		t.Errorf("HTTPRequest() should keep path and query refs = %v, %v", req, err)
+	}
+}
+
+func TestSplitHTTPFileURL(t *testing.T) {
+	tests := []struct{ url, target, rest string }{
+		{"{{base}}/users?page={{page}}", "{{base}}", "/users?page={{page}}"},
+		{"https://{{host}}/v1/x", "https://{{host}}", "/v1/x"},
+		{"https://a.com?x=1", "https://a.com", "?x=1"},
+		{"a.com", "a.com", ""},
+		{"/v1/users", "", "/v1/users"},
+	}
+	for _, tt := range tests {
+		if target, rest := splitHTTPFileURL(tt.url); target != tt.target || rest != tt.rest {
+			t.Errorf("splitHTTPFileURL(%q) = %q, %q", tt.url, target, rest)
+		}
+	}
+}
+
+func TestDoHTTPFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	if err := DoCollection("api.example.com", "."); err != nil {
+		t.Fatalf("DoCollection() error = %v", err)
+	}
+	if err := DoFolder("v1", "api.example.com"); err != nil {
+		t.Fatalf("DoFolder() error = %v", err)
+	}
+	os.WriteFile("requests.http", []byte(testHTTPFile), 0o644)
+
+	if err := DoHTTPFile("requests.http", ".", "environments/base.bru", RequestOptions{SkipHeaders: DefaultSkipHeaders}); err != nil {
+		t.Fatalf("DoHTTPFile() error = %v", err)
+	}
+
+	for _, fp := range []string{"Get users.bru", "create-user.bru", "health.bru"} {
+		if _, err := os.Stat(filepath.Join("api.example.com", "v1", fp)); err != nil {
+			t.Errorf("expected file %q was not created", fp)
+		}
+	}
+
+	data, _ := os.ReadFile(filepath.Join("api.example.com", "v1", "Get users.bru"))
+	for _, want := range []string{"name: Get users", "url: {{proto}}://{{host}}/v1/users?page=1&size=10\n", "size: 10\n"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Get users.bru should contain %q\ngot:\n%s", want, data)
+		}
+	}
+	if strings.Contains(string(data), "secret-1") {
+		t.Errorf("Get users.bru should not contain the token value\ngot:\n%s", data)
+	}
+
+	data, _ = os.ReadFile(filepath.Join("api.example.com", "v1", "create-user.bru"))
+	for _, want := range []string{"X-Request-Id: {{$uuid}}", `"name": "{{name}}"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("create-user.bru should contain %q\ngot:\n%s", want, data)
+		}
+	}
+
+	env, _ := EnvFromFile(filepath.Join("api.example.com", "environments", "base.bru"))
+	if env.Vars["token"] != "secret-1" || env.Vars["base"] != "https://api.example.com/v1" || env.Vars["host"] != "api.example.com" {
+		t.Errorf("env vars = %v", env.Vars)
+	}
+}
+
+func TestDoHTTPFileKeepsVarRefs(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := DoCollection("api.example.com", tmpDir); err != nil {
+		t.Fatalf("DoCollection() error = %v", err)
+	}
+	envPath := filepath.Join(tmpDir, "api.example.com", "environments", "base.bru")
+	EnvSetVars(envPath, Pairs{{"user", "alice"}})
+
+	input := filepath.Join(tmpDir, "items.http")
+	os.WriteFile(input, []byte(`@page = 1
+@user = bob
+
+# @name items
+POST https://api.example.com/items?page={{page}}&limit=1
+Content-Type: application/json
+
+{"id": 1, "owner": "{{user}}"}
+`), 0o644)
+
+	if err := DoHTTPFile(input, tmpDir, "environments/base.bru", RequestOptions{SkipHeaders: DefaultSkipHeaders}); err != nil {
+		t.Fatalf("DoHTTPFile() error = %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(tmpDir, "api.example.com", "items.bru"))
+	for _, want := range []string{"/items?page={{page}}&limit=1\n", `"id": 1`, `"owner": "{{user}}"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("items.bru should contain %q\ngot:\n%s", want, data)
+		}
+	}
+	env, _ := EnvFromFile(envPath)
+	if env.Vars["page"] != "1" || env.Vars["user"] != "alice" {
+		t.Errorf("env vars = %v", env.Vars)
+	}
+}
@@ -0,0 +1,552 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// InsomniaExport Insomnia v4 export, only fields used for conversion
+type InsomniaExport struct {
+	Type      string             `json:"_type"`
+	Format    int                `json:"__export_format"`
+	Resources []InsomniaResource `json:"resources"`
+}
+
+// InsomniaResource workspace, request group, request or environment
+type InsomniaResource struct {
+	ID          string  `json:"_id"`
+	ParentID    string  `json:"parentId"`
+	Type        string  `json:"_type"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	MetaSortKey float64 `json:"metaSortKey"`
+
+	// request
+	Method         string         `json:"method"`
+	URL            string         `json:"url"`
+	Body           InsomniaBody   `json:"body"`
+	Parameters     []InsomniaPair `json:"parameters"`
+	Headers        []InsomniaPair `json:"headers"`
+	Authentication map[string]any `json:"authentication"`
+
+	// environment data
+	Data map[string]any `json:"data"`
+	// request group environment
+	Environment map[string]any `json:"environment"`
+}
+
+type InsomniaBody struct {
+	MimeType string         `json:"mimeType"`
+	Text     string         `json:"text"`
+	Params   []InsomniaPair `json:"params"`
+}
+
+type InsomniaPair struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled"`
+	Type     string `json:"type"`
+	FileName string `json:"fileName"`
+}
+
+var (
+	insomniaVarRe = regexp.MustCompile(`\{\{\s*(?:_\.)?([\w.\-]+)\s*\}\}`)
+	insomniaTagRe = regexp.MustCompile(`\{%\s*(\w+)\s*(.*?)\s*%\}`)
+	// simple JSONPath like $.data.items[0].id
+	insomniaJSONPathRe = regexp.MustCompile(`^\$((\.[A-Za-z_$][\w$]*)|\[\d+\])+$`)
+	insomniaFieldRe    = regexp.MustCompile(`\.([A-Za-z_$][\w$]*)`)
+)
+
+// insomniaImport state of the workspace import
+type insomniaImport struct {
+	opts     RequestOptions
+	children map[string][]InsomniaResource
+	byID     map[string]InsomniaResource
+	// post-response scripts setting vars of converted response tags,
+	// by request id
+	scripts map[string][]string
+	created int
+	skipped int
+	total   int
+}
+
+// DoInsomnia converts workspaces of the Insomnia v4 export into
+// bruno collections. Request groups become folders, the base environment
+// is written to environments/base.bru and sub environments to their own
+// files with base values included. {% response %} tags are converted to
+// variables set by post-response scripts of the referenced requests,
+// unconverted tags are kept and listed in the request docs.
+func DoInsomnia(input, collection, basedir string, opts RequestOptions) error {
+	if input == "" {
+		return fmt.Errorf("-i input file is required")
+	}
+
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return fmt.Errorf("read %q file error %w", input, err)
+	}
+
+	var export InsomniaExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return fmt.Errorf("parse insomnia export error %w", err)
+	}
+	if export.Type != "export" || export.Format != 4 {
+		return fmt.Errorf("parse insomnia export error: %q is not an insomnia v4 export", input)
+	}
+
+	ii := &insomniaImport{
+		opts:     opts,
+		children: map[string][]InsomniaResource{},
+		byID:     map[string]InsomniaResource{},
+		scripts:  map[string][]string{},
+	}
+	for _, r := range export.Resources {
+		ii.byID[r.ID] = r
+		ii.children[r.ParentID] = append(ii.children[r.ParentID], r)
+	}
+	for id := range ii.children {
+		sort.SliceStable(ii.children[id], func(i, j int) bool {
+			return ii.children[id][i].MetaSortKey < ii.children[id][j].MetaSortKey
+		})
+	}
+
+	// response tags of all requests are converted before writing,
+	// scripts are added to the referenced requests
+	converted := map[string]BruRequest{}
+	for _, r := range export.Resources {
+		if r.Type == "request" {
+			converted[r.ID] = ii.request(r)
+		}
+	}
+
+	for _, ws := range export.Resources {
+		if ws.Type != "workspace" {
+			continue
+		}
+		name := collection
+		if name == "" {
+			name = requestFileName(ws.Name)
+		}
+		if name == "" {
+			name = "insomnia"
+		}
+		dir, err := ensureCollection(name, basedir)
+		if err != nil {
+			return err
+		}
+		if err := ii.environments(dir, ws.ID); err != nil {
+			return err
+		}
+		ii.items(dir, ws.ID, converted)
+	}
+
+	fmt.Fprintf(os.Stderr, "[I] insomnia requests: %d, created: %d, skipped: %d\n", ii.total, ii.created, ii.skipped)
+	return nil
+}
+
+// items writes requests and request groups of the parent recursively
+func (ii *insomniaImport) items(dir, parentID string, converted map[string]BruRequest) {
+	written := map[string]bool{}
+	for _, r := range ii.children[parentID] {
+		switch r.Type {
+		case "request_group":
+			sub, err := ii.folder(dir, r)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[W] skip folder %q: %s\n", r.Name, err)
+				continue
+			}
+			ii.items(sub, r.ID, converted)
+		case "request":
+			ii.total++
+			br := converted[r.ID]
+			if written[br.Name] {
+				br.Name, _ = nextFreeName(dir, br.Name)
+			}
+			if scripts := ii.scripts[r.ID]; len(scripts) > 0 {
+				br.PostResponse = strings.Join(scripts, "\n")
+			}
+			fp, err := WriteBruRequest(dir, br, ii.opts)
+			if err != nil {
+				ii.skipped++
+				fmt.Fprintf(os.Stderr, "[W] skip request %q: %s\n", r.Name, err)
+				continue
+			}
+			written[br.Name] = true
+			ii.created++
+			fmt.Println(fp)
+		case "grpc_request", "websocket_request":
+			ii.total++
+			ii.skipped++
+			fmt.Fprintf(os.Stderr, "[W] skip request %q: unsupported type %s\n", r.Name, r.Type)
+		}
+	}
+}
+
+// folder creates the folder of the request group with its
+// environment as folder vars, auth and headers
+func (ii *insomniaImport) folder(dir string, group InsomniaResource) (string, error) {
+	sub, doc, err := importFolder(dir, group.Name)
+	if err != nil {
+		return "", err
+	}
+
+	var notes []string
+	if vars := ii.flattenData(group.Environment, &notes); len(vars) > 0 {
+		doc.SetBlock(NewBruDict("vars:pre-request", vars))
+	}
+	if len(group.Headers) > 0 {
+		doc.SetBlock(bruDictWithDisabled("headers", ii.pairs(group.Headers, &notes)))
+	}
+	if len(group.Authentication) > 0 {
+		SetAuthBlocks(doc, ii.auth(group.Name, group.Authentication, &notes))
+	}
+	if docs := insomniaDocs(group.Description, notes); len(docs) > 0 {
+		doc.SetBlock(NewBruText("docs", strings.Join(docs, "\n")))
+	}
+
+	return sub, doc.WriteFile(filepath.Join(sub, "folder.bru"))
+}
+
+// environments writes the base environment of the workspace to base.bru,
+// sub environments to <name>.bru with base values included
+func (ii *insomniaImport) environments(dir, workspaceID string) error {
+	for _, base := range ii.children[workspaceID] {
+		if base.Type != "environment" {
+			continue
+		}
+		var notes []string
+		baseVars := ii.flattenData(base.Data, &notes)
+		if err := EnvSetVars(filepath.Join(dir, "environments", "base.bru"), baseVars); err != nil {
+			return err
+		}
+
+		for _, env := range ii.children[base.ID] {
+			if env.Type != "environment" {
+				continue
+			}
+			vars := append(Pairs(nil), baseVars...)
+			for _, v := range ii.flattenData(env.Data, &notes) {
+				vars.Set(v.Key, v.Value)
+			}
+			name := requestFileName(env.Name)
+			if name == "" {
+				name = env.ID
+			}
+			fp := filepath.Join(dir, "environments", name+".bru")
+			if _, err := os.Stat(fp); err != nil {
+				if err := os.WriteFile(fp, []byte(EnvGenerate(nil)), 0o644); err != nil {
+					return fmt.Errorf("write %q file error %w", fp, err)
+				}
+			}
+			if err := EnvSetVars(fp, vars); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "[I] environment: %s\n", fp)
+		}
+		for _, note := range notes {
+			fmt.Fprintf(os.Stderr, "[W] environment %q: %s\n", base.Name, note)
+		}
+	}
+	return nil
+}
+
+// flattenData flattens nested environment data to dotted names,
+// e.g. {"api": {"host": "x"}} -> api.host: x
+func (ii *insomniaImport) flattenData(data map[string]any, notes *[]string) Pairs {
+	var vars Pairs
+	var walk func(prefix string, value any)
+	walk = func(prefix string, value any) {
+		switch x := value.(type) {
+		case map[string]any:
+			keys := make([]string, 0, len(x))
+			for k := range x {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				name := k
+				if prefix != "" {
+					name = prefix + "." + k
+				}
+				walk(name, x[k])
+			}
+		case string:
+			vars.Add(prefix, ii.convert(x, "", notes))
+		default:
+			data, _ := json.Marshal(x)
+			vars.Add(prefix, string(data))
+		}
+	}
+	walk("", data)
+	return vars
+}
+
+// request converts the request, response tags referencing other
+// requests add scripts to ii.scripts
+func (ii *insomniaImport) request(r InsomniaResource) BruRequest {
+	var notes []string
+	br := BruRequest{
+		Name:   requestFileName(r.Name),
+		Method: strings.ToUpper(r.Method),
+		URL:    ii.convert(r.URL, r.ID, &notes),
+	}
+	if br.Name == "" {
+		br.Name = br.Method
+	}
+
+	br.Query = ii.pairs(r.Parameters, &notes)
+	if enabled := enabledPairs(br.Query); len(enabled) > 0 {
+		sep := "?"
+		if strings.Contains(br.URL, "?") {
+			sep = "&"
+		}
+		br.URL += sep + QueryString(enabled)
+	}
+	br.Headers = ii.pairs(r.Headers, &notes)
+	br.Auth = ii.auth(r.Name, r.Authentication, &notes)
+	ii.body(&br, r, &notes)
+	br.Docs = insomniaDocs(r.Description, notes)
+
+	return br
+}
+
+func (ii *insomniaImport) body(br *BruRequest, r InsomniaResource, notes *[]string) {
+	br.BodyType = "none"
+	body := r.Body
+	switch body.MimeType {
+	case "":
+		if body.Text != "" {
+			br.BodyType, br.Body = "text", ii.convert(body.Text, r.ID, notes)
+		}
+		return
+	case "application/x-www-form-urlencoded":
+		br.BodyType = "formUrlEncoded"
+		br.Form = ii.pairs(body.Params, notes)
+		return
+	case "multipart/form-data":
+		br.BodyType = "multipartForm"
+		for _, p := range body.Params {
+			key := p.Name
+			if p.Disabled {
+				key = "~" + key
+			}
+			value := ii.convert(p.Value, r.ID, notes)
+			if p.Type == "file" {
+				value = "@file(" + p.FileName + ")"
+			}
+			br.Form.Add(key, value)
+		}
+		return
+	case "application/graphql":
+		var gql struct {
+			Query     string          `json:"query"`
+			Variables json.RawMessage `json:"variables"`
+		}
+		if err := json.Unmarshal([]byte(body.Text), &gql); err == nil {
+			br.Type, br.BodyType = "graphql", "graphql"
+			br.Body = ii.convert(gql.Query, r.ID, notes)
+			if len(gql.Variables) > 0 && string(gql.Variables) != "null" {
+				br.GraphQLVars = ii.convert(indentJSON(gql.Variables), r.ID, notes)
+			}
+			return
+		}
+	}
+
+	bt, err := BodyTypeFromContentType(body.MimeType)
+	if err != nil || bt == "none" || bt == "multipartForm" || bt == "formUrlEncoded" || bt == "file" {
+		bt = "text"
+	}
+	br.BodyType, br.Body = bt, ii.convert(body.Text, r.ID, notes)
+}
+
+// auth maps Insomnia authentication, empty authentication inherits
+// auth of the folder
+func (ii *insomniaImport) auth(owner string, a map[string]any, notes *[]string) *RequestAuth {
+	str := func(key string) string {
+		v, _ := a[key].(string)
+		return ii.convert(v, "", notes)
+	}
+	if len(a) == 0 {
+		return &RequestAuth{Mode: "inherit"}
+	}
+	if disabled, _ := a["disabled"].(bool); disabled {
+		return &RequestAuth{Mode: "none"}
+	}
+
+	auth := &RequestAuth{Mode: str("type")}
+	switch auth.Mode {
+	case "none", "":
+		auth.Mode = "none"
+	case "bearer":
+		auth.Values.Add("token", str("token"))
+	case "basic", "digest":
+		auth.Values.Add("username", str("username"))
+		auth.Values.Add("password", str("password"))
+	case "ntlm":
+		auth.Values.Add("username", str("username"))
+		auth.Values.Add("password", str("password"))
+		auth.Values.Add("domain", str("domain"))
+	case "apikey":
+		placement := "header"
+		if str("addTo") == "queryParams" {
+			placement = "queryparams"
+		}
+		auth.Values.Add("key", str("key"))
+		auth.Values.Add("value", str("value"))
+		auth.Values.Add("placement", placement)
+	default:
+		fmt.Fprintf(os.Stderr, "[W] %s: unsupported auth type %q, auth is none\n", owner, auth.Mode)
+		*notes = append(*notes, fmt.Sprintf("unsupported auth type %s", auth.Mode))
+		return &RequestAuth{Mode: "none"}
+	}
+	return auth
+}
+
+// pairs converts enabled and disabled (`~`) pairs
+func (ii *insomniaImport) pairs(list []InsomniaPair, notes *[]string) Pairs {
+	var pairs Pairs
+	for _, p := range list {
+		if p.Name == "" {
+			continue
+		}
+		key := p.Name
+		if p.Disabled {
+			key = "~" + key
+		}
+		pairs.Add(key, ii.convert(p.Value, "", notes))
+	}
+	return pairs
+}
+
+// convert rewrites {{ _.var }} references to {{var}} and template tags
+// to bruno variables. Unconverted tags are kept and added to notes.
+func (ii *insomniaImport) convert(s, requestID string, notes *[]string) string {
+	s = insomniaVarRe.ReplaceAllString(s, "{{$1}}")
+	return insomniaTagRe.ReplaceAllStringFunc(s, func(tag string) string {
+		m := insomniaTagRe.FindStringSubmatch(tag)
+		args := parseInsomniaTagArgs(m[2])
+		switch m[1] {
+		case "uuid":
+			return "{{$guid}}"
+		case "timestamp":
+			return "{{$timestamp}}"
+		case "now":
+			if len(args) == 0 || args[0] == "iso-8601" {
+				return "{{$isoTimestamp}}"
+			}
+			if args[0] == "unix" {
+				return "{{$timestamp}}"
+			}
+		case "response":
+			if name, ok := ii.responseVar(args, requestID); ok {
+				return "{{" + name + "}}"
+			}
+		}
+		*notes = append(*notes, "unconverted Insomnia tag "+tag)
+		return tag
+	})
+}
+
+// responseVar returns the variable of {% response 'field', 'req_id', 'path' %}
+// and adds the post-response script setting it to the referenced request.
+// Body fields with simple JSONPath and headers are supported.
+func (ii *insomniaImport) responseVar(args []string, requestID string) (string, bool) {
+	if len(args) < 3 {
+		return "", false
+	}
+	field, refID, path := args[0], args[1], decodeInsomniaArg(args[2])
+	ref, ok := ii.byID[refID]
+	if !ok || ref.Type != "request" || refID == requestID {
+		return "", false
+	}
+
+	var name, value string
+	switch {
+	case field == "body" && insomniaJSONPathRe.MatchString(path):
+		accessor := strings.TrimPrefix(path, "$")
+		accessor = strings.ReplaceAll(accessor, ".", "?.")
+		accessor = strings.ReplaceAll(accessor, "[", "?.[")
+		segments := insomniaFieldRe.FindAllString(path, -1)
+		last := "value"
+		if len(segments) > 0 {
+			last = strings.TrimPrefix(segments[len(segments)-1], ".")
+		}
+		name = envVarName(ref.Name + "_" + last)
+		value = "res.getBody()" + accessor
+	case field == "header" && path != "":
+		name = envVarName(ref.Name + "_" + path)
+		value = fmt.Sprintf("res.getHeader(%q)", strings.ToLower(path))
+	default:
+		return "", false
+	}
+
+	line := fmt.Sprintf("bru.setVar(%q, %s);", name, value)
+	if !slices.Contains(ii.scripts[refID], line) {
+		ii.scripts[refID] = append(ii.scripts[refID], line)
+	}
+	return name, true
+}
+
+// parseInsomniaTagArgs splits comma separated tag arguments,
+// quotes are removed
+func parseInsomniaTagArgs(s string) []string {
+	var args []string
+	var sb strings.Builder
+	var quote rune
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			sb.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+		case r == ',':
+			args = append(args, strings.TrimSpace(sb.String()))
+			sb.Reset()
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	if rest := strings.TrimSpace(sb.String()); rest != "" || len(args) > 0 {
+		args = append(args, rest)
+	}
+	return args
+}
+
+// decodeInsomniaArg decodes `b64::<base64>::46b` encoded tag argument
+func decodeInsomniaArg(arg string) string {
+	encoded, ok := strings.CutPrefix(arg, "b64::")
+	if !ok {
+		return arg
+	}
+	encoded, _, _ = strings.Cut(encoded, "::")
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return arg
+	}
+	return string(data)
+}
+
+// insomniaDocs returns docs lines with unconverted items checklist
+// and the description
+func insomniaDocs(description string, notes []string) []string {
+	var docs []string
+	for _, note := range notes {
+		docs = append(docs, "- [ ] "+note)
+	}
+	if description = strings.TrimRight(description, "\n"); description != "" {
+		if len(docs) > 0 {
+			docs = append(docs, "")
+		}
+		docs = append(docs, strings.Split(description, "\n")...)
+	}
+	return docs
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testInsomniaExport = `{
+  "_type": "export",
+  "__export_format": 4,
+  "__export_source": "insomnia.desktop.app:v2023.5.8",
+  "resources": [
+    {"_id": "wrk_1", "parentId": null, "_type": "workspace", "name": "Pet Store"},
+    {"_id": "env_base", "parentId": "wrk_1", "_type": "environment", "name": "Base Environment",
+     "data": {"base_url": "https://api.example.com", "api": {"version": "v2"}, "retries": 3}},
+    {"_id": "env_stage", "parentId": "env_base", "_type": "environment", "name": "Staging",
+     "data": {"base_url": "https://staging.example.com"}},
+    {"_id": "fld_pets", "parentId": "wrk_1", "_type": "request_group", "name": "Pets", "metaSortKey": 1,
+     "environment": {"page_size": 20},
+     "authentication": {"type": "bearer", "token": "{{ _.token }}"}},
+    {"_id": "req_login", "parentId": "wrk_1", "_type": "request", "name": "Login", "metaSortKey": 0,
+     "method": "POST", "url": "{{ _.base_url }}/login",
+     "body": {"mimeType": "application/json", "text": "{\"user\": \"demo\"}"},
+     "headers": [{"name": "Content-Type", "value": "application/json"}],
+     "authentication": {}},
+    {"_id": "req_list", "parentId": "fld_pets", "_type": "request", "name": "List pets", "metaSortKey": 2,
+     "method": "GET", "url": "{{ _.base_url }}/{{ _.api.version }}/pets",
+     "parameters": [{"name": "limit", "value": "{{ _.page_size }}"}, {"name": "tag", "value": "dog", "disabled": true}],
+     "headers": [{"name": "X-Request-Id", "value": "{% uuid 'v4' %}"}, {"name": "X-Debug", "value": "1", "disabled": true}],
+     "body": {},
+     "authentication": {}},
+    {"_id": "req_create", "parentId": "fld_pets", "_type": "request", "name": "Create pet", "metaSortKey": 1,
+     "method": "POST", "url": "{{ _.base_url }}/pets",
+     "body": {"mimeType": "multipart/form-data", "params": [{"name": "name", "value": "rex"}, {"name": "photo", "type": "file", "fileName": "/tmp/rex.png"}]},
+     "headers": [
+       {"name": "X-Session", "value": "{% response 'body', 'req_login', 'b64::JC5kYXRhLnRva2Vu::46b', 'never', 60 %}"},
+       {"name": "X-Trace", "value": "{% response 'header', 'req_login', 'b64::WC1UcmFjZQ==::46b', 'never', 60 %}"},
+       {"name": "X-Raw", "value": "{% response 'raw', 'req_login', '', 'never', 60 %}"}
+     ],
+     "description": "Creates a pet.",
+     "authentication": {"type": "oauth2", "grantType": "client_credentials"}},
+    {"_id": "req_search", "parentId": "wrk_1", "_type": "request", "name": "Search", "metaSortKey": 3,
+     "method": "POST", "url": "{{ _.base_url }}/graphql",
+     "body": {"mimeType": "application/graphql", "text": "{\"query\": \"query { pets { id } }\", \"variables\": {\"n\": 1}}"},
+     "authentication": {"type": "apikey", "key": "X-Key", "value": "{{ _.api_key }}", "addTo": "queryParams"}},
+    {"_id": "jar_1", "parentId": "wrk_1", "_type": "cookie_jar", "name": "Default Jar"}
+  ]
+}`
+
+func TestParseInsomniaTagArgs(t *testing.T) {
+	got := parseInsomniaTagArgs(`'body', "req_1", 'a,b', 60`)
+	want := []string{"body", "req_1", "a,b", "60"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseInsomniaTagArgs() = %q, want %q", got, want)
+	}
+	if got := decodeInsomniaArg("b64::JC5pZA==::46b"); got != "$.id" {
+		t.Errorf("decodeInsomniaArg() = %q", got)
+	}
+}
+
+func TestDoInsomnia(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "insomnia.json")
+	os.WriteFile(input, []byte(testInsomniaExport), 0o644)
+
+	if err := DoInsomnia(input, "", tmpDir, RequestOptions{}); err != nil {
+		t.Fatalf("DoInsomnia() error = %v", err)
+	}
+
+	coll := filepath.Join(tmpDir, "Pet Store")
+	tests := []struct {
+		file    string
+		want    []string
+		notWant []string
+	}{
+		{
+			file: "environments/base.bru",
+			want: []string{"base_url: https://api.example.com", "api.version: v2", "retries: 3"},
+		},
+		{
+			file: "environments/Staging.bru",
+			want: []string{"base_url: https://staging.example.com", "api.version: v2"},
+		},
+		{
+			file:    "Pets/folder.bru",
+			want:    []string{"name: Pets", "mode: bearer", "token: {{token}}", "vars:pre-request {\n  page_size: 20\n}"},
+			notWant: []string{"Cookie: {{cook}}"},
+		},
+		{
+			file: "Login.bru",
+			want: []string{
+				"url: {{base_url}}/login", "body: json", "auth: inherit",
+				"script:post-response {\n  bru.setVar(\"login_token\", res.getBody()?.data?.token);\n  bru.setVar(\"login_x_trace\", res.getHeader(\"x-trace\"));\n}",
+			},
+		},
+		{
+			file: "Pets/List pets.bru",
+			want: []string{
+				"url: {{base_url}}/{{api.version}}/pets?limit={{page_size}}",
+				"  limit: {{page_size}}\n  ~tag: dog",
+				"X-Request-Id: {{$guid}}", "~X-Debug: 1",
+			},
+		},
+		{
+			file: "Pets/Create pet.bru",
+			want: []string{
+				"seq: 2", "body: multipartForm", "name: rex", "photo: @file(/tmp/rex.png)",
+				"X-Session: {{login_token}}", "X-Trace: {{login_x_trace}}", "auth: none",
+				"- [ ] unconverted Insomnia tag {% response 'raw', 'req_login', '', 'never', 60 %}",
+				"- [ ] unsupported auth type oauth2", "Creates a pet.",
+			},
+		},
+		{
+			file: "Search.bru",
+			want: []string{
+				"type: graphql", "body:graphql {\n  query { pets { id } }\n}", "\"n\": 1",
+				"placement: queryparams", "value: {{api_key}}",
+			},
+		},
+	}
+	for _, tt := range tests {
+		data, err := os.ReadFile(filepath.Join(coll, tt.file))
+		if err != nil {
+			t.Errorf("expected file %q was not created", tt.file)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("%s should contain %q\ngot:\n%s", tt.file, want, data)
+			}
+		}
+		for _, notWant := range tt.notWant {
+			if strings.Contains(string(data), notWant) {
+				t.Errorf("%s should not contain %q\ngot:\n%s", tt.file, notWant, data)
+			}
+		}
+	}
+
+	if err := DoInsomnia(filepath.Join(coll, "bruno.json"), "", tmpDir, RequestOptions{}); err == nil {
+		t.Error("DoInsomnia() expected error for non insomnia file")
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	uuidRe      = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	jwtRe       = regexp.MustCompile(`^eyJ[\w-]+\.eyJ[\w-]+\.[\w-]*$`)
+	csrfNameRe  = regexp.MustCompile(`(?i)csrf|xsrf|authenticity_token|^_token$|requestverificationtoken`)
+	sessionRe   = regexp.MustCompile(`(?i)sess|^sid$|[._-]sid$|^auth|token`)
+	camelWordRe = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+	nonWordRe   = regexp.MustCompile(`[^a-z0-9]+`)
+
+	// numericIDRe numbers of 3+ digits, shorter numbers are usually
+	// versions, pages or counters and would be replaced everywhere
+	numericIDRe = regexp.MustCompile(`^[0-9]{3,}$`)
+)
+
+// LearnVars detects values worth an env variable: UUIDs and numeric IDs
+// in path segments, UUIDs and JWTs in query params, headers and body
+// fields, session cookies and CSRF tokens. Variable names are proposed
+// from the segment, param, header or cookie name. Values already in env
+// and headers handled elsewhere (skipped, inherited, auth) are ignored.
+func LearnVars(rd RequestData) Pairs {
+	var vars Pairs
+	if rd.Env == nil {
+		return vars
+	}
+
+	add := func(base, value string) {
+		if value == "" || strings.Contains(value, "{{") {
+			return
+		}
+		if _, ok := rd.Env.ReverseVars[value]; ok {
+			return
+		}
+		for _, v := range vars {
+			if v.Value == value {
+				return
+			}
+		}
+		vars.Add(uniqueVarName(rd.Env, vars, base), value)
+	}
+
+	prev := ""
+	for _, segment := range strings.Split(strings.Trim(rd.Path, "/"), "/") {
+		if uuidRe.MatchString(segment) || numericIDRe.MatchString(segment) {
+			add(pathVarName(prev), segment)
+		}
+		prev = segment
+	}
+
+	for _, param := range ParseQuery(rd.RawQuery) {
+		if learnValue(param.Value) {
+			add(envVarName(param.Key), param.Value)
+		}
+	}
+
+	learnHeaders(rd, add)
+
+	for _, field := range learnBodyFields(rd) {
+		switch {
+		case csrfNameRe.MatchString(field.Key):
+			add("csrf_token", field.Value)
+		case learnValue(field.Value):
+			add(envVarName(field.Key), field.Value)
+		}
+	}
+
+	return vars
+}
+
+// learnValue reports whether the value is UUID or JWT
+func learnValue(value string) bool {
+	return uuidRe.MatchString(value) || jwtRe.MatchString(value)
+}
+
+func learnHeaders(rd RequestData, add func(base, value string)) {
+	if rd.HTTPReq == nil {
+		return
+	}
+
+	for _, key := range headerKeys(rd.HTTPReq.Header, rd.HeaderOrder) {
+		canonical := http.CanonicalHeaderKey(key)
+		if headerInList(key, rd.Options.SkipHeaders) || canonical == "Authorization" || headerInList(key, APIKeyHeaders) {
+			continue
+		}
+		if _, ok := rd.InheritedHeaders[canonical]; ok {
+			continue
+		}
+
+		for _, value := range rd.HTTPReq.Header[key] {
+			if canonical == "Cookie" {
+				for _, cookie := range strings.Split(value, ";") {
+					name, v, ok := strings.Cut(strings.TrimSpace(cookie), "=")
+					if !ok {
+						continue
+					}
+					if csrfNameRe.MatchString(name) || sessionRe.MatchString(name) || learnValue(v) {
+						add(envVarName(name), v)
+					}
+				}
+				continue
+			}
+
+			switch {
+			case csrfNameRe.MatchString(key):
+				add("csrf_token", value)
+			case learnValue(value):
+				add(envVarName(key), value)
+			}
+		}
+	}
+}
+
+// learnBodyFields returns fields of form and json object bodies.
+// Form values which need url encoding are skipped, the raw body
+// wouldn't contain them for substitution.
+func learnBodyFields(rd RequestData) Pairs {
+	var fields Pairs
+	switch rd.BodyType {
+	case "formUrlEncoded":
+		for _, field := range ParseQuery(rd.Body) {
+			if value, err := url.QueryUnescape(field.Value); err == nil && value == field.Value {
+				fields.Add(field.Key, value)
+			}
+		}
+	case "multipartForm":
+		parts, _ := ParseMultipartParts(rd.Body)
+		for _, part := range parts {
+			if part.FileName == "" {
+				fields.Add(part.Name, part.Value)
+			}
+		}
+	case "json", "graphql":
+		body := rd.Body
+		if rd.GraphQL != nil {
+			body = rd.GraphQL.Variables
+		}
+		objFields, _ := parseJSONObject(body)
+		for _, f := range objFields {
+			var value string
+			if len(f.Value) > 1 && f.Value[0] == '"' {
+				value = strings.Trim(string(f.Value), `"`)
+			}
+			if value != "" && !strings.ContainsAny(value, `\"`) {
+				fields.Add(f.Key, value)
+			}
+		}
+	}
+	return fields
+}
+
+// pathVarName proposes the variable name for the ID following
+// the segment, e.g. users -> user_id, categories -> category_id
+func pathVarName(segment string) string {
+	name := envVarName(segment)
+	if name == "" || name == "value" || strings.Contains(segment, "{{") || numericIDRe.MatchString(segment) || uuidRe.MatchString(segment) {
+		return "id"
+	}
+
+	switch {
+	case strings.HasSuffix(name, "ies"):
+		name = strings.TrimSuffix(name, "ies") + "y"
+	case strings.HasSuffix(name, "sses"):
+		name = strings.TrimSuffix(name, "es")
+	case strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss") && !strings.HasSuffix(name, "us") && !strings.HasSuffix(name, "is"):
+		name = strings.TrimSuffix(name, "s")
+	}
+	if strings.HasSuffix(name, "_id") || name == "id" {
+		return name
+	}
+	return name + "_id"
+}
+
+// envVarName converts param, header or cookie name
+// to snake case variable name, e.g. userId -> user_id,
+// X-CSRF-Token -> x_csrf_token, connect.sid -> connect_sid
+func envVarName(s string) string {
+	name := camelWordRe.ReplaceAllString(s, "${1}_${2}")
+	name = nonWordRe.ReplaceAllString(strings.ToLower(name), "_")
+	name = strings.Trim(name, "_")
+	if name == "" {
+		return "value"
+	}
+	if name[0] >= '0' && name[0] <= '9' {
+		name = "v_" + name
+	}
+	return name
+}
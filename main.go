@@ -4,19 +4,53 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 )
 
 var (
-	flagOp         = flag.String("o", "request", "operaton. collection|folder|request")
+	flagOp         = flag.String("o", "request", "operaton. collection|folder|request|har|burp|mitm|http|openapi|postman|insomnia|export|proxy|serve")
 	flagCollection = flag.String("c", "", "collection name")
 	flagFolder     = flag.String("f", "", "folder name")
 	flagBaseDir    = flag.String("base", ".", "base collection folder for request")
 	flagEnvFile    = flag.String("e", "environments/base.bru", "environment file")
+	flagInput      = flag.String("i", "", "input file for har, burp, mitm, http, openapi, postman and insomnia operations, .bru file or directory for export")
+	flagSkipHeads  = flag.String("skip-headers", strings.Join(DefaultSkipHeaders, ","), "comma separated headers to drop from request")
+	flagPathParams = flag.Bool("path-params", false, "write env variables in path as bruno :path params")
+	flagMerge      = flag.Bool("merge", false, "merge new params, headers and body fields into existing request file")
+	flagSuffix     = flag.Bool("suffix", false, "write name-METHOD-2.bru if request file exists")
+	flagHosts      = flag.String("host", "", "comma separated hosts to import, subdomains included (for -o mitm and proxy)")
+	flagMethods    = flag.String("method", "", "comma separated methods to import (for -o mitm and proxy)")
+	flagExamples   = flag.Bool("examples", false, "keep captured responses as examples in docs (for -o burp and postman)")
+	flagStream     = flag.Bool("stream", false, "read several concatenated raw requests from stdin (for -o request)")
+	flagEnvs       = flag.String("envs", "", "comma separated Postman environment files (for -o postman)")
+	flagFormat     = flag.String("format", "raw", "export format raw|curl|har (for -o export)")
+	flagListen     = flag.String("listen", "127.0.0.1:8089", "listen address (for -o proxy and serve)")
+	flagToken      = flag.String("token", "", "bearer token of the API, random if empty (for -o serve)")
+	flagOrigins    = flag.String("origins", "", "comma separated origins allowed to post from browser pages (for -o serve)")
+	flagCADir      = flag.String("ca", "", "directory of the CA for https interception, created if missing (for -o proxy)")
+	flagScheme     = flag.String("scheme", "", "scheme of requests which don't define it by request line, :scheme, X-Forwarded-Proto or # meta line")
+	flagPortVar    = flag.Bool("port-var", false, "write non default ports to the port env var instead of the request url")
+	flagLearn      = flag.Bool("learn", false, "write detected ids, tokens and session cookies to env file as variables")
 )
 
 func main() {
 	flag.Parse()
 
+	if *flagMerge && *flagSuffix {
+		raiseError(fmt.Errorf("-merge and -suffix can't be used together"))
+	}
+
+	opts := RequestOptions{
+		SkipHeaders: ParseHeadersList(*flagSkipHeads),
+		PathParams:  *flagPathParams,
+		Merge:       *flagMerge,
+		Suffix:      *flagSuffix,
+		Learn:       *flagLearn,
+		Examples:    *flagExamples,
+		Scheme:      strings.ToLower(*flagScheme),
+		PortVar:     *flagPortVar,
+	}
+
 	switch *flagOp {
 	case "collection":
 		err := DoStructure(*flagCollection, *flagFolder)
@@ -29,7 +63,64 @@ func main() {
 			raiseError(err)
 		}
 	case "request":
-		err := DoRequest(*flagBaseDir, *flagEnvFile)
+		var err error
+		if *flagStream {
+			err = DoRequestStream(os.Stdin, os.Stdout, *flagBaseDir, *flagEnvFile, opts)
+		} else {
+			err = DoRequest(*flagBaseDir, *flagEnvFile, opts)
+		}
+		if err != nil {
+			raiseError(err)
+		}
+	case "har":
+		err := DoHAR(*flagInput, *flagBaseDir, *flagEnvFile, opts)
+		if err != nil {
+			raiseError(err)
+		}
+	case "burp":
+		err := DoBurp(*flagInput, *flagBaseDir, *flagEnvFile, opts)
+		if err != nil {
+			raiseError(err)
+		}
+	case "mitm":
+		filter := NewRequestFilter(*flagHosts, *flagMethods)
+		err := DoMitm(*flagInput, *flagBaseDir, *flagEnvFile, filter, opts)
+		if err != nil {
+			raiseError(err)
+		}
+	case "http":
+		err := DoHTTPFile(*flagInput, *flagBaseDir, *flagEnvFile, opts)
+		if err != nil {
+			raiseError(err)
+		}
+	case "openapi":
+		err := DoOpenAPI(*flagInput, *flagCollection, *flagBaseDir, opts)
+		if err != nil {
+			raiseError(err)
+		}
+	case "postman":
+		err := DoPostman(*flagInput, *flagCollection, *flagBaseDir, ParseHeadersList(*flagEnvs), opts)
+		if err != nil {
+			raiseError(err)
+		}
+	case "insomnia":
+		err := DoInsomnia(*flagInput, *flagCollection, *flagBaseDir, opts)
+		if err != nil {
+			raiseError(err)
+		}
+	case "proxy":
+		filter := NewRequestFilter(*flagHosts, *flagMethods)
+		err := DoProxy(*flagListen, *flagCADir, *flagBaseDir, *flagEnvFile, filter, opts)
+		if err != nil {
+			raiseError(err)
+		}
+	case "serve":
+		err := DoServe(*flagListen, *flagBaseDir, *flagEnvFile, *flagToken, ParseHeadersList(*flagOrigins), opts)
+		if err != nil {
+			raiseError(err)
+		}
+	case "export":
+		err := DoExport(*flagInput, *flagFormat, *flagEnvFile, os.Stdout)
 		if err != nil {
 			raiseError(err)
 		}
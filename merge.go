@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// mergeRequestFile merges the captured request content
+// into the existing .bru file
+func mergeRequestFile(fp, content string) error {
+	doc, err := BruFromFile(fp)
+	if err != nil {
+		return err
+	}
+
+	added := MergeRequest(doc, ParseBru(content))
+	if len(added) == 0 {
+		return nil
+	}
+
+	return doc.WriteFile(fp)
+}
+
+// nextFreeName returns the first name-N (N from 2) without .bru file in dir
+// and the file path
+func nextFreeName(dir, name string) (string, string) {
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", name, i)
+		fp := filepath.Join(dir, candidate+".bru")
+		if _, err := os.Stat(fp); err != nil {
+			return candidate, fp
+		}
+	}
+}
+
+// MergeRequest adds query params, headers and body fields of the captured
+// request which are missing in doc. Every added item is marked in the docs
+// checklist, user's scripts, asserts and docs are kept intact.
+// Returns descriptions of added items.
+func MergeRequest(doc, captured *BruDoc) []string {
+	var added []string
+
+	params := mergeDictBlock(doc, captured, "params:query", false)
+	for _, key := range params {
+		added = append(added, "new param: "+key)
+	}
+	if len(params) > 0 {
+		updateURLQuery(doc)
+	}
+
+	for _, key := range mergeDictBlock(doc, captured, "headers", true) {
+		added = append(added, "new header: "+key)
+	}
+
+	added = append(added, mergeBody(doc, captured)...)
+
+	if len(added) == 0 {
+		return nil
+	}
+
+	docs := doc.Block("docs")
+	if docs == nil {
+		docs = NewBruText("docs", "")
+		doc.AddBlock(docs)
+	}
+	lines := docs.Text
+	for _, item := range added {
+		if lines != "" && !strings.HasSuffix(lines, "\n") {
+			lines += "\n"
+		}
+		lines += "- [ ] " + item
+	}
+	docs.Text = lines
+
+	return added
+}
+
+// methodBlock returns the request method block (get, post...)
+func methodBlock(doc *BruDoc) *BruBlock {
+	for _, name := range httpMethodBlocks {
+		if b := doc.Block(name); b != nil {
+			return b
+		}
+	}
+	return nil
+}
+
+// mergeDictBlock adds entries of the captured block missing in doc,
+// keys are compared case insensitive if fold is set.
+// Returns added keys.
+func mergeDictBlock(doc, captured *BruDoc, name string, fold bool) []string {
+	src := captured.Block(name)
+	if src == nil || len(src.Entries) == 0 {
+		return nil
+	}
+
+	dst := doc.Block(name)
+	if dst == nil {
+		dst = &BruBlock{Name: name, Kind: src.Kind}
+		doc.InsertBlock(dst)
+	}
+
+	var added []string
+	for _, entry := range src.Entries {
+		if bruHasKey(dst, entry.Key, fold) {
+			continue
+		}
+		dst.Entries = append(dst.Entries, entry)
+		added = append(added, entry.Key)
+	}
+	return added
+}
+
+func bruHasKey(b *BruBlock, key string, fold bool) bool {
+	for _, e := range b.Entries {
+		if e.Key == key || (fold && strings.EqualFold(e.Key, key)) {
+			return true
+		}
+	}
+	return false
+}
+
+// updateURLQuery rewrites the query string of the method block url
+// from enabled entries of params:query. Empty params written without
+// '=' in the url stay that way.
+func updateURLQuery(doc *BruDoc) {
+	method := methodBlock(doc)
+	params := doc.Block("params:query")
+	if method == nil || params == nil {
+		return
+	}
+	u, ok := method.Get("url")
+	if !ok {
+		return
+	}
+
+	base, rawQuery, _ := strings.Cut(u, "?")
+	noValue := make(map[string]bool)
+	for _, param := range ParseQuery(rawQuery) {
+		if param.NoValue {
+			noValue[param.Key] = true
+		}
+	}
+	var query QueryParams
+	for _, p := range params.Pairs() {
+		query = append(query, QueryParam{Key: p.Key, Value: p.Value, NoValue: noValue[p.Key]})
+	}
+	if len(query) > 0 {
+		base += "?" + query.String()
+	}
+	method.Set("url", base)
+}
+
+// mergeBody adds body fields of the captured request.
+// Form bodies are merged by field name, json bodies by top level keys.
+// If doc has no body, captured body is added as is. A captured body
+// of another mode isn't merged, it's only reported.
+// Returns descriptions of added items.
+func mergeBody(doc, captured *BruDoc) []string {
+	src := captured.BlocksWithPrefix("body:")
+	if len(src) == 0 {
+		return nil
+	}
+	body := src[0]
+
+	existing := doc.BlocksWithPrefix("body:")
+	if len(existing) == 0 {
+		if cm, dm := methodBlock(captured), methodBlock(doc); cm != nil && dm != nil {
+			mode, _ := cm.Get("body")
+			dm.Set("body", mode)
+		}
+		doc.InsertBlock(body)
+
+		var keys []string
+		if body.Kind == BruDict {
+			for _, e := range body.Entries {
+				keys = append(keys, e.Key)
+			}
+		} else if fields, ok := parseJSONObject(body.Text); ok {
+			for _, f := range fields {
+				keys = append(keys, f.Key)
+			}
+		}
+		return bodyParams(keys)
+	}
+
+	dst := doc.Block(body.Name)
+	switch {
+	case dst == nil:
+		mode := strings.TrimPrefix(body.Name, "body:")
+		fmt.Fprintf(os.Stderr, "[W] captured %s body isn't merged into %s\n", mode, strings.TrimPrefix(existing[0].Name, "body:"))
+		return []string{"new body " + mode}
+	case body.Kind == BruDict:
+		return bodyParams(mergeDictBlock(doc, captured, body.Name, false))
+	case body.Name == "body:json":
+		text, keys := mergeJSONObject(dst.Text, body.Text)
+		if len(keys) > 0 {
+			dst.Text = text
+		}
+		return bodyParams(keys)
+	}
+	return nil
+}
+
+// bodyParams returns `new body param: key` items of added body keys
+func bodyParams(keys []string) []string {
+	var added []string
+	for _, key := range keys {
+		added = append(added, "new body param: "+key)
+	}
+	return added
+}
+
+// jsonField top level field of a json object in source order
+type jsonField struct {
+	Key   string
+	Value json.RawMessage
+}
+
+// parseJSONObject returns top level fields of the json object in source order
+func parseJSONObject(s string) ([]jsonField, bool) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	tok, err := dec.Token()
+	if err != nil || tok != json.Delim('{') {
+		return nil, false
+	}
+
+	var fields []jsonField
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, false
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil, false
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, false
+		}
+		fields = append(fields, jsonField{Key: key, Value: value})
+	}
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('}') {
+		return nil, false
+	}
+	if _, err := dec.Token(); err == nil {
+		return nil, false
+	}
+
+	return fields, true
+}
+
+// mergeJSONObject adds top level keys of src missing in dst.
+// Returns merged json and added keys, dst is returned as is
+// if nothing was added or any side isn't a json object.
+func mergeJSONObject(dst, src string) (string, []string) {
+	dstFields, ok := parseJSONObject(dst)
+	if !ok {
+		return dst, nil
+	}
+	srcFields, ok := parseJSONObject(src)
+	if !ok {
+		return dst, nil
+	}
+
+	known := make(map[string]bool)
+	for _, f := range dstFields {
+		known[f.Key] = true
+	}
+	var added []string
+	for _, f := range srcFields {
+		if !known[f.Key] {
+			dstFields = append(dstFields, f)
+			added = append(added, f.Key)
+		}
+	}
+	if len(added) == 0 {
+		return dst, nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("{\n")
+	for i, f := range dstFields {
+		key, _ := json.Marshal(f.Key)
+		buf.WriteString("  ")
+		buf.Write(key)
+		buf.WriteString(": ")
+		if err := json.Indent(&buf, f.Value, "  ", "  "); err != nil {
+			buf.Write(f.Value)
+		}
+		if i < len(dstFields)-1 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString("}")
+
+	return buf.String(), added
+}
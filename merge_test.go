@@ -0,0 +1,301 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const mergeExistingFixture = `meta {
+  name: api-users-POST
+  type: http
+  seq: 1
+}
+
+post {
+  url: {{proto}}://{{host}}/api/users?page=1
+  body: json
+  auth: none
+}
+
+params:query {
+  page: 1
+}
+
+headers {
+  Accept: application/json
+}
+
+body:json {
+  {
+    "name": "bob"
+  }
+}
+
+assert {
+  res.status: eq 201
+}
+
+script:post-response {
+  bru.setVar("id", res.body.id);
+}
+
+docs {
+  - [x] methods
+  - [ ] params
+}
+`
+
+func TestMergeRequest(t *testing.T) {
+	captured := ParseBru(requestContent(RequestData{
+		Name:     "api-users-POST",
+		Method:   "POST",
+		Path:     "/api/users",
+		RawQuery: "page=2&sort=asc",
+		BodyType: "json",
+		Body:     `{"name": "alice", "role": "admin", "tags": ["a"]}`,
+		HTTPReq: &http.Request{Header: http.Header{
+			"Accept":    {"text/html"},
+			"X-Request": {"1"},
+		}},
+		HeaderOrder: []string{"Accept", "X-Request"},
+		Env:         &BrunoEnv{Vars: map[string]string{"proto": "https", "host": "example.com"}},
+	}))
+
+	doc := ParseBru(mergeExistingFixture)
+	added := MergeRequest(doc, captured)
+
+	expectedAdded := []string{
+		"new param: sort",
+		"new header: X-Request",
+		"new body param: role",
+		"new body param: tags",
+	}
+	if !reflect.DeepEqual(added, expectedAdded) {
+		t.Errorf("MergeRequest() added = %v, want %v", added, expectedAdded)
+	}
+
+	got := doc.String()
+	for _, want := range []string{
+		"url: {{proto}}://{{host}}/api/users?page=1&sort=asc",
+		"params:query {\n  page: 1\n  sort: asc\n}",
+		"headers {\n  Accept: application/json\n  X-Request: 1\n}",
+		"body:json {\n  {\n    \"name\": \"bob\",\n    \"role\": \"admin\",\n    \"tags\": [\n      \"a\"\n    ]\n  }\n}",
+		"assert {\n  res.status: eq 201\n}",
+		"script:post-response {\n  bru.setVar(\"id\", res.body.id);\n}",
+		"docs {\n  - [x] methods\n  - [ ] params\n  - [ ] new param: sort\n  - [ ] new header: X-Request\n  - [ ] new body param: role\n  - [ ] new body param: tags\n}",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("merged document should contain %q\ngot:\n%s", want, got)
+		}
+	}
+}
+
+func TestMergeRequestKeepsQueryKeysWithoutValue(t *testing.T) {
+	rd := RequestData{
+		Name:     "items",
+		Method:   "GET",
+		Path:     "/items/5",
+		RawQuery: "flag",
+		BodyType: "none",
+		Env:      &BrunoEnv{Vars: map[string]string{"proto": "https", "host": "example.com"}},
+	}
+	doc := ParseBru(requestContent(rd))
+	rd.RawQuery = "flag&x=1"
+	MergeRequest(doc, ParseBru(requestContent(rd)))
+
+	if want := "url: {{proto}}://{{host}}/items/5?flag&x=1"; !strings.Contains(doc.String(), want) {
+		t.Errorf("merged document should contain %q\ngot:\n%s", want, doc)
+	}
+}
+
+func TestMergeRequestOtherBodyMode(t *testing.T) {
+	captured := ParseBru(requestContent(RequestData{
+		Name:     "api-users-POST",
+		Method:   "POST",
+		Path:     "/api/users",
+		RawQuery: "page=1",
+		BodyType: "formUrlEncoded",
+		Body:     "name=alice",
+		Env:      &BrunoEnv{Vars: map[string]string{"proto": "https", "host": "example.com"}},
+	}))
+
+	doc := ParseBru(mergeExistingFixture)
+	added := MergeRequest(doc, captured)
+	if want := []string{"new body form-urlencoded"}; !reflect.DeepEqual(added, want) {
+		t.Errorf("MergeRequest() added = %v, want %v", added, want)
+	}
+
+	got := doc.String()
+	for _, want := range []string{
+		"body:json {\n  {\n    \"name\": \"bob\"\n  }\n}",
+		"  - [ ] new body form-urlencoded\n}",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("merged document should contain %q\ngot:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "body:form-urlencoded") {
+		t.Errorf("captured body of another mode should not be merged\ngot:\n%s", got)
+	}
+}
+
+func TestMergeRequestNothingNew(t *testing.T) {
+	doc := ParseBru(mergeExistingFixture)
+	captured := ParseBru(mergeExistingFixture)
+
+	if added := MergeRequest(doc, captured); len(added) != 0 {
+		t.Errorf("MergeRequest() added = %v, want nothing", added)
+	}
+	if doc.String() != mergeExistingFixture {
+		t.Errorf("document should not change\ngot:\n%s", doc.String())
+	}
+}
+
+func TestMergeRequestAddsMissingBlocks(t *testing.T) {
+	existing := "meta {\n  name: a\n}\n\nget {\n  url: {{proto}}://{{host}}/a\n  body: none\n  auth: none\n}\n\nsettings {\n  encodeUrl: false\n}\n"
+	captured := ParseBru(requestContent(RequestData{
+		Name:     "a",
+		Method:   "POST",
+		Path:     "/a",
+		RawQuery: "q=1",
+		BodyType: "formUrlEncoded",
+		Body:     "x=1&y=2",
+		Env:      &BrunoEnv{Vars: map[string]string{"proto": "https", "host": "example.com"}},
+	}))
+
+	doc := ParseBru(existing)
+	added := MergeRequest(doc, captured)
+	if len(added) != 3 {
+		t.Errorf("MergeRequest() added = %v, want 3 items", added)
+	}
+
+	names := []string{}
+	for _, b := range doc.Blocks {
+		names = append(names, b.Name)
+	}
+	expected := []string{"meta", "get", "params:query", "body:form-urlencoded", "settings", "docs"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("blocks = %v, want %v", names, expected)
+	}
+	if mode, _ := doc.Block("get").Get("body"); mode != "formUrlEncoded" {
+		t.Errorf("body mode = %q, want formUrlEncoded", mode)
+	}
+	if url, _ := doc.Block("get").Get("url"); url != "{{proto}}://{{host}}/a?q=1" {
+		t.Errorf("url = %q", url)
+	}
+}
+
+func TestMergeJSONObject(t *testing.T) {
+	tests := []struct {
+		name      string
+		dst       string
+		src       string
+		expected  string
+		wantAdded []string
+	}{
+		{
+			name:      "keys appended in source order",
+			dst:       `{"b": 1, "a": {"x": 1}}`,
+			src:       `{"a": 2, "z": null, "c": "s"}`,
+			expected:  "{\n  \"b\": 1,\n  \"a\": {\n    \"x\": 1\n  },\n  \"z\": null,\n  \"c\": \"s\"\n}",
+			wantAdded: []string{"z", "c"},
+		},
+		{
+			name:     "nothing new keeps dst as is",
+			dst:      `{"a":1}`,
+			src:      `{"a": 2}`,
+			expected: `{"a":1}`,
+		},
+		{
+			name:     "array body is not merged",
+			dst:      `[1]`,
+			src:      `{"a": 2}`,
+			expected: `[1]`,
+		},
+		{
+			name:     "invalid json is not merged",
+			dst:      `{"a": 1}`,
+			src:      `{"id": {{id}}}`,
+			expected: `{"a": 1}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, added := mergeJSONObject(tt.dst, tt.src)
+			if got != tt.expected {
+				t.Errorf("mergeJSONObject() = %q, want %q", got, tt.expected)
+			}
+			if !reflect.DeepEqual(added, tt.wantAdded) {
+				t.Errorf("mergeJSONObject() added = %v, want %v", added, tt.wantAdded)
+			}
+		})
+	}
+}
+
+func TestCreateRequestFileExistingModes(t *testing.T) {
+	newRD := func(basedir, query string, opts RequestOptions) RequestData {
+		return RequestData{
+			Basedir:  basedir,
+			Method:   "GET",
+			Path:     "/api/items",
+			RawQuery: query,
+			BodyType: "none",
+			Options:  opts,
+			Env:      &BrunoEnv{Vars: map[string]string{"proto": "https", "host": "example.com"}},
+		}
+	}
+
+	t.Run("default mode fails", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		if _, err := createRequestFile(newRD(tmpDir, "", RequestOptions{})); err != nil {
+			t.Fatalf("createRequestFile() error = %v", err)
+		}
+		if _, err := createRequestFile(newRD(tmpDir, "", RequestOptions{})); err == nil {
+			t.Errorf("createRequestFile() should fail for existing file")
+		}
+	})
+
+	t.Run("suffix mode", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		opts := RequestOptions{Suffix: true}
+		for _, want := range []string{"api-items-GET.bru", "api-items-GET-2.bru", "api-items-GET-3.bru"} {
+			fp, err := createRequestFile(newRD(tmpDir, "", opts))
+			if err != nil {
+				t.Fatalf("createRequestFile() error = %v", err)
+			}
+			if fp != filepath.Join(tmpDir, want) {
+				t.Errorf("createRequestFile() = %q, want %q", fp, want)
+			}
+		}
+		data, _ := os.ReadFile(filepath.Join(tmpDir, "api-items-GET-2.bru"))
+		if !strings.Contains(string(data), "name: api-items-GET-2") {
+			t.Errorf("suffixed file should have own name\ngot:\n%s", data)
+		}
+	})
+
+	t.Run("merge mode", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		opts := RequestOptions{Merge: true}
+		if _, err := createRequestFile(newRD(tmpDir, "a=1", opts)); err != nil {
+			t.Fatalf("createRequestFile() error = %v", err)
+		}
+		fp, err := createRequestFile(newRD(tmpDir, "b=2", opts))
+		if err != nil {
+			t.Fatalf("createRequestFile() error = %v", err)
+		}
+		data, _ := os.ReadFile(fp)
+		for _, want := range []string{"?a=1&b=2", "  a: 1\n  b: 2\n", "- [ ] new param: b"} {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("merged file should contain %q\ngot:\n%s", want, data)
+			}
+		}
+		if entries, _ := os.ReadDir(tmpDir); len(entries) != 1 {
+			t.Errorf("merge should not create new files, got %d", len(entries))
+		}
+	})
+}
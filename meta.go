@@ -1,10 +1,61 @@
 package main
 
+import "strings"
+
+// MetaOrder canonical order of meta keys, as bruno writes them
+var MetaOrder = []string{"name", "type", "seq"}
+
 // meta block in format
 //
 //	meta {
 //	  name: api
 //	}
-func MetaGenerate(vars map[string]string) string {
-	return NameBlockMap("meta", vars)
+//
+// Known keys are written in MetaOrder, others keep their order.
+func MetaGenerate(vars Pairs) string {
+	ordered := make(Pairs, 0, len(vars))
+	for _, key := range MetaOrder {
+		for _, pair := range vars {
+			if pair.Key == key {
+				ordered = append(ordered, pair)
+			}
+		}
+	}
+	for _, pair := range vars {
+		if !metaKnownKey(pair.Key) {
+			ordered = append(ordered, pair)
+		}
+	}
+	return NameBlockMap("meta", ordered)
+}
+
+func metaKnownKey(key string) bool {
+	for _, known := range MetaOrder {
+		if key == known {
+			return true
+		}
+	}
+	return false
+}
+
+// MetaAddTags adds the tags list to the meta block
+//
+//	meta {
+//	  name: api
+//	  tags: [
+//	    smoke
+//	  ]
+//	}
+func MetaAddTags(block string, tags []string) string {
+	if len(tags) == 0 {
+		return block
+	}
+	var sb strings.Builder
+	sb.WriteString(strings.TrimSuffix(block, "}\n"))
+	sb.WriteString("  tags: [\n")
+	for _, tag := range tags {
+		sb.WriteString("    " + tag + "\n")
+	}
+	sb.WriteString("  ]\n}\n")
+	return sb.String()
 }
@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestMetaGenerate(t *testing.T) {
+	tests := []struct {
+		name     string
+		vars     Pairs
+		expected string
+	}{
+		{
+			name:     "empty meta",
+			vars:     nil,
+			expected: "",
+		},
+		{
+			name:     "known keys in canonical order",
+			vars:     Pairs{{"seq", "3"}, {"type", "http"}, {"name", "login"}},
+			expected: "meta {\n  name: login\n  type: http\n  seq: 3\n}\n",
+		},
+		{
+			name:     "unknown keys keep order after known",
+			vars:     Pairs{{"tags", "auth"}, {"name", "login"}, {"auth", "none"}},
+			expected: "meta {\n  name: login\n  tags: auth\n  auth: none\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MetaGenerate(tt.vars)
+			if got != tt.expected {
+				t.Errorf("MetaGenerate() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMetaAddTags(t *testing.T) {
+	block := MetaGenerate(Pairs{{"name", "login"}})
+	if got := MetaAddTags(block, nil); got != block {
+		t.Errorf("MetaAddTags() without tags = %q", got)
+	}
+	want := "meta {\n  name: login\n  tags: [\n    smoke\n    auth\n  ]\n}\n"
+	if got := MetaAddTags(block, []string{"smoke", "auth"}); got != want {
+		t.Errorf("MetaAddTags() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DoMitm converts http flows of the mitmproxy dump (mitmdump -w)
+// into .bru requests of the matching collection. Flows not matching
+// the filter are ignored. Created files are printed to stdout,
+// skipped flows and summary to stderr.
+func DoMitm(input, basedir, envfile string, filter RequestFilter, opts RequestOptions) error {
+	if input == "" {
+		return fmt.Errorf("-i input file is required")
+	}
+
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return fmt.Errorf("read %q file error %w", input, err)
+	}
+
+	flows, err := ReadMitmFlows(data)
+	if err != nil {
+		return fmt.Errorf("parse mitmproxy flows error %w", err)
+	}
+
+	created, skipped, filtered := 0, 0, 0
+	for i, flow := range flows {
+		req, order, err := MitmFlowToHTTP(flow)
+		if err != nil {
+			skipped++
+			fmt.Fprintf(os.Stderr, "[W] skip flow %d: %s\n", i, err)
+			continue
+		}
+		if !filter.Match(req) {
+			filtered++
+			continue
+		}
+		fp, err := ConvertRequest(req, order, basedir, envfile, opts)
+		if err != nil {
+			skipped++
+			fmt.Fprintf(os.Stderr, "[W] skip flow %d %s %s: %s\n", i, req.Method, req.URL, err)
+			continue
+		}
+		created++
+		fmt.Println(fp)
+	}
+
+	fmt.Fprintf(os.Stderr, "[I] mitmproxy flows: %d, created: %d, skipped: %d, filtered: %d\n", len(flows), created, skipped, filtered)
+	return nil
+}
+
+// ReadMitmFlows decodes all flows of the mitmproxy dump
+func ReadMitmFlows(data []byte) ([]map[string]any, error) {
+	var flows []map[string]any
+	for len(bytes.TrimSpace(data)) > 0 {
+		value, rest, err := ParseTNetString(data)
+		if err != nil {
+			return nil, fmt.Errorf("flow %d: %w", len(flows), err)
+		}
+		flow, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("flow %d: not a dict", len(flows))
+		}
+		flows = append(flows, flow)
+		data = rest
+	}
+	return flows, nil
+}
+
+// MitmFlowToHTTP builds *http.Request from the mitmproxy http flow
+// and returns header names in captured order.
+// HTTP/2 pseudo-headers are skipped, :authority is used as Host.
+func MitmFlowToHTTP(flow map[string]any) (*http.Request, []string, error) {
+	if kind := tnetString(flow["type"]); kind != "" && kind != "http" {
+		return nil, nil, fmt.Errorf("unsupported flow type %q", kind)
+	}
+	r, ok := flow["request"].(map[string]any)
+	if !ok {
+		return nil, nil, fmt.Errorf("flow has no request")
+	}
+
+	method := tnetString(r["method"])
+	scheme := tnetString(r["scheme"])
+	host := tnetString(r["host"])
+	path := tnetString(r["path"])
+	if method == "" || host == "" {
+		return nil, nil, fmt.Errorf("flow request has no method or host")
+	}
+	if scheme != "http" && scheme != "https" {
+		return nil, nil, fmt.Errorf("unsupported url scheme %q", scheme)
+	}
+
+	authority := tnetString(r["authority"])
+	if authority == "" {
+		authority = host
+		port := tnetInt(r["port"])
+		if port != 0 && !(scheme == "http" && port == 80) && !(scheme == "https" && port == 443) {
+			authority += ":" + strconv.FormatInt(port, 10)
+		}
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	content, _ := r["content"].([]byte)
+	req, err := http.NewRequest(method, scheme+"://"+authority+path, bytes.NewReader(content))
+	if err != nil {
+		return nil, nil, fmt.Errorf("create request error %w", err)
+	}
+
+	var order []string
+	headers, _ := r["headers"].([]any)
+	for _, h := range headers {
+		pair, ok := h.([]any)
+		if !ok || len(pair) != 2 {
+			continue
+		}
+		name, value := tnetString(pair[0]), tnetString(pair[1])
+		if name == "" || strings.HasPrefix(name, ":") {
+			continue
+		}
+		key := http.CanonicalHeaderKey(name)
+		if key == "Host" {
+			req.Host = value
+			continue
+		}
+		req.Header.Add(key, value)
+		order = append(order, key)
+	}
+
+	return req, order, nil
+}
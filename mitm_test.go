@@ -0,0 +1,138 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testMitmFlow(method, scheme, host string, port int, path string, headers [][2]string, content string) map[string]any {
+	var hs []any
+	for _, h := range headers {
+		hs = append(hs, []any{[]byte(h[0]), []byte(h[1])})
+	}
+	var body any
+	if content != "" {
+		body = []byte(content)
+	}
+	return map[string]any{
+		"version": 19,
+		"type":    "http",
+		"request": map[string]any{
+			"method":       []byte(method),
+			"scheme":       []byte(scheme),
+			"host":         host,
+			"port":         port,
+			"authority":    []byte(""),
+			"path":         []byte(path),
+			"http_version": []byte("HTTP/1.1"),
+			"headers":      hs,
+			"content":      body,
+		},
+	}
+}
+
+// roundTrip encodes and decodes the flow to get parsed value types
+func roundTrip(t *testing.T, flow map[string]any) map[string]any {
+	t.Helper()
+	flows, err := ReadMitmFlows([]byte(tnetEncode(flow)))
+	if err != nil || len(flows) != 1 {
+		t.Fatalf("ReadMitmFlows() = %v, %v", flows, err)
+	}
+	return flows[0]
+}
+
+func TestMitmFlowToHTTP(t *testing.T) {
+	flow := testMitmFlow("POST", "https", "api.example.com", 8443, "/users?page=1",
+		[][2]string{{"content-type", "application/json"}, {"x-b", "1"}, {"X-A", "2"}},
+		`{"a":1}`)
+
+	req, order, err := MitmFlowToHTTP(roundTrip(t, flow))
+	if err != nil {
+		t.Fatalf("MitmFlowToHTTP() error = %v", err)
+	}
+	if req.Method != "POST" || req.Host != "api.example.com:8443" || req.URL.String() != "https://api.example.com:8443/users?page=1" {
+		t.Errorf("request = %s %s host %s", req.Method, req.URL, req.Host)
+	}
+	if expected := []string{"Content-Type", "X-B", "X-A"}; !reflect.DeepEqual(order, expected) {
+		t.Errorf("order = %v, want %v", order, expected)
+	}
+	body, _ := io.ReadAll(req.Body)
+	if string(body) != `{"a":1}` {
+		t.Errorf("body = %q", body)
+	}
+
+	// HTTP/2 flow without Host header
+	h2 := testMitmFlow("GET", "https", "api.example.com", 443, "/", [][2]string{{":authority", "api.example.com"}, {"accept", "*/*"}}, "")
+	h2["request"].(map[string]any)["authority"] = []byte("api.example.com")
+	req, order, err = MitmFlowToHTTP(roundTrip(t, h2))
+	if err != nil {
+		t.Fatalf("MitmFlowToHTTP() error = %v", err)
+	}
+	if req.Host != "api.example.com" || !reflect.DeepEqual(order, []string{"Accept"}) {
+		t.Errorf("h2 request host = %q, order = %v", req.Host, order)
+	}
+
+	if _, _, err := MitmFlowToHTTP(map[string]any{"type": "tcp"}); err == nil {
+		t.Error("MitmFlowToHTTP() expected error for tcp flow")
+	}
+	if _, _, err := MitmFlowToHTTP(roundTrip(t, testMitmFlow("GET", "ws", "a.com", 80, "/", nil, ""))); err == nil {
+		t.Error("MitmFlowToHTTP() expected error for ws scheme")
+	}
+}
+
+func TestReadMitmFlows(t *testing.T) {
+	data := tnetEncode(testMitmFlow("GET", "http", "a.com", 80, "/", nil, "")) +
+		tnetEncode(testMitmFlow("GET", "http", "b.com", 80, "/", nil, "")) + "\n"
+	flows, err := ReadMitmFlows([]byte(data))
+	if err != nil {
+		t.Fatalf("ReadMitmFlows() error = %v", err)
+	}
+	if len(flows) != 2 {
+		t.Fatalf("ReadMitmFlows() = %d flows, want 2", len(flows))
+	}
+
+	if _, err := ReadMitmFlows([]byte("5:abc")); err == nil {
+		t.Error("ReadMitmFlows() expected error for truncated data")
+	}
+	if _, err := ReadMitmFlows([]byte("3:abc,")); err == nil {
+		t.Error("ReadMitmFlows() expected error for non dict flow")
+	}
+}
+
+func TestDoMitm(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	if err := DoCollection("api.example.com", "."); err != nil {
+		t.Fatalf("DoCollection() error = %v", err)
+	}
+
+	data := tnetEncode(testMitmFlow("GET", "https", "api.example.com", 443, "/users", [][2]string{{"Host", "api.example.com"}}, "")) +
+		tnetEncode(testMitmFlow("DELETE", "https", "api.example.com", 443, "/users/1", [][2]string{{"Host", "api.example.com"}}, "")) +
+		tnetEncode(testMitmFlow("GET", "https", "cdn.other.com", 443, "/app.js", [][2]string{{"Host", "cdn.other.com"}}, "")) +
+		tnetEncode(testMitmFlow("POST", "https", "api.example.com", 443, "/login", [][2]string{{"Host", "api.example.com"}, {"Content-Type", "application/json"}}, `{"u":1}`))
+	os.WriteFile("flows.mitm", []byte(data), 0o644)
+
+	filter := NewRequestFilter("example.com", "GET,POST")
+	if err := DoMitm("flows.mitm", ".", "environments/base.bru", filter, RequestOptions{SkipHeaders: DefaultSkipHeaders}); err != nil {
+		t.Fatalf("DoMitm() error = %v", err)
+	}
+
+	for _, fp := range []string{"users-GET.bru", "login-POST.bru"} {
+		if _, err := os.Stat(filepath.Join("api.example.com", fp)); err != nil {
+			t.Errorf("expected file %q was not created", fp)
+		}
+	}
+	entries, _ := os.ReadDir("api.example.com")
+	for _, e := range entries {
+		if strings.Contains(e.Name(), "DELETE") {
+			t.Errorf("filtered DELETE flow was converted: %s", e.Name())
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FilesDir collection subfolder for files of multipart bodies
+const FilesDir = "files"
+
+// FormPart part of the multipart/form-data body
+type FormPart struct {
+	Name string
+	// Value field value or file content
+	Value string
+	// FileName original file name, empty for plain fields
+	FileName string
+	// ContentType value of the part Content-Type header
+	ContentType string
+	// Path file path relative to the collection dir,
+	// set when the file is saved
+	Path string
+}
+
+var (
+	fileNameUnsafeRe  = regexp.MustCompile(`[^\w.\-]+`)
+	formContentTypeRe = regexp.MustCompile(`\s*@contentType\(([^()]*)\)$`)
+	formFileValueRe   = regexp.MustCompile(`^@file\(([^()]*)\)$`)
+)
+
+// maxFormPartSize is the size limit of a single multipart part
+const maxFormPartSize = 10 << 20
+
+// ParseMultipartParts parse http multipart/form-data body
+// to parts in original order, including file parts.
+// A part over maxFormPartSize is an error, it isn't truncated.
+func ParseMultipartParts(body string) ([]FormPart, error) {
+	if body == "" {
+		return nil, nil
+	}
+
+	var parts []FormPart
+	reader := multipart.NewReader(strings.NewReader(body), extractBoundary(body))
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			return parts, nil
+		}
+		if part.FormName() == "" {
+			continue
+		}
+		value, err := io.ReadAll(io.LimitReader(part, maxFormPartSize+1))
+		if err != nil {
+			return parts, nil
+		}
+		if len(value) > maxFormPartSize {
+			return parts, fmt.Errorf("multipart part %q is larger than %d MB", part.FormName(), maxFormPartSize>>20)
+		}
+		parts = append(parts, FormPart{
+			Name:        part.FormName(),
+			Value:       string(value),
+			FileName:    part.FileName(),
+			ContentType: part.Header.Get("Content-Type"),
+		})
+	}
+}
+
+// FormPartsPairs returns `body:multipart-form` entries.
+// Files are referenced as @file(path), explicit part content types
+// are kept with @contentType(type).
+func FormPartsPairs(parts []FormPart) Pairs {
+	var result Pairs
+	for _, p := range parts {
+		value := p.Value
+		if p.FileName != "" {
+			path := p.Path
+			if path == "" {
+				path = FilesDir + "/" + safeFileName(p.FileName)
+			}
+			value = "@file(" + path + ")"
+		}
+		if p.ContentType != "" {
+			value += " @contentType(" + p.ContentType + ")"
+		}
+		result.Add(p.Name, value)
+	}
+	return result
+}
+
+// ParseFormPartValue reverses FormPartsPairs for a single
+// `body:multipart-form` entry: @file(path) sets Path and FileName,
+// @contentType(type) sets ContentType
+func ParseFormPartValue(name, value string) FormPart {
+	part := FormPart{Name: name, Value: value}
+	if m := formContentTypeRe.FindStringSubmatch(value); m != nil {
+		part.ContentType = m[1]
+		part.Value = strings.TrimSuffix(value, m[0])
+	}
+	if m := formFileValueRe.FindStringSubmatch(part.Value); m != nil {
+		part.Path = m[1]
+		part.FileName = filepath.Base(m[1])
+		part.Value = ""
+	}
+	return part
+}
+
+// saveFormFiles writes file parts to the files folder of the collection
+// and sets their Path. A file with the same name and content is reused,
+// otherwise name-2.ext, name-3.ext... is used.
+func saveFormFiles(basedir string, parts []FormPart) error {
+	if basedir == "" {
+		basedir = "."
+	}
+
+	for i, p := range parts {
+		if p.FileName == "" {
+			continue
+		}
+		dir := filepath.Join(basedir, FilesDir)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("create %q dir error %w", dir, err)
+		}
+
+		name := safeFileName(p.FileName)
+		ext := filepath.Ext(name)
+		stem := strings.TrimSuffix(name, ext)
+		for n := 2; ; n++ {
+			fp := filepath.Join(dir, name)
+			existing, err := os.ReadFile(fp)
+			if err != nil {
+				if err := os.WriteFile(fp, []byte(p.Value), 0o644); err != nil {
+					return fmt.Errorf("write %q file error %w", fp, err)
+				}
+				break
+			}
+			if bytes.Equal(existing, []byte(p.Value)) {
+				break
+			}
+			name = fmt.Sprintf("%s-%d%s", stem, n, ext)
+		}
+		parts[i].Path = FilesDir + "/" + name
+	}
+
+	return nil
+}
+
+// safeFileName returns the base name of the uploaded file
+// with characters unsafe for file paths replaced by "_"
+func safeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = fileNameUnsafeRe.ReplaceAllString(name, "_")
+	name = strings.TrimLeft(name, ".")
+	if name == "" || name == "_" {
+		return "file"
+	}
+	return name
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testMultipartBody = "--boundary\r\n" +
+	"Content-Disposition: form-data; name=\"title\"\r\n\r\n" +
+	"hello\r\n" +
+	"--boundary\r\n" +
+	"Content-Disposition: form-data; name=\"doc\"; filename=\"report.pdf\"\r\n" +
+	"Content-Type: application/pdf\r\n\r\n" +
+	"%PDF-1.4\r\n" +
+	"--boundary\r\n" +
+	"Content-Disposition: form-data; name=\"meta\"\r\n" +
+	"Content-Type: application/json\r\n\r\n" +
+	"{\"a\":1}\r\n" +
+	"--boundary\r\n" +
+	"Content-Disposition: form-data; name=\"doc\"; filename=\"report.pdf\"\r\n" +
+	"Content-Type: application/pdf\r\n\r\n" +
+	"%PDF-1.7\r\n" +
+	"--boundary--\r\n"
+
+func TestParseMultipartParts(t *testing.T) {
+	expected := []FormPart{
+		{Name: "title", Value: "hello"},
+		{Name: "doc", Value: "%PDF-1.4", FileName: "report.pdf", ContentType: "application/pdf"},
+		{Name: "meta", Value: "{\"a\":1}", ContentType: "application/json"},
+		{Name: "doc", Value: "%PDF-1.7", FileName: "report.pdf", ContentType: "application/pdf"},
+	}
+	got, err := ParseMultipartParts(testMultipartBody)
+	if err != nil || !reflect.DeepEqual(got, expected) {
+		t.Errorf("ParseMultipartParts() = %+v, %v, want %+v", got, err, expected)
+	}
+
+	if got, err := ParseMultipartParts(""); got != nil || err != nil {
+		t.Errorf("ParseMultipartParts(\"\") = %+v, %v, want nil", got, err)
+	}
+}
+
+func TestParseMultipartPartsTooLarge(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		size    int
+		wantErr bool
+	}{
+		{"at limit", maxFormPartSize, false},
+		{"over limit", maxFormPartSize + 1, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			body := "--boundary\r\n" +
+				"Content-Disposition: form-data; name=\"f\"; filename=\"big.bin\"\r\n\r\n" +
+				strings.Repeat("a", tt.size) + "\r\n" +
+				"--boundary--\r\n"
+			parts, err := ParseMultipartParts(body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMultipartParts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (len(parts) != 1 || len(parts[0].Value) != tt.size) {
+				t.Errorf("ParseMultipartParts() = %d parts, want 1 part of %d bytes", len(parts), tt.size)
+			}
+		})
+	}
+}
+
+func TestFormPartsPairs(t *testing.T) {
+	parts := []FormPart{
+		{Name: "title", Value: "hello"},
+		{Name: "doc", Value: "x", FileName: "report.pdf", ContentType: "application/pdf", Path: "files/report-2.pdf"},
+		{Name: "meta", Value: "{}", ContentType: "application/json"},
+		{Name: "avatar", Value: "x", FileName: "../me.png"},
+	}
+	expected := Pairs{
+		{"title", "hello"},
+		{"doc", "@file(files/report-2.pdf) @contentType(application/pdf)"},
+		{"meta", "{} @contentType(application/json)"},
+		{"avatar", "@file(files/me.png)"},
+	}
+	if got := FormPartsPairs(parts); !reflect.DeepEqual(got, expected) {
+		t.Errorf("FormPartsPairs() = %v, want %v", got, expected)
+	}
+}
+
+func TestParseFormPartValue(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected FormPart
+	}{
+		{"hello", FormPart{Name: "f", Value: "hello"}},
+		{"@file(files/report-2.pdf) @contentType(application/pdf)", FormPart{Name: "f", FileName: "report-2.pdf", ContentType: "application/pdf", Path: "files/report-2.pdf"}},
+		{"{} @contentType(application/json)", FormPart{Name: "f", Value: "{}", ContentType: "application/json"}},
+		{"@file(files/me.png)", FormPart{Name: "f", FileName: "me.png", Path: "files/me.png"}},
+	}
+	for _, tt := range tests {
+		if got := ParseFormPartValue("f", tt.value); got != tt.expected {
+			t.Errorf("ParseFormPartValue(%q) = %+v, want %+v", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestSaveFormFiles(t *testing.T) {
+	basedir := t.TempDir()
+	os.MkdirAll(filepath.Join(basedir, FilesDir), 0o755)
+	os.WriteFile(filepath.Join(basedir, FilesDir, "same.txt"), []byte("same"), 0o644)
+
+	parts, _ := ParseMultipartParts(testMultipartBody)
+	parts = append(parts, FormPart{Name: "f", Value: "same", FileName: "same.txt"})
+	if err := saveFormFiles(basedir, parts); err != nil {
+		t.Fatalf("saveFormFiles() error = %v", err)
+	}
+
+	var paths []string
+	for _, p := range parts {
+		paths = append(paths, p.Path)
+	}
+	expected := []string{"", "files/report.pdf", "", "files/report-2.pdf", "files/same.txt"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("saveFormFiles() paths = %v, want %v", paths, expected)
+	}
+
+	for path, content := range map[string]string{
+		"files/report.pdf":   "%PDF-1.4",
+		"files/report-2.pdf": "%PDF-1.7",
+		"files/same.txt":     "same",
+	} {
+		data, err := os.ReadFile(filepath.Join(basedir, path))
+		if err != nil || string(data) != content {
+			t.Errorf("file %s = %q, %v, want %q", path, data, err, content)
+		}
+	}
+
+	// the same upload again reuses saved files
+	again, _ := ParseMultipartParts(testMultipartBody)
+	if err := saveFormFiles(basedir, again); err != nil {
+		t.Fatalf("saveFormFiles() error = %v", err)
+	}
+	if again[1].Path != "files/report.pdf" || again[3].Path != "files/report-2.pdf" {
+		t.Errorf("saveFormFiles() should reuse files, got %q, %q", again[1].Path, again[3].Path)
+	}
+}
+
+func TestSafeFileName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"photo.png", "photo.png"},
+		{"../../etc/passwd", "passwd"},
+		{`C:\Users\me\my file.txt`, "my_file.txt"},
+		{".hidden", "hidden"},
+		{"", "file"},
+		{"..", "file"},
+	}
+	for _, tt := range tests {
+		if got := safeFileName(tt.name); got != tt.expected {
+			t.Errorf("safeFileName(%q) = %q, want %q", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestCreateRequestFileMultipartFiles(t *testing.T) {
+	basedir := t.TempDir()
+	rd := RequestData{
+		Basedir:  basedir,
+		Method:   "POST",
+		Path:     "/upload",
+		BodyType: "multipartForm",
+		Body:     testMultipartBody,
+		Env: &BrunoEnv{
+			Vars:        map[string]string{"greeting": "hello"},
+			ReverseVars: map[string]string{"hello": "greeting"},
+		},
+	}
+
+	fp, err := createRequestFile(rd)
+	if err != nil {
+		t.Fatalf("createRequestFile() error = %v", err)
+	}
+	data, _ := os.ReadFile(fp)
+	want := "body:multipart-form {\n" +
+		"  title: {{greeting}}\n" +
+		"  doc: @file(files/report.pdf) @contentType(application/pdf)\n" +
+		"  meta: {\"a\":1} @contentType(application/json)\n" +
+		"  doc: @file(files/report-2.pdf) @contentType(application/pdf)\n" +
+		"}\n"
+	if !strings.Contains(string(data), want) {
+		t.Errorf("request file should contain\n%s\ngot:\n%s", want, data)
+	}
+	if _, err := os.Stat(filepath.Join(basedir, "files", "report-2.pdf")); err != nil {
+		t.Errorf("file part should be saved: %v", err)
+	}
+}
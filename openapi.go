@@ -0,0 +1,903 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPISpec OpenAPI 3 or Swagger 2 document, only fields used for conversion
+type OpenAPISpec struct {
+	OpenAPI    string                   `yaml:"openapi"`
+	Swagger    string                   `yaml:"swagger"`
+	Info       OpenAPIInfo              `yaml:"info"`
+	Servers    []OpenAPIServer          `yaml:"servers"`
+	Paths      yamlMap[OpenAPIPathItem] `yaml:"paths"`
+	Components OpenAPIComponents        `yaml:"components"`
+	Security   []yamlMap[[]string]      `yaml:"security"`
+
+	// Swagger 2
+	Host                string                         `yaml:"host"`
+	BasePath            string                         `yaml:"basePath"`
+	Schemes             []string                       `yaml:"schemes"`
+	Consumes            []string                       `yaml:"consumes"`
+	Definitions         map[string]*OpenAPISchema      `yaml:"definitions"`
+	Parameters          map[string]OpenAPIParameter    `yaml:"parameters"`
+	SecurityDefinitions yamlMap[OpenAPISecurityScheme] `yaml:"securityDefinitions"`
+}
+
+type OpenAPIInfo struct {
+	Title string `yaml:"title"`
+}
+
+type OpenAPIServer struct {
+	URL         string                              `yaml:"url"`
+	Description string                              `yaml:"description"`
+	Variables   map[string]struct{ Default string } `yaml:"variables"`
+}
+
+type OpenAPIComponents struct {
+	Schemas         map[string]*OpenAPISchema      `yaml:"schemas"`
+	Parameters      map[string]OpenAPIParameter    `yaml:"parameters"`
+	RequestBodies   map[string]OpenAPIRequestBody  `yaml:"requestBodies"`
+	SecuritySchemes yamlMap[OpenAPISecurityScheme] `yaml:"securitySchemes"`
+}
+
+type OpenAPIPathItem struct {
+	Parameters []OpenAPIParameter `yaml:"parameters"`
+	Get        *OpenAPIOperation  `yaml:"get"`
+	Put        *OpenAPIOperation  `yaml:"put"`
+	Post       *OpenAPIOperation  `yaml:"post"`
+	Delete     *OpenAPIOperation  `yaml:"delete"`
+	Options    *OpenAPIOperation  `yaml:"options"`
+	Head       *OpenAPIOperation  `yaml:"head"`
+	Patch      *OpenAPIOperation  `yaml:"patch"`
+	Trace      *OpenAPIOperation  `yaml:"trace"`
+}
+
+type OpenAPIOperation struct {
+	OperationID string               `yaml:"operationId"`
+	Summary     string               `yaml:"summary"`
+	Description string               `yaml:"description"`
+	Tags        []string             `yaml:"tags"`
+	Parameters  []OpenAPIParameter   `yaml:"parameters"`
+	RequestBody *OpenAPIRequestBody  `yaml:"requestBody"`
+	Security    *[]yamlMap[[]string] `yaml:"security"`
+	Consumes    []string             `yaml:"consumes"`
+}
+
+type OpenAPIParameter struct {
+	Ref      string         `yaml:"$ref"`
+	Name     string         `yaml:"name"`
+	In       string         `yaml:"in"`
+	Required bool           `yaml:"required"`
+	Schema   *OpenAPISchema `yaml:"schema"`
+	Example  any            `yaml:"example"`
+
+	// Swagger 2 parameters without schema
+	Type    string `yaml:"type"`
+	Default any    `yaml:"default"`
+	Enum    []any  `yaml:"enum"`
+}
+
+type OpenAPIRequestBody struct {
+	Ref     string                    `yaml:"$ref"`
+	Content yamlMap[OpenAPIMediaType] `yaml:"content"`
+}
+
+type OpenAPIMediaType struct {
+	Schema   *OpenAPISchema               `yaml:"schema"`
+	Example  any                          `yaml:"example"`
+	Examples yamlMap[struct{ Value any }] `yaml:"examples"`
+}
+
+type OpenAPISchema struct {
+	Ref        string                  `yaml:"$ref"`
+	Type       any                     `yaml:"type"`
+	Format     string                  `yaml:"format"`
+	Properties yamlMap[*OpenAPISchema] `yaml:"properties"`
+	Items      *OpenAPISchema          `yaml:"items"`
+	Example    any                     `yaml:"example"`
+	Default    any                     `yaml:"default"`
+	Enum       []any                   `yaml:"enum"`
+	AllOf      []*OpenAPISchema        `yaml:"allOf"`
+	OneOf      []*OpenAPISchema        `yaml:"oneOf"`
+	AnyOf      []*OpenAPISchema        `yaml:"anyOf"`
+}
+
+type OpenAPISecurityScheme struct {
+	Type   string `yaml:"type"`
+	Scheme string `yaml:"scheme"`
+	In     string `yaml:"in"`
+	Name   string `yaml:"name"`
+}
+
+// yamlMap mapping which keeps the document order
+type yamlMap[T any] []yamlMapItem[T]
+
+type yamlMapItem[T any] struct {
+	Key   string
+	Value T
+}
+
+func (m *yamlMap[T]) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Tag == "!!null" {
+		return nil
+	}
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: mapping expected", node.Line)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		var value T
+		if err := node.Content[i+1].Decode(&value); err != nil {
+			return err
+		}
+		*m = append(*m, yamlMapItem[T]{Key: node.Content[i].Value, Value: value})
+	}
+	return nil
+}
+
+// Get returns the value of the key
+func (m yamlMap[T]) Get(key string) (T, bool) {
+	for _, item := range m {
+		if item.Key == key {
+			return item.Value, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// openAPIMaxDepth max depth of $ref and allOf resolution,
+// protects from recursive schemas
+const openAPIMaxDepth = 8
+
+var openAPIPathParamRe = regexp.MustCompile(`\{([^{}/]+)\}`)
+
+// DoOpenAPI creates the collection from OpenAPI 3 / Swagger 2 spec.
+// Requests are grouped into folders by the first tag or the first path
+// segment, one .bru file per operation. The collection name is taken
+// from the server host or the spec title if empty. Created files are
+// printed to stdout, skipped operations and summary to stderr.
+func DoOpenAPI(input, collection, basedir string, opts RequestOptions) error {
+	if input == "" {
+		return fmt.Errorf("-i input file is required")
+	}
+
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return fmt.Errorf("read %q file error %w", input, err)
+	}
+
+	spec, err := ParseOpenAPI(data)
+	if err != nil {
+		return err
+	}
+
+	servers := spec.ServerURLs()
+	if collection == "" {
+		collection = openAPICollectionName(spec, servers)
+	}
+	dir, err := openAPICollection(spec, servers, collection, basedir)
+	if err != nil {
+		return err
+	}
+
+	created, skipped, total := 0, 0, 0
+	for _, path := range spec.Paths {
+		for _, op := range path.Value.operations() {
+			total++
+			fp, err := writeOpenAPIOperation(spec, dir, path.Key, op.method, path.Value, op.op, servers, opts)
+			if err != nil {
+				skipped++
+				fmt.Fprintf(os.Stderr, "[W] skip operation %s %s: %s\n", op.method, path.Key, err)
+				continue
+			}
+			created++
+			fmt.Println(fp)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "[I] openapi operations: %d, created: %d, skipped: %d\n", total, created, skipped)
+	return nil
+}
+
+// ParseOpenAPI parses yaml or json spec
+func ParseOpenAPI(data []byte) (*OpenAPISpec, error) {
+	var spec OpenAPISpec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("parse openapi spec error %w", err)
+	}
+	if spec.OpenAPI == "" && spec.Swagger == "" {
+		return nil, fmt.Errorf("parse openapi spec error: openapi or swagger version is missing")
+	}
+	return &spec, nil
+}
+
+// ServerURLs returns server urls with variables replaced by defaults.
+// Swagger 2 url is built from schemes, host and basePath. Relative
+// urls like /api/v3 are kept with an empty host, they set the base path.
+func (s *OpenAPISpec) ServerURLs() []*url.URL {
+	var raw []string
+	if s.Swagger != "" {
+		if s.Host != "" {
+			scheme := "https"
+			if len(s.Schemes) > 0 {
+				scheme = s.Schemes[0]
+			}
+			raw = append(raw, scheme+"://"+s.Host+s.BasePath)
+		} else if s.BasePath != "" {
+			raw = append(raw, s.BasePath)
+		}
+	}
+	for _, server := range s.Servers {
+		u := server.URL
+		for name, v := range server.Variables {
+			u = strings.ReplaceAll(u, "{"+name+"}", v.Default)
+		}
+		raw = append(raw, u)
+	}
+
+	var urls []*url.URL
+	for _, r := range raw {
+		u, err := url.Parse(r)
+		if err != nil || (u.Host == "" && !strings.HasPrefix(u.Path, "/")) {
+			fmt.Fprintf(os.Stderr, "[W] skip server %q: absolute url or path expected\n", r)
+			continue
+		}
+		urls = append(urls, u)
+	}
+	return urls
+}
+
+func openAPICollectionName(spec *OpenAPISpec, servers []*url.URL) string {
+	for _, server := range servers {
+		if server.Host != "" {
+			return strings.ToLower(server.Hostname())
+		}
+	}
+	if name := requestFileName(spec.Info.Title); name != "" {
+		return name
+	}
+	return "openapi"
+}
+
+// missingAuthVars returns secret variables of all security requirements
+// which are not defined in the env file yet, existing secrets are kept
+func (s *OpenAPISpec) missingAuthVars(envPath string) Pairs {
+	env, err := EnvFromFile(envPath)
+	if err != nil {
+		env = &BrunoEnv{}
+	}
+
+	requirements := append([]yamlMap[[]string](nil), s.Security...)
+	for _, path := range s.Paths {
+		for _, op := range path.Value.operations() {
+			if op.op.Security != nil {
+				requirements = append(requirements, *op.op.Security...)
+			}
+		}
+	}
+
+	var vars Pairs
+	for _, req := range requirements {
+		auth := s.auth([]yamlMap[[]string]{req})
+		if auth == nil {
+			continue
+		}
+		for _, v := range auth.Vars {
+			_, inEnv := env.Vars[v.Key]
+			_, added := vars.Get(v.Key)
+			if !inEnv && !added {
+				vars.Add(v.Key, v.Value)
+			}
+		}
+	}
+	return vars
+}
+
+// openAPICollection creates the collection if missing, writes server
+// and auth variables to the env files and global security to collection.bru.
+// Without absolute servers host of a created collection is left empty.
+func openAPICollection(spec *OpenAPISpec, servers []*url.URL, collection, basedir string) (string, error) {
+	_, missing := os.Stat(filepath.Join(basedir, collection, "bruno.json"))
+	dir, err := ensureCollection(collection, basedir)
+	if err != nil {
+		return "", err
+	}
+
+	auth := spec.auth(spec.Security)
+	base := filepath.Join(dir, "environments", "base.bru")
+	hosts := 0
+	for i, server := range servers {
+		if server.Host == "" {
+			continue
+		}
+		envPath := base
+		hosts++
+		if hosts > 1 {
+			name := requestFileName(servers[i].Host)
+			if d := spec.Servers; len(d) == len(servers) && d[i].Description != "" {
+				name = requestFileName(d[i].Description)
+			}
+			envPath = filepath.Join(dir, "environments", name+".bru")
+			if _, err := os.Stat(envPath); err != nil {
+				if err := os.WriteFile(envPath, []byte(EnvGenerate(nil)), 0o644); err != nil {
+					return "", fmt.Errorf("write %q file error %w", envPath, err)
+				}
+			}
+		}
+		var vars Pairs
+		vars.Add("proto", server.Scheme)
+		vars.Add("host", server.Host)
+		if err := EnvSetVars(envPath, append(vars, spec.missingAuthVars(envPath)...)); err != nil {
+			return "", err
+		}
+	}
+	if hosts == 0 {
+		var vars Pairs
+		if missing != nil {
+			vars.Add("host", "")
+		}
+		if err := EnvSetVars(base, append(vars, spec.missingAuthVars(base)...)); err != nil {
+			return "", err
+		}
+	}
+
+	if auth != nil {
+		doc, err := BruFromFile(filepath.Join(dir, "collection.bru"))
+		if err != nil {
+			return "", err
+		}
+		SetAuthBlocks(doc, auth)
+		if err := doc.WriteFile(filepath.Join(dir, "collection.bru")); err != nil {
+			return "", err
+		}
+	}
+
+	return dir, nil
+}
+
+type openAPIMethodOperation struct {
+	method string
+	op     *OpenAPIOperation
+}
+
+// operations returns operations of the path item in http methods order
+func (p OpenAPIPathItem) operations() []openAPIMethodOperation {
+	var ops []openAPIMethodOperation
+	for _, item := range []openAPIMethodOperation{
+		{"GET", p.Get}, {"POST", p.Post}, {"PUT", p.Put}, {"PATCH", p.Patch},
+		{"DELETE", p.Delete}, {"HEAD", p.Head}, {"OPTIONS", p.Options}, {"TRACE", p.Trace},
+	} {
+		if item.op != nil {
+			ops = append(ops, item)
+		}
+	}
+	return ops
+}
+
+func writeOpenAPIOperation(spec *OpenAPISpec, collDir, path, method string, item OpenAPIPathItem, op *OpenAPIOperation, servers []*url.URL, opts RequestOptions) (string, error) {
+	folder := openAPIFolder(path, op)
+	dir := collDir
+	if folder != "" {
+		dir = filepath.Join(collDir, folder)
+		if err := openAPIFolderFile(dir, folder); err != nil {
+			return "", err
+		}
+	}
+
+	name := requestFileName(op.OperationID)
+	if name == "" {
+		name = requestFileName(op.Summary)
+	}
+	if name == "" {
+		name = pathToName(strings.Trim(openAPIPathParamRe.ReplaceAllString(path, "{{$1}}"), "/"))
+		if name == "" {
+			name = method
+		} else {
+			name += "-" + method
+		}
+	}
+
+	basePath := ""
+	if len(servers) > 0 {
+		basePath = strings.TrimRight(servers[0].Path, "/")
+	}
+	return WriteBruRequest(dir, spec.bruRequest(name, method, basePath+path, item, op), opts)
+}
+
+// openAPIFolder returns the first tag or the first static path segment
+func openAPIFolder(path string, op *OpenAPIOperation) string {
+	if len(op.Tags) > 0 {
+		return requestFileName(op.Tags[0])
+	}
+	for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		if segment != "" && !strings.Contains(segment, "{") {
+			return requestFileName(segment)
+		}
+	}
+	return ""
+}
+
+// openAPIFolderFile creates the folder with folder.bru if missing.
+// Unlike DoFolder it doesn't add auth headers, spec auth is written
+// to the auth blocks.
+func openAPIFolderFile(dir, name string) error {
+	if _, err := os.Stat(dir); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("error creating directory: %v", err)
+	}
+	var meta Pairs
+	meta.Add("name", name)
+	if err := os.WriteFile(filepath.Join(dir, "folder.bru"), []byte(MetaGenerate(meta)), 0o644); err != nil {
+		return fmt.Errorf("error creating folder.bru: %v", err)
+	}
+	return nil
+}
+
+// bruRequest returns the request of the operation
+func (s *OpenAPISpec) bruRequest(name, method, path string, item OpenAPIPathItem, op *OpenAPIOperation) BruRequest {
+	r := BruRequest{Name: name, Method: method}
+	var cookies []string
+	var bodyParam *OpenAPIParameter
+
+	for _, p := range s.operationParams(item, op) {
+		value := s.paramExample(p)
+		key := p.Name
+		if !p.Required {
+			key = "~" + key
+		}
+		switch p.In {
+		case "path":
+			r.PathParams.Add(p.Name, value)
+		case "query":
+			r.Query.Add(key, value)
+		case "header":
+			r.Headers.Add(key, value)
+		case "cookie":
+			cookies = append(cookies, p.Name+"="+value)
+		case "formData":
+			r.Form.Add(key, value)
+		case "body":
+			bodyParam = &p
+		}
+	}
+	if len(cookies) > 0 {
+		r.Headers.Add("Cookie", strings.Join(cookies, "; "))
+	}
+
+	r.BodyType = "none"
+	switch {
+	case op.RequestBody != nil:
+		var err error
+		r.BodyType, r.Body, r.Form, err = s.requestBody(op.RequestBody)
+		if err != nil {
+			r.Docs = append(r.Docs, "- [ ] "+err.Error())
+		}
+	case bodyParam != nil:
+		r.BodyType, r.Body = "json", s.schemaExampleText(bodyParam.Schema)
+		if ct := s.consumes(op); ct != "" {
+			if bt, err := BodyTypeFromContentType(ct); err == nil && bt != "multipartForm" && bt != "formUrlEncoded" && bt != "file" {
+				r.BodyType = bt
+			}
+		}
+	case len(r.Form) > 0:
+		r.BodyType = "formUrlEncoded"
+		if strings.HasPrefix(s.consumes(op), "multipart/") {
+			r.BodyType = "multipartForm"
+		}
+	}
+
+	r.Auth = s.operationAuth(op)
+
+	r.URL = "{{proto}}://{{host}}" + openAPIPathParamRe.ReplaceAllString(path, ":$1")
+	if enabled := enabledPairs(r.Query); len(enabled) > 0 {
+		r.URL += "?" + QueryString(enabled)
+	}
+
+	if op.Summary != "" {
+		r.Docs = append(r.Docs, op.Summary)
+	}
+	if op.Description != "" {
+		if len(r.Docs) > 0 {
+			r.Docs = append(r.Docs, "")
+		}
+		r.Docs = append(r.Docs, strings.Split(strings.TrimRight(op.Description, "\n"), "\n")...)
+	}
+
+	return r
+}
+
+// operationParams returns path item and operation parameters,
+// operation parameters override path item ones with the same name and location
+func (s *OpenAPISpec) operationParams(item OpenAPIPathItem, op *OpenAPIOperation) []OpenAPIParameter {
+	var params []OpenAPIParameter
+	for _, p := range append(append([]OpenAPIParameter(nil), item.Parameters...), op.Parameters...) {
+		p = s.resolveParam(p)
+		replaced := false
+		for i := range params {
+			if params[i].Name == p.Name && params[i].In == p.In {
+				params[i] = p
+				replaced = true
+			}
+		}
+		if !replaced {
+			params = append(params, p)
+		}
+	}
+	return params
+}
+
+func (s *OpenAPISpec) resolveParam(p OpenAPIParameter) OpenAPIParameter {
+	for range openAPIMaxDepth {
+		if p.Ref == "" {
+			return p
+		}
+		name := refName(p.Ref)
+		resolved, ok := s.Components.Parameters[name]
+		if !ok {
+			resolved, ok = s.Parameters[name]
+		}
+		if !ok {
+			return OpenAPIParameter{}
+		}
+		p = resolved
+	}
+	return p
+}
+
+// paramExample returns the parameter example as string
+func (s *OpenAPISpec) paramExample(p OpenAPIParameter) string {
+	switch {
+	case p.Example != nil:
+		return exampleString(p.Example)
+	case p.Schema != nil:
+		return exampleString(s.schemaExample(p.Schema, nil))
+	case p.Default != nil:
+		return exampleString(p.Default)
+	case len(p.Enum) > 0:
+		return exampleString(p.Enum[0])
+	}
+	return ""
+}
+
+// requestBody returns body type and example of the preferred content type,
+// json is preferred over other types
+func (s *OpenAPISpec) requestBody(rb *OpenAPIRequestBody) (string, string, Pairs, error) {
+	for range openAPIMaxDepth {
+		if rb.Ref == "" {
+			break
+		}
+		resolved, ok := s.Components.RequestBodies[refName(rb.Ref)]
+		if !ok {
+			return "none", "", nil, fmt.Errorf("unresolved request body %s", rb.Ref)
+		}
+		rb = &resolved
+	}
+	if len(rb.Content) == 0 {
+		return "none", "", nil, nil
+	}
+
+	media := rb.Content[0]
+	for _, item := range rb.Content {
+		if bt, _ := BodyTypeFromContentType(item.Key); bt == "json" {
+			media = item
+			break
+		}
+	}
+
+	bodyType, err := BodyTypeFromContentType(media.Key)
+	if err != nil || bodyType == "file" {
+		return "none", "", nil, fmt.Errorf("body content type %s isn't supported", media.Key)
+	}
+
+	var example any
+	switch {
+	case media.Value.Example != nil:
+		example = media.Value.Example
+	case len(media.Value.Examples) > 0:
+		example = media.Value.Examples[0].Value.Value
+	}
+
+	switch bodyType {
+	case "formUrlEncoded", "multipartForm":
+		var form Pairs
+		schema := s.resolveSchema(media.Value.Schema)
+		if schema != nil {
+			for _, prop := range s.schemaProperties(schema, 0) {
+				form.Add(prop.Key, exampleString(s.schemaExample(prop.Value, nil)))
+			}
+		}
+		return bodyType, "", form, nil
+	case "json":
+		if example != nil {
+			return bodyType, indentJSON(exampleJSON(example)), nil, nil
+		}
+		return bodyType, s.schemaExampleText(media.Value.Schema), nil, nil
+	}
+
+	if str, ok := example.(string); ok {
+		return bodyType, str, nil, nil
+	}
+	return bodyType, "", nil, nil
+}
+
+func (s *OpenAPISpec) consumes(op *OpenAPIOperation) string {
+	if len(op.Consumes) > 0 {
+		return op.Consumes[0]
+	}
+	if len(s.Consumes) > 0 {
+		return s.Consumes[0]
+	}
+	return ""
+}
+
+// schemaExampleText returns indented json example of the schema
+func (s *OpenAPISpec) schemaExampleText(schema *OpenAPISchema) string {
+	return indentJSON(s.schemaExample(schema, nil))
+}
+
+// schemaExample builds json example of the schema, object properties
+// are written in spec order. refs are $refs being expanded, recursive
+// references are written as null.
+func (s *OpenAPISpec) schemaExample(schema *OpenAPISchema, refs []string) json.RawMessage {
+	if schema != nil && schema.Ref != "" {
+		if slices.Contains(refs, schema.Ref) || len(refs) > openAPIMaxDepth {
+			return json.RawMessage("null")
+		}
+		refs = append(refs[:len(refs):len(refs)], schema.Ref)
+	}
+	schema = s.resolveSchema(schema)
+	if schema == nil {
+		return json.RawMessage("null")
+	}
+
+	switch {
+	case schema.Example != nil:
+		return exampleJSON(schema.Example)
+	case schema.Default != nil:
+		return exampleJSON(schema.Default)
+	case len(schema.Enum) > 0:
+		return exampleJSON(schema.Enum[0])
+	case len(schema.OneOf) > 0:
+		return s.schemaExample(schema.OneOf[0], refs)
+	case len(schema.AnyOf) > 0:
+		return s.schemaExample(schema.AnyOf[0], refs)
+	}
+
+	switch schemaType(schema) {
+	case "object":
+		var buf bytes.Buffer
+		buf.WriteString("{")
+		for i, prop := range s.schemaProperties(schema, 0) {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			key, _ := json.Marshal(prop.Key)
+			buf.Write(key)
+			buf.WriteString(":")
+			buf.Write(s.schemaExample(prop.Value, refs))
+		}
+		buf.WriteString("}")
+		return buf.Bytes()
+	case "array":
+		return json.RawMessage("[" + string(s.schemaExample(schema.Items, refs)) + "]")
+	case "integer", "number":
+		return json.RawMessage("0")
+	case "boolean":
+		return json.RawMessage("false")
+	case "string":
+		return exampleJSON(stringFormatExample(schema.Format))
+	}
+	return json.RawMessage("null")
+}
+
+// schemaProperties returns properties of the object schema,
+// allOf schemas are merged
+func (s *OpenAPISpec) schemaProperties(schema *OpenAPISchema, depth int) yamlMap[*OpenAPISchema] {
+	props := append(yamlMap[*OpenAPISchema](nil), schema.Properties...)
+	if depth > openAPIMaxDepth {
+		return props
+	}
+	for _, sub := range schema.AllOf {
+		if sub = s.resolveSchema(sub); sub != nil {
+			props = append(props, s.schemaProperties(sub, depth+1)...)
+		}
+	}
+	return props
+}
+
+func (s *OpenAPISpec) resolveSchema(schema *OpenAPISchema) *OpenAPISchema {
+	for range openAPIMaxDepth {
+		if schema == nil || schema.Ref == "" {
+			return schema
+		}
+		name := refName(schema.Ref)
+		resolved, ok := s.Components.Schemas[name]
+		if !ok {
+			resolved = s.Definitions[name]
+		}
+		schema = resolved
+	}
+	return nil
+}
+
+// schemaType returns the schema type, the first non null type
+// for OpenAPI 3.1 type lists. Schemas with properties are objects.
+func schemaType(schema *OpenAPISchema) string {
+	switch t := schema.Type.(type) {
+	case string:
+		return t
+	case []any:
+		for _, item := range t {
+			if str, ok := item.(string); ok && str != "null" {
+				return str
+			}
+		}
+	}
+	if len(schema.Properties) > 0 || len(schema.AllOf) > 0 {
+		return "object"
+	}
+	return ""
+}
+
+func stringFormatExample(format string) string {
+	switch format {
+	case "date-time":
+		return "2024-01-01T00:00:00Z"
+	case "date":
+		return "2024-01-01"
+	case "uuid":
+		return "00000000-0000-0000-0000-000000000000"
+	case "email":
+		return "user@example.com"
+	case "uri", "url":
+		return "https://example.com"
+	case "binary", "byte":
+		return ""
+	}
+	return "string"
+}
+
+// operationAuth returns auth of the operation, inherit if the operation
+// doesn't override global security
+func (s *OpenAPISpec) operationAuth(op *OpenAPIOperation) *RequestAuth {
+	global := s.auth(s.Security)
+	if op.Security == nil {
+		if global != nil {
+			return &RequestAuth{Mode: "inherit"}
+		}
+		return &RequestAuth{Mode: "none"}
+	}
+
+	auth := s.auth(*op.Security)
+	switch {
+	case auth == nil:
+		return &RequestAuth{Mode: "none"}
+	case global != nil && global.Mode == auth.Mode && authValuesEqual(global.Values, auth.Values):
+		return &RequestAuth{Mode: "inherit"}
+	}
+	return auth
+}
+
+// auth maps the first scheme of the first security requirement
+// to bruno auth, secrets are {{var}} references listed in Vars
+func (s *OpenAPISpec) auth(requirements []yamlMap[[]string]) *RequestAuth {
+	if len(requirements) == 0 || len(requirements[0]) == 0 {
+		return nil
+	}
+	name := requirements[0][0].Key
+	scheme, ok := s.Components.SecuritySchemes.Get(name)
+	if !ok {
+		scheme, ok = s.SecurityDefinitions.Get(name)
+	}
+	if !ok {
+		return nil
+	}
+
+	auth := &RequestAuth{}
+	switch {
+	case scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "basic"), scheme.Type == "basic":
+		auth.Mode = "basic"
+		auth.Values = Pairs{{"username", "{{username}}"}, {"password", "{{password}}"}}
+		auth.Vars = Pairs{{"username", ""}, {"password", ""}}
+	case scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "digest"):
+		auth.Mode = "digest"
+		auth.Values = Pairs{{"username", "{{username}}"}, {"password", "{{password}}"}}
+		auth.Vars = Pairs{{"username", ""}, {"password", ""}}
+	case scheme.Type == "apiKey":
+		placement := "header"
+		if scheme.In == "query" {
+			placement = "queryparams"
+		}
+		auth.Mode = "apikey"
+		auth.Values = Pairs{{"key", scheme.Name}, {"value", "{{api_key}}"}, {"placement", placement}}
+		auth.Vars = Pairs{{"api_key", ""}}
+	default:
+		// http bearer, oauth2 and openIdConnect access tokens
+		auth.Mode = "bearer"
+		auth.Values = Pairs{{"token", "{{token}}"}}
+		auth.Vars = Pairs{{"token", ""}}
+	}
+	return auth
+}
+
+// refName returns the last segment of $ref, e.g. User for #/components/schemas/User
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// exampleJSON marshals example value decoded from yaml
+func exampleJSON(v any) json.RawMessage {
+	data, err := json.Marshal(normalizeYAML(v))
+	if err != nil {
+		return json.RawMessage("null")
+	}
+	return data
+}
+
+// normalizeYAML converts yaml maps with non string keys
+// to json compatible maps
+func normalizeYAML(v any) any {
+	switch x := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(x))
+		for k, item := range x {
+			m[k] = normalizeYAML(item)
+		}
+		return m
+	case map[any]any:
+		m := make(map[string]any, len(x))
+		for k, item := range x {
+			m[fmt.Sprint(k)] = normalizeYAML(item)
+		}
+		return m
+	case []any:
+		list := make([]any, len(x))
+		for i, item := range x {
+			list[i] = normalizeYAML(item)
+		}
+		return list
+	}
+	return v
+}
+
+// exampleString returns string examples as is, other values as json
+func exampleString(v any) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case json.RawMessage:
+		var str string
+		if err := json.Unmarshal(x, &str); err == nil {
+			return str
+		}
+		if string(x) == "null" {
+			return ""
+		}
+		return string(x)
+	}
+	return string(exampleJSON(v))
+}
+
+func indentJSON(data json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return string(data)
+	}
+	return buf.String()
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testOpenAPISpec = `openapi: 3.0.3
+info:
+  title: Pet Store
+servers:
+  - url: https://api.example.com:8443/v1
+  - url: http://localhost:8080/v1
+    description: Local
+security:
+  - bearerAuth: []
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      tags: [pets]
+      summary: List pets
+      parameters:
+        - name: limit
+          in: query
+          schema: {type: integer, example: 20}
+        - name: status
+          in: query
+          required: true
+          schema: {type: string, enum: [available, sold]}
+    post:
+      operationId: createPet
+      tags: [pets]
+      requestBody:
+        $ref: '#/components/requestBodies/Pet'
+  /pets/{petId}:
+    parameters:
+      - $ref: '#/components/parameters/PetId'
+    get:
+      tags: [pets]
+      security: []
+      description: Returns a pet.
+    delete:
+      tags: [pets]
+      security:
+        - apiKey: []
+  /store/orders:
+    post:
+      requestBody:
+        content:
+          application/x-www-form-urlencoded:
+            schema:
+              type: object
+              properties:
+                petId: {type: integer}
+                note: {type: string}
+components:
+  parameters:
+    PetId:
+      name: petId
+      in: path
+      required: true
+      schema: {type: string, format: uuid}
+  requestBodies:
+    Pet:
+      content:
+        application/json:
+          schema:
+            $ref: '#/components/schemas/Pet'
+  schemas:
+    Pet:
+      type: object
+      properties:
+        name: {type: string, example: Rex}
+        tags:
+          type: array
+          items: {type: string}
+        owner:
+          $ref: '#/components/schemas/Owner'
+        parent:
+          $ref: '#/components/schemas/Pet'
+    Owner:
+      allOf:
+        - type: object
+          properties:
+            id: {type: integer}
+        - type: object
+          properties:
+            email: {type: string, format: email}
+  securitySchemes:
+    bearerAuth: {type: http, scheme: bearer}
+    apiKey: {type: apiKey, in: header, name: X-API-Key}
+`
+
+func TestParseOpenAPI(t *testing.T) {
+	spec, err := ParseOpenAPI([]byte(testOpenAPISpec))
+	if err != nil {
+		t.Fatalf("ParseOpenAPI() error = %v", err)
+	}
+
+	var paths []string
+	for _, p := range spec.Paths {
+		paths = append(paths, p.Key)
+	}
+	if got := strings.Join(paths, ","); got != "/pets,/pets/{petId},/store/orders" {
+		t.Errorf("paths order = %q", got)
+	}
+
+	urls := spec.ServerURLs()
+	if len(urls) != 2 || urls[0].Host != "api.example.com:8443" || urls[1].Scheme != "http" {
+		t.Errorf("ServerURLs() = %v", urls)
+	}
+
+	if _, err := ParseOpenAPI([]byte(`{"info": {"title": "x"}}`)); err == nil {
+		t.Error("ParseOpenAPI() expected error without version")
+	}
+}
+
+func TestOpenAPISwaggerServer(t *testing.T) {
+	spec, err := ParseOpenAPI([]byte(`{"swagger": "2.0", "host": "api.example.com", "basePath": "/v2", "schemes": ["http"], "paths": {}}`))
+	if err != nil {
+		t.Fatalf("ParseOpenAPI() error = %v", err)
+	}
+	urls := spec.ServerURLs()
+	if len(urls) != 1 || urls[0].String() != "http://api.example.com/v2" {
+		t.Errorf("ServerURLs() = %v", urls)
+	}
+
+	spec, err = ParseOpenAPI([]byte(`{"swagger": "2.0", "basePath": "/v2", "paths": {}}`))
+	if err != nil {
+		t.Fatalf("ParseOpenAPI() error = %v", err)
+	}
+	urls = spec.ServerURLs()
+	if len(urls) != 1 || urls[0].Host != "" || urls[0].Path != "/v2" {
+		t.Errorf("ServerURLs() without host = %v", urls)
+	}
+}
+
+func TestDoOpenAPIRelativeServer(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "petstore.yaml")
+	spec := `openapi: 3.0.2
+info:
+  title: Petstore
+servers:
+  - url: /api/v3
+paths:
+  /pet/{petId}:
+    get:
+      operationId: getPetById
+      tags: [pet]
+      parameters:
+        - name: petId
+          in: path
+          required: true
+          schema:
+            type: integer
+`
+	os.WriteFile(input, []byte(spec), 0o644)
+
+	if err := DoOpenAPI(input, "", tmpDir, RequestOptions{}); err != nil {
+		t.Fatalf("DoOpenAPI() error = %v", err)
+	}
+
+	coll := filepath.Join(tmpDir, "Petstore")
+	data, err := os.ReadFile(filepath.Join(coll, "pet", "getPetById.bru"))
+	if err != nil {
+		t.Fatalf("request file was not created: %v", err)
+	}
+	if want := "url: {{proto}}://{{host}}/api/v3/pet/:petId"; !strings.Contains(string(data), want) {
+		t.Errorf("request file should contain %q\ngot:\n%s", want, data)
+	}
+	env, err := EnvFromFile(filepath.Join(coll, "environments", "base.bru"))
+	if err != nil {
+		t.Fatalf("EnvFromFile() error = %v", err)
+	}
+	if host, ok := env.Vars["host"]; !ok || host != "" {
+		t.Errorf("env host = %q, %v, want empty placeholder", host, ok)
+	}
+}
+
+func TestOpenAPISchemaExample(t *testing.T) {
+	spec, err := ParseOpenAPI([]byte(testOpenAPISpec))
+	if err != nil {
+		t.Fatalf("ParseOpenAPI() error = %v", err)
+	}
+
+	got := string(spec.schemaExample(&OpenAPISchema{Ref: "#/components/schemas/Pet"}, nil))
+	want := `{"name":"Rex","tags":["string"],"owner":{"id":0,"email":"user@example.com"},"parent":null}`
+	if got != want {
+		t.Errorf("schemaExample() = %s, want %s", got, want)
+	}
+
+	nullable := &OpenAPISchema{Type: []any{"null", "integer"}}
+	if got := string(spec.schemaExample(nullable, nil)); got != "0" {
+		t.Errorf("schemaExample() of type list = %s, want 0", got)
+	}
+}
+
+func TestDoOpenAPI(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "spec.yaml")
+	os.WriteFile(input, []byte(testOpenAPISpec), 0o644)
+
+	if err := DoOpenAPI(input, "", tmpDir, RequestOptions{}); err != nil {
+		t.Fatalf("DoOpenAPI() error = %v", err)
+	}
+
+	coll := filepath.Join(tmpDir, "api.example.com")
+	tests := []struct {
+		file string
+		want []string
+	}{
+		{
+			file: "pets/listPets.bru",
+			want: []string{
+				"url: {{proto}}://{{host}}/v1/pets?status=available",
+				"auth: inherit",
+				"~limit: 20",
+				"docs {\n  List pets\n}",
+			},
+		},
+		{
+			file: "pets/createPet.bru",
+			want: []string{"body: json", `    "name": "Rex",`, `    "parent": null`},
+		},
+		{
+			file: "pets/pets-PETID-GET.bru",
+			want: []string{
+				"url: {{proto}}://{{host}}/v1/pets/:petId",
+				"auth: none",
+				"params:path {\n  petId: 00000000-0000-0000-0000-000000000000\n}",
+			},
+		},
+		{
+			file: "pets/pets-PETID-DELETE.bru",
+			want: []string{"auth: apikey", "key: X-API-Key", "value: {{api_key}}", "placement: header"},
+		},
+		{
+			file: "store/store-orders-POST.bru",
+			want: []string{"body:form-urlencoded {\n  petId: 0\n  note: string\n}"},
+		},
+		{
+			file: "collection.bru",
+			want: []string{"auth {\n  mode: bearer\n}", "auth:bearer {\n  token: {{token}}\n}"},
+		},
+		{
+			file: "environments/Local.bru",
+			want: []string{"proto: http", "host: localhost:8080"},
+		},
+	}
+	for _, tt := range tests {
+		data, err := os.ReadFile(filepath.Join(coll, tt.file))
+		if err != nil {
+			t.Errorf("expected file %q was not created", tt.file)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("%s should contain %q\ngot:\n%s", tt.file, want, data)
+			}
+		}
+	}
+
+	env, err := EnvFromFile(filepath.Join(coll, "environments", "base.bru"))
+	if err != nil {
+		t.Fatalf("EnvFromFile() error = %v", err)
+	}
+	if env.Vars["host"] != "api.example.com:8443" || env.Vars["proto"] != "https" {
+		t.Errorf("env host = %q, proto = %q", env.Vars["host"], env.Vars["proto"])
+	}
+	if _, ok := env.Vars["token"]; !ok {
+		t.Error("env should contain token variable")
+	}
+
+	// secrets filled by user are kept on the next import
+	EnvSetVars(filepath.Join(coll, "environments", "base.bru"), Pairs{{"token", "secret"}})
+	if err := DoOpenAPI(input, "", tmpDir, RequestOptions{Suffix: true}); err != nil {
+		t.Fatalf("DoOpenAPI() second run error = %v", err)
+	}
+	env, _ = EnvFromFile(filepath.Join(coll, "environments", "base.bru"))
+	if env.Vars["token"] != "secret" {
+		t.Errorf("token = %q, want secret", env.Vars["token"])
+	}
+	if _, err := os.Stat(filepath.Join(coll, "pets", "listPets-2.bru")); err != nil {
+		t.Error("expected listPets-2.bru with -suffix")
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// QueryParam single query string param. NoValue is set for keys
+// without '=' like ?flag, they are written back without '='.
+type QueryParam struct {
+	Key     string
+	Value   string
+	NoValue bool
+}
+
+// QueryParams query string params in original order
+type QueryParams []QueryParam
+
+// ParseQuery splits the raw query string into params in original order.
+// Repeated keys and empty values are kept, encoding is left as is,
+// because requests are written with `encodeUrl: false`.
+func ParseQuery(rawQuery string) QueryParams {
+	var params QueryParams
+	for _, part := range strings.Split(rawQuery, "&") {
+		if part == "" {
+			continue
+		}
+		key, value, found := strings.Cut(part, "=")
+		params = append(params, QueryParam{Key: key, Value: value, NoValue: !found})
+	}
+	return params
+}
+
+// Pairs returns params as params:query entries
+func (q QueryParams) Pairs() Pairs {
+	pairs := make(Pairs, 0, len(q))
+	for _, param := range q {
+		pairs.Add(param.Key, param.Value)
+	}
+	return pairs
+}
+
+// String joins params back to the key=value&flag format
+func (q QueryParams) String() string {
+	parts := make([]string, 0, len(q))
+	for _, param := range q {
+		if param.NoValue && param.Value == "" {
+			parts = append(parts, param.Key)
+			continue
+		}
+		parts = append(parts, param.Key+"="+param.Value)
+	}
+	return strings.Join(parts, "&")
+}
+
+// QueryParamsEnv replaces param values with {{key}} placeholders using env values.
+func QueryParamsEnv(params QueryParams, env *BrunoEnv) QueryParams {
+	result := make(QueryParams, 0, len(params))
+	for _, param := range params {
+		param.Value = EnvToBody(param.Value, env)
+		result = append(result, param)
+	}
+	return result
+}
+
+// QueryString joins params back to the key=value&key2=value2 format
+func QueryString(params Pairs) string {
+	parts := make([]string, 0, len(params))
+	for _, param := range params {
+		parts = append(parts, param.Key+"="+param.Value)
+	}
+	return strings.Join(parts, "&")
+}
+
+// params:query block in format
+//
+//	params:query {
+//	  page: 1
+//	}
+func QueryParamsGenerate(params Pairs) string {
+	return NameBlockMap("params:query", params)
+}
+
+var pathVarRe = regexp.MustCompile(`^\{\{(\w+)\}\}$`)
+
+// PathParams converts {{var}} path segments to Bruno :var path params.
+// For example, api/users/{{user_id}} returns api/users/:user_id and [user_id].
+// Every name is returned once, even if used in several segments.
+func PathParams(path string) (string, []string) {
+	var names []string
+	seen := make(map[string]bool)
+
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		match := pathVarRe.FindStringSubmatch(part)
+		if match == nil {
+			continue
+		}
+		parts[i] = ":" + match[1]
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			names = append(names, match[1])
+		}
+	}
+
+	return strings.Join(parts, "/"), names
+}
+
+// params:path block in format
+//
+//	params:path {
+//	  user_id: {{user_id}}
+//	}
+func PathParamsGenerate(names []string) string {
+	var params Pairs
+	for _, name := range names {
+		params.Add(name, "{{"+name+"}}")
+	}
+	return NameBlockMap("params:path", params)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		rawQuery string
+		expected QueryParams
+	}{
+		{
+			name:     "empty query",
+			rawQuery: "",
+			expected: nil,
+		},
+		{
+			name:     "params keep order",
+			rawQuery: "z=1&a=2",
+			expected: QueryParams{{"z", "1", false}, {"a", "2", false}},
+		},
+		{
+			name:     "repeated keys are kept",
+			rawQuery: "id=1&id=2",
+			expected: QueryParams{{"id", "1", false}, {"id", "2", false}},
+		},
+		{
+			name:     "empty values and keys without value",
+			rawQuery: "a=&b",
+			expected: QueryParams{{"a", "", false}, {"b", "", true}},
+		},
+		{
+			name:     "encoding is left as is",
+			rawQuery: "q=hello%20world&f=a%3Db",
+			expected: QueryParams{{"q", "hello%20world", false}, {"f", "a%3Db", false}},
+		},
+		{
+			name:     "empty parts are skipped",
+			rawQuery: "a=1&&b=2&",
+			expected: QueryParams{{"a", "1", false}, {"b", "2", false}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseQuery(tt.rawQuery)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ParseQuery(%q) = %v, want %v", tt.rawQuery, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestQueryParamsEnv(t *testing.T) {
+	env := &BrunoEnv{
+		Vars:        map[string]string{"user_id": "123"},
+		ReverseVars: map[string]string{"123": "user_id"},
+	}
+	params := QueryParams{{"user", "123", false}, {"123", "x", false}, {"page", "1234", false}, {"flag", "", true}}
+
+	got := QueryParamsEnv(params, env)
+	expected := QueryParams{{"user", "{{user_id}}", false}, {"123", "x", false}, {"page", "1234", false}, {"flag", "", true}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("QueryParamsEnv() = %v, want %v", got, expected)
+	}
+}
+
+func TestQueryString(t *testing.T) {
+	params := Pairs{{"a", "1"}, {"a", "2"}, {"b", ""}}
+	if got := QueryString(params); got != "a=1&a=2&b=" {
+		t.Errorf("QueryString() = %q, want %q", got, "a=1&a=2&b=")
+	}
+	if got := QueryString(nil); got != "" {
+		t.Errorf("QueryString(nil) = %q, want empty string", got)
+	}
+}
+
+func TestQueryParamsString(t *testing.T) {
+	tests := []struct {
+		name     string
+		rawQuery string
+	}{
+		{"key without value", "flag"},
+		{"empty value", "a="},
+		{"mixed", "a=1&flag&b=&a=2"},
+		{"encoding is kept", "q=a%20b&f"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseQuery(tt.rawQuery).String(); got != tt.rawQuery {
+				t.Errorf("ParseQuery(%q).String() = %q", tt.rawQuery, got)
+			}
+		})
+	}
+}
+
+func TestQueryParamsGenerate(t *testing.T) {
+	got := QueryParamsGenerate(Pairs{{"id", "1"}, {"id", "2"}, {"q", ""}})
+	expected := "params:query {\n  id: 1\n  id: 2\n  q: \n}\n"
+	if got != expected {
+		t.Errorf("QueryParamsGenerate() = %q, want %q", got, expected)
+	}
+	if got := QueryParamsGenerate(nil); got != "" {
+		t.Errorf("QueryParamsGenerate(nil) = %q, want empty string", got)
+	}
+}
+
+func TestPathParams(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		wantPath  string
+		wantNames []string
+	}{
+		{"no variables", "api/users", "api/users", nil},
+		{"single variable", "api/users/{{user_id}}", "api/users/:user_id", []string{"user_id"}},
+		{"multiple variables", "{{org}}/users/{{user_id}}", ":org/users/:user_id", []string{"org", "user_id"}},
+		{"repeated variable", "a/{{id}}/b/{{id}}", "a/:id/b/:id", []string{"id"}},
+		{"partial segment is not a param", "api/v{{version}}/users", "api/v{{version}}/users", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotPath, gotNames := PathParams(tt.path)
+			if gotPath != tt.wantPath {
+				t.Errorf("PathParams(%q) path = %q, want %q", tt.path, gotPath, tt.wantPath)
+			}
+			if !reflect.DeepEqual(gotNames, tt.wantNames) {
+				t.Errorf("PathParams(%q) names = %v, want %v", tt.path, gotNames, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestPathParamsGenerate(t *testing.T) {
+	got := PathParamsGenerate([]string{"org", "user_id"})
+	for _, want := range []string{"params:path {", "  org: {{org}}", "  user_id: {{user_id}}"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("PathParamsGenerate() = %q, should contain %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,734 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// PostmanCollection Postman v2.1 collection, only fields used for conversion
+type PostmanCollection struct {
+	Info     PostmanInfo    `json:"info"`
+	Item     []PostmanItem  `json:"item"`
+	Auth     *PostmanAuth   `json:"auth"`
+	Event    []PostmanEvent `json:"event"`
+	Variable []PostmanKV    `json:"variable"`
+}
+
+type PostmanInfo struct {
+	Name        string             `json:"name"`
+	Schema      string             `json:"schema"`
+	Description PostmanDescription `json:"description"`
+}
+
+// PostmanItem request or folder, folders have no request
+type PostmanItem struct {
+	Name        string             `json:"name"`
+	Item        []PostmanItem      `json:"item"`
+	Request     *PostmanRequest    `json:"request"`
+	Response    []PostmanResponse  `json:"response"`
+	Auth        *PostmanAuth       `json:"auth"`
+	Event       []PostmanEvent     `json:"event"`
+	Description PostmanDescription `json:"description"`
+}
+
+type PostmanRequest struct {
+	Method      string             `json:"method"`
+	Header      []PostmanKV        `json:"header"`
+	URL         PostmanURL         `json:"url"`
+	Body        *PostmanBody       `json:"body"`
+	Auth        *PostmanAuth       `json:"auth"`
+	Description PostmanDescription `json:"description"`
+}
+
+// UnmarshalJSON accepts the request object or the url string
+func (r *PostmanRequest) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		*r = PostmanRequest{Method: "GET", URL: PostmanURL{Raw: raw}}
+		return nil
+	}
+	type request PostmanRequest
+	return json.Unmarshal(data, (*request)(r))
+}
+
+// PostmanKV header, query param, form field or variable
+type PostmanKV struct {
+	Key         string       `json:"key"`
+	Value       PostmanValue `json:"value"`
+	Disabled    bool         `json:"disabled"`
+	Type        string       `json:"type"`
+	Src         any          `json:"src"`
+	ContentType string       `json:"contentType"`
+}
+
+// PostmanValue scalar value, numbers and booleans are kept as text
+type PostmanValue string
+
+func (v *PostmanValue) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*v = PostmanValue(s)
+		return nil
+	}
+	if string(data) == "null" {
+		*v = ""
+		return nil
+	}
+	*v = PostmanValue(data)
+	return nil
+}
+
+// PostmanURL url object or string
+type PostmanURL struct {
+	Raw      string      `json:"raw"`
+	Protocol string      `json:"protocol"`
+	Host     any         `json:"host"`
+	Path     any         `json:"path"`
+	Query    []PostmanKV `json:"query"`
+	Variable []PostmanKV `json:"variable"`
+}
+
+func (u *PostmanURL) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		*u = PostmanURL{Raw: raw}
+		return nil
+	}
+	type postmanURL PostmanURL
+	return json.Unmarshal(data, (*postmanURL)(u))
+}
+
+// String returns the raw url or builds it from parts
+func (u PostmanURL) String() string {
+	if u.Raw != "" {
+		return u.Raw
+	}
+	var sb strings.Builder
+	if u.Protocol != "" {
+		sb.WriteString(u.Protocol + "://")
+	}
+	sb.WriteString(strings.Join(postmanStrings(u.Host), "."))
+	if path := postmanStrings(u.Path); len(path) > 0 {
+		sb.WriteString("/" + strings.Join(path, "/"))
+	}
+	var query Pairs
+	for _, q := range u.Query {
+		if !q.Disabled {
+			query.Add(q.Key, string(q.Value))
+		}
+	}
+	if len(query) > 0 {
+		sb.WriteString("?" + QueryString(query))
+	}
+	return sb.String()
+}
+
+type PostmanBody struct {
+	Mode       string      `json:"mode"`
+	Raw        string      `json:"raw"`
+	URLEncoded []PostmanKV `json:"urlencoded"`
+	FormData   []PostmanKV `json:"formdata"`
+	File       struct {
+		Src string `json:"src"`
+	} `json:"file"`
+	GraphQL struct {
+		Query     string `json:"query"`
+		Variables string `json:"variables"`
+	} `json:"graphql"`
+	Options struct {
+		Raw struct {
+			Language string `json:"language"`
+		} `json:"raw"`
+	} `json:"options"`
+	Disabled bool `json:"disabled"`
+}
+
+// PostmanAuth auth type with attributes of the type
+type PostmanAuth struct {
+	Type   string
+	Values Pairs
+}
+
+// UnmarshalJSON reads attributes of the auth type, listed as
+// [{key, value}] in v2.1 or as object in v2.0
+func (a *PostmanAuth) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw["type"], &a.Type); err != nil {
+		return fmt.Errorf("auth type error %w", err)
+	}
+	attrs, ok := raw[a.Type]
+	if !ok {
+		return nil
+	}
+	var list []PostmanKV
+	if err := json.Unmarshal(attrs, &list); err == nil {
+		for _, kv := range list {
+			a.Values.Add(kv.Key, string(kv.Value))
+		}
+		return nil
+	}
+	var object map[string]PostmanValue
+	if err := json.Unmarshal(attrs, &object); err != nil {
+		return fmt.Errorf("auth %s attributes error %w", a.Type, err)
+	}
+	for k, v := range object {
+		a.Values.Add(k, string(v))
+	}
+	return nil
+}
+
+func (a PostmanAuth) value(key string) string {
+	v, _ := a.Values.Get(key)
+	return v
+}
+
+type PostmanEvent struct {
+	Listen string `json:"listen"`
+	Script struct {
+		Exec any `json:"exec"`
+	} `json:"script"`
+}
+
+// PostmanDescription description string or {content}
+type PostmanDescription string
+
+func (d *PostmanDescription) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*d = PostmanDescription(s)
+		return nil
+	}
+	var object struct {
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil
+	}
+	*d = PostmanDescription(object.Content)
+	return nil
+}
+
+type PostmanResponse struct {
+	Name   string      `json:"name"`
+	Status string      `json:"status"`
+	Code   int         `json:"code"`
+	Header []PostmanKV `json:"header"`
+	Body   string      `json:"body"`
+}
+
+// PostmanEnvironment exported Postman environment
+type PostmanEnvironment struct {
+	Name   string `json:"name"`
+	Values []struct {
+		Key     string       `json:"key"`
+		Value   PostmanValue `json:"value"`
+		Type    string       `json:"type"`
+		Enabled *bool        `json:"enabled"`
+	} `json:"values"`
+}
+
+// postmanScriptReplacer Postman sandbox API with bruno equivalents
+var postmanScriptReplacer = strings.NewReplacer(
+	"pm.environment.set(", "bru.setEnvVar(",
+	"pm.environment.get(", "bru.getEnvVar(",
+	"pm.collectionVariables.set(", "bru.setVar(",
+	"pm.collectionVariables.get(", "bru.getVar(",
+	"pm.variables.set(", "bru.setVar(",
+	"pm.variables.get(", "bru.getVar(",
+	"pm.globals.set(", "bru.setGlobalEnvVar(",
+	"pm.globals.get(", "bru.getGlobalEnvVar(",
+	"pm.response.json()", "res.getBody()",
+	"pm.response.code", "res.getStatus()",
+	"pm.response.responseTime", "res.getResponseTime()",
+	"pm.response.headers.get(", "res.getHeader(",
+	"pm.response.to.have.status(", "expect(res.getStatus()).to.equal(",
+	"pm.info.requestName", "req.getName()",
+	"pm.test(", "test(",
+	"pm.expect(", "expect(",
+	"postman.setEnvironmentVariable(", "bru.setEnvVar(",
+	"postman.getEnvironmentVariable(", "bru.getEnvVar(",
+	"postman.setGlobalVariable(", "bru.setGlobalEnvVar(",
+	"postman.getGlobalVariable(", "bru.getGlobalEnvVar(",
+)
+
+var postmanAPIRe = regexp.MustCompile(`\b(pm|postman)\.\w+`)
+
+// TranslatePostmanScript replaces known Postman sandbox calls with bruno
+// ones and returns lines with Postman API left untranslated
+func TranslatePostmanScript(script string) (string, []string) {
+	script = postmanScriptReplacer.Replace(script)
+	var untranslated []string
+	for i, line := range strings.Split(script, "\n") {
+		if postmanAPIRe.MatchString(line) {
+			untranslated = append(untranslated, fmt.Sprintf("line %d: %s", i+1, strings.TrimSpace(line)))
+		}
+	}
+	return script, untranslated
+}
+
+// postmanImport state of the collection import
+type postmanImport struct {
+	opts         RequestOptions
+	written      map[string]bool
+	created      int
+	skipped      int
+	total        int
+	untranslated int
+}
+
+// DoPostman converts Postman v2.1 collection into the bruno collection.
+// Folders are created by DoFolder, requests keep Postman {{vars}}.
+// Environments are written to environments/<name>.bru. Script lines
+// with untranslated Postman API are reported to stderr and listed in docs.
+func DoPostman(input, collection, basedir string, envFiles []string, opts RequestOptions) error {
+	if input == "" {
+		return fmt.Errorf("-i input file is required")
+	}
+
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return fmt.Errorf("read %q file error %w", input, err)
+	}
+
+	var pc PostmanCollection
+	if err := json.Unmarshal(data, &pc); err != nil {
+		return fmt.Errorf("parse postman collection error %w", err)
+	}
+	if !strings.Contains(pc.Info.Schema, "collection") {
+		return fmt.Errorf("parse postman collection error: %q is not a postman collection", input)
+	}
+
+	if collection == "" {
+		collection = requestFileName(pc.Info.Name)
+	}
+	if collection == "" {
+		collection = "postman"
+	}
+	dir, err := ensureCollection(collection, basedir)
+	if err != nil {
+		return err
+	}
+
+	pi := &postmanImport{opts: opts, written: map[string]bool{}}
+	if err := pi.collection(dir, pc); err != nil {
+		return err
+	}
+	for _, env := range envFiles {
+		fp, err := importPostmanEnvironment(env, dir)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "[I] environment: %s\n", fp)
+	}
+	pi.items(dir, pc.Item)
+
+	fmt.Fprintf(os.Stderr, "[I] postman requests: %d, created: %d, skipped: %d, untranslated script lines: %d\n",
+		pi.total, pi.created, pi.skipped, pi.untranslated)
+	return nil
+}
+
+// collection writes collection auth, scripts and variables to collection.bru
+func (pi *postmanImport) collection(dir string, pc PostmanCollection) error {
+	fp := filepath.Join(dir, "collection.bru")
+	doc, err := BruFromFile(fp)
+	if err != nil {
+		return err
+	}
+
+	if pc.Auth != nil {
+		SetAuthBlocks(doc, pi.auth(pc.Info.Name, pc.Auth))
+	}
+	var vars Pairs
+	for _, v := range pc.Variable {
+		key := v.Key
+		if v.Disabled {
+			key = "~" + key
+		}
+		vars.Add(key, string(v.Value))
+	}
+	if len(vars) > 0 {
+		doc.SetBlock(bruDictWithDisabled("vars:pre-request", vars))
+	}
+	pi.setScripts(doc, pc.Info.Name, pc.Event)
+	if pc.Info.Description != "" {
+		doc.SetBlock(NewBruText("docs", string(pc.Info.Description)))
+	}
+
+	return doc.WriteFile(fp)
+}
+
+// items converts requests and folders recursively
+func (pi *postmanImport) items(dir string, items []PostmanItem) {
+	for _, item := range items {
+		if item.Request == nil {
+			sub, err := pi.folder(dir, item)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[W] skip folder %q: %s\n", item.Name, err)
+				continue
+			}
+			pi.items(sub, item.Item)
+			continue
+		}
+
+		pi.total++
+		fp, err := pi.request(dir, item)
+		if err != nil {
+			pi.skipped++
+			fmt.Fprintf(os.Stderr, "[W] skip request %q: %s\n", item.Name, err)
+			continue
+		}
+		pi.created++
+		fmt.Println(fp)
+	}
+}
+
+// folder creates the folder with Postman folder auth and scripts
+func (pi *postmanImport) folder(dir string, item PostmanItem) (string, error) {
+	sub, doc, err := importFolder(dir, item.Name)
+	if err != nil {
+		return "", err
+	}
+	if item.Auth != nil {
+		SetAuthBlocks(doc, pi.auth(item.Name, item.Auth))
+	}
+	pi.setScripts(doc, item.Name, item.Event)
+	if item.Description != "" {
+		doc.SetBlock(NewBruText("docs", string(item.Description)))
+	}
+
+	return sub, doc.WriteFile(filepath.Join(sub, "folder.bru"))
+}
+
+func (pi *postmanImport) request(dir string, item PostmanItem) (string, error) {
+	pr := item.Request
+	name := requestFileName(item.Name)
+	if name == "" {
+		name = strings.ToUpper(pr.Method)
+	}
+	// Postman allows requests with the same name in a folder
+	if pi.written[filepath.Join(dir, name)] {
+		name, _ = nextFreeName(dir, name)
+	}
+
+	r := BruRequest{
+		Name:   name,
+		Method: strings.ToUpper(pr.Method),
+		URL:    pr.URL.String(),
+	}
+	if r.Method == "" {
+		r.Method = "GET"
+	}
+	for _, q := range pr.URL.Query {
+		r.Query.Add(postmanKey(q), string(q.Value))
+	}
+	for _, v := range pr.URL.Variable {
+		r.PathParams.Add(v.Key, string(v.Value))
+	}
+	for _, h := range pr.Header {
+		r.Headers.Add(postmanKey(h), string(h.Value))
+	}
+
+	r.Auth = &RequestAuth{Mode: "inherit"}
+	if pr.Auth != nil {
+		r.Auth = pi.auth(item.Name, pr.Auth)
+	}
+	if err := postmanBody(&r, pr); err != nil {
+		return "", err
+	}
+
+	for _, event := range item.Event {
+		script, untranslated := TranslatePostmanScript(postmanScript(event))
+		switch event.Listen {
+		case "prerequest":
+			r.PreRequest = script
+		case "test":
+			r.Tests = script
+		default:
+			continue
+		}
+		pi.reportUntranslated(item.Name, event.Listen, untranslated)
+		for _, line := range untranslated {
+			r.Docs = append(r.Docs, fmt.Sprintf("- [ ] untranslated %s script %s", event.Listen, line))
+		}
+	}
+
+	description := string(pr.Description)
+	if description == "" {
+		description = string(item.Description)
+	}
+	if description != "" {
+		if len(r.Docs) > 0 {
+			r.Docs = append(r.Docs, "")
+		}
+		r.Docs = append(r.Docs, strings.Split(strings.TrimRight(description, "\n"), "\n")...)
+	}
+	if pi.opts.Examples && len(item.Response) > 0 {
+		r.Docs = append(r.Docs, "", "## Example response", "", "```")
+		r.Docs = append(r.Docs, strings.Split(postmanResponseExample(item.Response[0]), "\n")...)
+		r.Docs = append(r.Docs, "```")
+	}
+
+	fp, err := WriteBruRequest(dir, r, pi.opts)
+	if err != nil {
+		return "", err
+	}
+	pi.written[strings.TrimSuffix(fp, ".bru")] = true
+	return fp, nil
+}
+
+// postmanBody sets the body of the request, raw body type is taken from
+// the language option or the Content-Type header
+func postmanBody(r *BruRequest, pr *PostmanRequest) error {
+	body := pr.Body
+	if body == nil || body.Disabled {
+		r.BodyType = "none"
+		return nil
+	}
+
+	switch body.Mode {
+	case "raw":
+		r.Body = body.Raw
+		switch body.Options.Raw.Language {
+		case "json":
+			r.BodyType = "json"
+		case "xml":
+			r.BodyType = "xml"
+		case "text", "html", "javascript":
+			r.BodyType = "text"
+		default:
+			r.BodyType = "text"
+			for _, h := range pr.Header {
+				if strings.EqualFold(h.Key, "Content-Type") && !h.Disabled {
+					if bt, err := BodyTypeFromContentType(string(h.Value)); err == nil && bt != "none" && bt != "multipartForm" && bt != "formUrlEncoded" && bt != "file" {
+						r.BodyType = bt
+					}
+				}
+			}
+		}
+	case "urlencoded":
+		r.BodyType = "formUrlEncoded"
+		for _, f := range body.URLEncoded {
+			r.Form.Add(postmanKey(f), string(f.Value))
+		}
+	case "formdata":
+		r.BodyType = "multipartForm"
+		for _, f := range body.FormData {
+			value := string(f.Value)
+			if f.Type == "file" {
+				value = "@file(" + strings.Join(postmanStrings(f.Src), "|") + ")"
+			}
+			if f.ContentType != "" {
+				value += " @contentType(" + f.ContentType + ")"
+			}
+			r.Form.Add(postmanKey(f), value)
+		}
+	case "file":
+		r.BodyType = "file"
+		r.Form.Add("file", "@file("+body.File.Src+")")
+	case "graphql":
+		r.Type = "graphql"
+		r.BodyType = "graphql"
+		r.Body = body.GraphQL.Query
+		r.GraphQLVars = body.GraphQL.Variables
+	case "":
+		r.BodyType = "none"
+	default:
+		return fmt.Errorf("unsupported body mode %q", body.Mode)
+	}
+	return nil
+}
+
+// auth maps Postman auth to bruno auth, unsupported types are reported
+// and written as none
+func (pi *postmanImport) auth(owner string, a *PostmanAuth) *RequestAuth {
+	auth := &RequestAuth{Mode: a.Type}
+	switch a.Type {
+	case "noauth":
+		auth.Mode = "none"
+	case "bearer":
+		auth.Values.Add("token", a.value("token"))
+	case "basic", "digest":
+		auth.Values.Add("username", a.value("username"))
+		auth.Values.Add("password", a.value("password"))
+	case "apikey":
+		placement := "header"
+		if a.value("in") == "query" {
+			placement = "queryparams"
+		}
+		auth.Values.Add("key", a.value("key"))
+		auth.Values.Add("value", a.value("value"))
+		auth.Values.Add("placement", placement)
+	case "awsv4":
+		auth.Values.Add("accessKeyId", a.value("accessKey"))
+		auth.Values.Add("secretAccessKey", a.value("secretKey"))
+		auth.Values.Add("sessionToken", a.value("sessionToken"))
+		auth.Values.Add("service", a.value("service"))
+		auth.Values.Add("region", a.value("region"))
+		auth.Values.Add("profileName", "")
+	case "ntlm":
+		auth.Values.Add("username", a.value("username"))
+		auth.Values.Add("password", a.value("password"))
+		auth.Values.Add("domain", a.value("domain"))
+	case "oauth2":
+		// token obtained in Postman, flows have to be configured in bruno
+		if token := a.value("accessToken"); token != "" {
+			auth.Mode = "bearer"
+			auth.Values.Add("token", token)
+			break
+		}
+		fallthrough
+	default:
+		fmt.Fprintf(os.Stderr, "[W] %s: unsupported auth type %q, auth is none\n", owner, a.Type)
+		return &RequestAuth{Mode: "none"}
+	}
+	return auth
+}
+
+// setScripts writes translated collection or folder scripts to the doc
+func (pi *postmanImport) setScripts(doc *BruDoc, owner string, events []PostmanEvent) {
+	for _, event := range events {
+		name := ""
+		switch event.Listen {
+		case "prerequest":
+			name = "script:pre-request"
+		case "test":
+			name = "tests"
+		default:
+			continue
+		}
+		script, untranslated := TranslatePostmanScript(postmanScript(event))
+		if strings.TrimSpace(script) == "" {
+			continue
+		}
+		pi.reportUntranslated(owner, event.Listen, untranslated)
+		doc.SetBlock(NewBruText(name, script))
+	}
+}
+
+func (pi *postmanImport) reportUntranslated(owner, listen string, lines []string) {
+	pi.untranslated += len(lines)
+	for _, line := range lines {
+		fmt.Fprintf(os.Stderr, "[W] %s: untranslated %s script %s\n", owner, listen, line)
+	}
+}
+
+// importPostmanEnvironment writes the Postman environment file to
+// environments/<name>.bru. Secret values are not written, their names
+// are listed in vars:secret.
+func importPostmanEnvironment(input, collDir string) (string, error) {
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return "", fmt.Errorf("read %q file error %w", input, err)
+	}
+	var env PostmanEnvironment
+	if err := json.Unmarshal(data, &env); err != nil {
+		return "", fmt.Errorf("parse postman environment error %w", err)
+	}
+
+	name := requestFileName(env.Name)
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(input), filepath.Ext(input))
+	}
+	fp := filepath.Join(collDir, "environments", name+".bru")
+	doc, err := BruFromFile(fp)
+	if err != nil {
+		doc = &BruDoc{}
+	}
+
+	vars := doc.Block("vars")
+	if vars == nil {
+		vars = NewBruDict("vars", nil)
+		doc.AddBlock(vars)
+	}
+	secrets := doc.Block("vars:secret")
+	for _, v := range env.Values {
+		if v.Type == "secret" {
+			if secrets == nil {
+				secrets = &BruBlock{Name: "vars:secret", Kind: BruList}
+				doc.AddBlock(secrets)
+			}
+			if _, ok := secrets.Get(v.Key); !ok {
+				secrets.Add(v.Key, "")
+			}
+			fmt.Fprintf(os.Stderr, "[W] %s: secret %s value is not written\n", env.Name, v.Key)
+			continue
+		}
+		vars.Set(v.Key, string(v.Value))
+		if v.Enabled != nil && !*v.Enabled {
+			for i := range vars.Entries {
+				if vars.Entries[i].Key == v.Key {
+					vars.Entries[i].Disabled = true
+				}
+			}
+		}
+	}
+
+	if err := doc.WriteFile(fp); err != nil {
+		return "", err
+	}
+	return fp, nil
+}
+
+// bruDictWithDisabled creates dictionary block,
+// keys with `~` prefix are disabled entries
+func bruDictWithDisabled(name string, pairs Pairs) *BruBlock {
+	b := NewBruDict(name, nil)
+	for _, p := range pairs {
+		key, disabled := strings.CutPrefix(p.Key, "~")
+		b.Entries = append(b.Entries, BruEntry{Key: key, Value: p.Value, Disabled: disabled})
+	}
+	return b
+}
+
+// postmanKey returns the key with `~` prefix for disabled entries
+func postmanKey(kv PostmanKV) string {
+	if kv.Disabled {
+		return "~" + kv.Key
+	}
+	return kv.Key
+}
+
+// postmanScript returns the script of the event, exec is a list of lines
+// or a string
+func postmanScript(event PostmanEvent) string {
+	return strings.Join(postmanStrings(event.Script.Exec), "\n")
+}
+
+// postmanStrings returns string or list of strings value
+func postmanStrings(v any) []string {
+	switch x := v.(type) {
+	case string:
+		if x == "" {
+			return nil
+		}
+		return []string{x}
+	case []any:
+		var list []string
+		for _, item := range x {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// postmanResponseExample returns the saved response for the docs example
+func postmanResponseExample(resp PostmanResponse) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "HTTP/1.1 %d %s\n", resp.Code, resp.Status)
+	for _, h := range resp.Header {
+		fmt.Fprintf(&sb, "%s: %s\n", h.Key, h.Value)
+	}
+	sb.WriteString("\n")
+	sb.WriteString(resp.Body)
+	return responseExample([]byte(sb.String()))
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testPostmanCollection = `{
+  "info": {
+    "name": "Shop API",
+    "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+  },
+  "auth": {"type": "bearer", "bearer": [{"key": "token", "value": "{{token}}", "type": "string"}]},
+  "variable": [
+    {"key": "baseUrl", "value": "https://api.example.com"},
+    {"key": "limit", "value": 10},
+    {"key": "old", "value": "x", "disabled": true}
+  ],
+  "event": [
+    {"listen": "prerequest", "script": {"exec": ["pm.variables.set(\"ts\", Date.now());"]}}
+  ],
+  "item": [
+    {
+      "name": "Users",
+      "auth": {"type": "apikey", "apikey": [{"key": "key", "value": "X-Key"}, {"key": "value", "value": "{{apiKey}}"}, {"key": "in", "value": "header"}]},
+      "item": [
+        {
+          "name": "Get user",
+          "request": {
+            "method": "GET",
+            "header": [{"key": "Accept", "value": "application/json"}, {"key": "X-Debug", "value": "1", "disabled": true}],
+            "url": {
+              "raw": "{{baseUrl}}/users/:id?expand=true",
+              "host": ["{{baseUrl}}"],
+              "path": ["users", ":id"],
+              "query": [{"key": "expand", "value": "true"}, {"key": "fields", "value": "name", "disabled": true}],
+              "variable": [{"key": "id", "value": "42"}]
+            },
+            "description": "Returns a user."
+          },
+          "event": [
+            {"listen": "test", "script": {"exec": [
+              "pm.test(\"ok\", function () {",
+              "  pm.expect(pm.response.code).to.eql(200);",
+              "});",
+              "pm.environment.set(\"userId\", pm.response.json().id);",
+              "pm.sendRequest(\"https://example.com\");"
+            ]}}
+          ],
+          "response": [
+            {"name": "ok", "status": "OK", "code": 200, "header": [{"key": "Content-Type", "value": "application/json"}], "body": "{\"id\": 42}"}
+          ]
+        },
+        {
+          "name": "Get user",
+          "request": {"method": "GET", "url": "{{baseUrl}}/users/me", "auth": {"type": "noauth"}}
+        }
+      ]
+    },
+    {
+      "name": "Create order",
+      "request": {
+        "method": "POST",
+        "header": [{"key": "Content-Type", "value": "application/json"}],
+        "body": {"mode": "raw", "raw": "{\n  \"item\": 1\n}", "options": {"raw": {"language": "json"}}},
+        "url": "{{baseUrl}}/orders"
+      }
+    },
+    {
+      "name": "Upload",
+      "request": {
+        "method": "POST",
+        "body": {"mode": "formdata", "formdata": [
+          {"key": "title", "value": "avatar", "type": "text"},
+          {"key": "file", "type": "file", "src": "/tmp/a.png", "contentType": "image/png"}
+        ]},
+        "url": "{{baseUrl}}/upload"
+      }
+    },
+    {
+      "name": "Search",
+      "request": {
+        "method": "POST",
+        "body": {"mode": "graphql", "graphql": {"query": "query { users { id } }", "variables": "{\"n\": 1}"}},
+        "url": "{{baseUrl}}/graphql"
+      }
+    }
+  ]
+}`
+
+const testPostmanEnvironment = `{
+  "name": "Staging",
+  "values": [
+    {"key": "baseUrl", "value": "https://staging.example.com", "enabled": true},
+    {"key": "token", "value": "s3cr3t", "type": "secret", "enabled": true},
+    {"key": "unused", "value": "1", "enabled": false}
+  ],
+  "_postman_variable_scope": "environment"
+}`
+
+func TestPostmanAuthUnmarshal(t *testing.T) {
+	var v21, v20 PostmanAuth
+	if err := json.Unmarshal([]byte(`{"type": "basic", "basic": [{"key": "username", "value": "u"}, {"key": "password", "value": "p"}]}`), &v21); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if err := json.Unmarshal([]byte(`{"type": "bearer", "bearer": {"token": "t"}}`), &v20); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if v21.value("username") != "u" || v21.value("password") != "p" || v20.value("token") != "t" {
+		t.Errorf("auth values = %v, %v", v21.Values, v20.Values)
+	}
+}
+
+func TestPostmanURLString(t *testing.T) {
+	var u PostmanURL
+	json.Unmarshal([]byte(`{"protocol": "https", "host": ["api", "example", "com"], "path": ["v1", "users"], "query": [{"key": "a", "value": "1"}, {"key": "b", "value": "2", "disabled": true}]}`), &u)
+	if got := u.String(); got != "https://api.example.com/v1/users?a=1" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestTranslatePostmanScript(t *testing.T) {
+	script, untranslated := TranslatePostmanScript("pm.environment.set(\"id\", pm.response.json().id);\npm.sendRequest(url);\npostman.setNextRequest(null);")
+	want := "bru.setEnvVar(\"id\", res.getBody().id);\npm.sendRequest(url);\npostman.setNextRequest(null);"
+	if script != want {
+		t.Errorf("script = %q, want %q", script, want)
+	}
+	if !reflect.DeepEqual(untranslated, []string{"line 2: pm.sendRequest(url);", "line 3: postman.setNextRequest(null);"}) {
+		t.Errorf("untranslated = %q", untranslated)
+	}
+}
+
+func TestDoPostman(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "shop.postman_collection.json")
+	envInput := filepath.Join(tmpDir, "staging.postman_environment.json")
+	os.WriteFile(input, []byte(testPostmanCollection), 0o644)
+	os.WriteFile(envInput, []byte(testPostmanEnvironment), 0o644)
+
+	opts := RequestOptions{Examples: true}
+	if err := DoPostman(input, "", tmpDir, []string{envInput}, opts); err != nil {
+		t.Fatalf("DoPostman() error = %v", err)
+	}
+
+	coll := filepath.Join(tmpDir, "Shop API")
+	tests := []struct {
+		file    string
+		want    []string
+		notWant []string
+	}{
+		{
+			file: "collection.bru",
+			want: []string{
+				"auth {\n  mode: bearer\n}", "token: {{token}}",
+				"vars:pre-request {\n  baseUrl: https://api.example.com\n  limit: 10\n  ~old: x\n}",
+				"script:pre-request {\n  bru.setVar(\"ts\", Date.now());\n}",
+			},
+		},
+		{
+			file:    "Users/folder.bru",
+			want:    []string{"name: Users", "mode: apikey", "key: X-Key", "placement: header"},
+			notWant: []string{"Cookie: {{cook}}"},
+		},
+		{
+			file: "Users/Get user.bru",
+			want: []string{
+				"url: {{baseUrl}}/users/:id?expand=true",
+				"auth: inherit",
+				"  expand: true\n  ~fields: name",
+				"params:path {\n  id: 42\n}",
+				"~X-Debug: 1",
+				"tests {\n  test(\"ok\", function () {\n    expect(res.getStatus()).to.eql(200);",
+				"bru.setEnvVar(\"userId\", res.getBody().id);",
+				"- [ ] untranslated test script line 5: pm.sendRequest(\"https://example.com\");",
+				"Returns a user.",
+				"## Example response",
+				`  {"id": 42}`,
+			},
+		},
+		{
+			file: "Users/Get user-2.bru",
+			want: []string{"url: {{baseUrl}}/users/me", "auth: none"},
+		},
+		{
+			file: "Create order.bru",
+			want: []string{"body: json", "body:json {\n  {\n    \"item\": 1\n  }\n}"},
+		},
+		{
+			file: "Upload.bru",
+			want: []string{"body: multipartForm", "title: avatar", "file: @file(/tmp/a.png) @contentType(image/png)"},
+		},
+		{
+			file: "Search.bru",
+			want: []string{"type: graphql", "body: graphql", "body:graphql {\n  query { users { id } }\n}", "body:graphql:vars {\n  {\"n\": 1}\n}"},
+		},
+		{
+			file:    "environments/Staging.bru",
+			want:    []string{"baseUrl: https://staging.example.com", "~unused: 1", "vars:secret [\n  token\n]"},
+			notWant: []string{"s3cr3t"},
+		},
+	}
+	for _, tt := range tests {
+		data, err := os.ReadFile(filepath.Join(coll, tt.file))
+		if err != nil {
+			t.Errorf("expected file %q was not created", tt.file)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("%s should contain %q\ngot:\n%s", tt.file, want, data)
+			}
+		}
+		for _, notWant := range tt.notWant {
+			if strings.Contains(string(data), notWant) {
+				t.Errorf("%s should not contain %q\ngot:\n%s", tt.file, notWant, data)
+			}
+		}
+	}
+
+	if err := DoPostman(envInput, "", tmpDir, nil, opts); err == nil {
+		t.Error("DoPostman() expected error for environment file as collection")
+	}
+}
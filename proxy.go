@@ -0,0 +1,461 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// hopHeaders are connection specific headers, they're not forwarded
+// upstream and not written to request files
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// Proxy forward HTTP proxy converting passing requests into .bru files.
+// CONNECT tunnels are intercepted when CA is set, otherwise they're
+// passed through as is and their requests are not captured.
+// Connections are served by the proxy itself instead of http.Server,
+// so captured requests keep the header order of the wire.
+type Proxy struct {
+	Basedir string
+	EnvFile string
+	Filter  RequestFilter
+	Options RequestOptions
+	// CA signs certificates of intercepted hosts
+	CA *tls.Certificate
+	// Transport sends requests upstream, http.DefaultTransport if nil
+	Transport http.RoundTripper
+
+	// mu serializes conversions, so seq numbers and env vars
+	// of concurrent requests don't collide
+	mu   sync.Mutex
+	seen map[string]bool
+	// captures conversions running in background
+	captures sync.WaitGroup
+
+	certMu sync.Mutex
+	certs  map[string]*tls.Certificate
+}
+
+// proxyMaxHeader limits the size of the request header block
+const proxyMaxHeader = 64 << 10
+
+// DoProxy runs the capturing proxy on listen address. With caDir TLS
+// is intercepted with the CA from caDir, created on first run.
+// On interrupt the proxy stops accepting connections and returns
+// after running conversions are written.
+func DoProxy(listen, caDir, basedir, envfile string, filter RequestFilter, opts RequestOptions) error {
+	p := &Proxy{
+		Basedir: basedir,
+		EnvFile: envfile,
+		Filter:  filter,
+		Options: opts,
+	}
+	if caDir != "" {
+		ca, err := LoadOrCreateCA(caDir)
+		if err != nil {
+			return err
+		}
+		p.CA = ca
+	}
+
+	l, err := net.Listen("tcp", listen)
+	if err != nil {
+		return fmt.Errorf("listen %s error %w", listen, err)
+	}
+	fmt.Fprintf(os.Stderr, "[I] proxy listening on %s\n", listen)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+	var interrupted atomic.Bool
+	go func() {
+		<-stop
+		interrupted.Store(true)
+		l.Close()
+	}()
+
+	err = p.Serve(l)
+	p.captures.Wait()
+	if interrupted.Load() {
+		return nil
+	}
+	return err
+}
+
+// Serve accepts proxy connections of the listener
+func (p *Proxy) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			p.serveRequests(conn, bufio.NewReaderSize(conn, proxyMaxHeader), "")
+		}()
+	}
+}
+
+// serveRequests reads requests of the connection, forwards them and
+// writes responses back. tunnel is host:port of the intercepted CONNECT
+// tunnel, its requests have origin-form targets; tunnel is empty for
+// connections of the proxy clients.
+func (p *Proxy) serveRequests(conn net.Conn, br *bufio.Reader, tunnel string) {
+	for {
+		order := peekHeaderOrder(br)
+		req, err := http.ReadRequest(br)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				fmt.Fprintf(os.Stderr, "[W] read request of %s error: %s\n", conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		switch {
+		case tunnel != "":
+			req.URL.Scheme = "https"
+			req.URL.Host = tunnel
+			if req.Host == "" {
+				req.Host = tunnel
+			}
+		case req.Method == http.MethodConnect:
+			p.connect(&bufferedConn{Conn: conn, r: br}, req.Host)
+			return
+		case !req.URL.IsAbs():
+			resp := proxyErrorResponse(http.StatusBadRequest, "http2bruno proxy: absolute URL is required")
+			resp.Write(conn)
+			return
+		}
+
+		resp, err := p.forward(req, order)
+		if err != nil {
+			resp = proxyErrorResponse(http.StatusBadGateway, err.Error())
+		}
+		for _, h := range hopHeaders {
+			resp.Header.Del(h)
+		}
+		// upstream may answer with HTTP/2, the client speaks HTTP/1.1
+		resp.ProtoMajor, resp.ProtoMinor = 1, 1
+		resp.Close = resp.Close || req.Close
+		err = resp.Write(conn)
+		resp.Body.Close()
+		if err != nil || resp.Close {
+			return
+		}
+	}
+}
+
+// peekHeaderOrder returns header names of the next request
+// of the reader without consuming it
+func peekHeaderOrder(br *bufio.Reader) []string {
+	n := 1
+	for {
+		_, err := br.Peek(n)
+		b, _ := br.Peek(br.Buffered())
+		if err != nil || bytes.Contains(b, []byte("\r\n\r\n")) || bytes.Contains(b, []byte("\n\n")) || len(b) == br.Size() {
+			return HeaderOrder(b)
+		}
+		// wait for more data only if the header block is incomplete
+		n = len(b) + 1
+	}
+}
+
+func proxyErrorResponse(status int, msg string) *http.Response {
+	return &http.Response{
+		StatusCode:    status,
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"text/plain; charset=utf-8"}},
+		Body:          io.NopCloser(strings.NewReader(msg)),
+		ContentLength: int64(len(msg)),
+		Close:         true,
+	}
+}
+
+// bufferedConn reads the connection through its buffered reader
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// forward sends the request upstream, it's captured in background
+func (p *Proxy) forward(r *http.Request, order []string) (*http.Response, error) {
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read request body error %w", err)
+	}
+
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	out.Body = nil
+	out.ContentLength = int64(len(body))
+	if len(body) > 0 {
+		out.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	for _, h := range hopHeaders {
+		out.Header.Del(h)
+	}
+
+	captured := out.Clone(context.Background())
+	p.captures.Add(1)
+	go func() {
+		defer p.captures.Done()
+		p.capture(captured, body, order)
+	}()
+
+	transport := p.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(out)
+	if err != nil {
+		return nil, fmt.Errorf("upstream request error %w", err)
+	}
+	return resp, nil
+}
+
+// capture converts in scope requests. Repeated requests are converted
+// once, with -merge every request is converted to merge new fields.
+func (p *Proxy) capture(r *http.Request, body []byte, order []string) {
+	if !p.Filter.Match(r) {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := proxyRequestKey(r)
+	if p.seen[key] && !p.Options.Merge {
+		return
+	}
+	if p.seen == nil {
+		p.seen = map[string]bool{}
+	}
+	p.seen[key] = true
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	fp, err := ConvertRequest(r, order, p.Basedir, p.EnvFile, p.Options)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[W] skip request %s %s: %s\n", r.Method, r.URL, err)
+		return
+	}
+	fmt.Println(fp)
+}
+
+// proxyRequestKey identifies repeated requests by method, host,
+// path and query param names
+func proxyRequestKey(r *http.Request) string {
+	var names []string
+	for name := range r.URL.Query() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return r.Method + " " + strings.ToLower(r.Host) + r.URL.Path + "?" + strings.Join(names, "&")
+}
+
+// connect handles CONNECT tunnels
+func (p *Proxy) connect(conn net.Conn, hostport string) {
+	var upstream net.Conn
+	if p.CA == nil {
+		var err error
+		upstream, err = net.DialTimeout("tcp", hostport, 10*time.Second)
+		if err != nil {
+			proxyErrorResponse(http.StatusBadGateway, err.Error()).Write(conn)
+			return
+		}
+		defer upstream.Close()
+	}
+
+	if _, err := io.WriteString(conn, "HTTP/1.1 200 Connection Established\r\n\r\n"); err != nil {
+		return
+	}
+
+	if upstream == nil {
+		p.intercept(conn, hostport)
+		return
+	}
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(upstream, conn)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(conn, upstream)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// intercept terminates TLS of the tunnel with a certificate signed by
+// the CA and forwards its requests
+func (p *Proxy) intercept(conn net.Conn, hostport string) {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = hostport
+	}
+	tlsConn := tls.Server(conn, &tls.Config{
+		NextProtos: []string{"http/1.1"},
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			name := hello.ServerName
+			if name == "" {
+				name = host
+			}
+			return p.certificate(name)
+		},
+	})
+	if err := tlsConn.Handshake(); err != nil {
+		fmt.Fprintf(os.Stderr, "[W] tls handshake with client for %s error: %s\n", hostport, err)
+		return
+	}
+
+	p.serveRequests(tlsConn, bufio.NewReaderSize(tlsConn, proxyMaxHeader), hostport)
+}
+
+// certificate returns the cached or a new certificate for host
+func (p *Proxy) certificate(host string) (*tls.Certificate, error) {
+	p.certMu.Lock()
+	defer p.certMu.Unlock()
+
+	if cert, ok := p.certs[host]; ok {
+		return cert, nil
+	}
+	cert, err := SignHostCertificate(p.CA, host)
+	if err != nil {
+		return nil, err
+	}
+	if p.certs == nil {
+		p.certs = map[string]*tls.Certificate{}
+	}
+	p.certs[host] = cert
+	return cert, nil
+}
+
+// LoadOrCreateCA loads ca.pem and ca-key.pem from dir, they're
+// generated if missing. The ca.pem must be trusted by the browser
+// for TLS interception.
+func LoadOrCreateCA(dir string) (*tls.Certificate, error) {
+	certPath := filepath.Join(dir, "ca.pem")
+	keyPath := filepath.Join(dir, "ca-key.pem")
+
+	if cert, err := tls.LoadX509KeyPair(certPath, keyPath); err == nil {
+		cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return nil, fmt.Errorf("parse %q file error %w", certPath, err)
+		}
+		return &cert, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate CA key error %w", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          randomSerial(),
+		Subject:               pkix.Name{CommonName: "http2bruno proxy CA", Organization: []string{"http2bruno"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("create CA certificate error %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("marshal CA key error %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create %q dir error %w", dir, err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := os.WriteFile(certPath, certPEM, 0o644); err != nil {
+		return nil, fmt.Errorf("write %q file error %w", certPath, err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(keyPath, keyPEM, 0o600); err != nil {
+		return nil, fmt.Errorf("write %q file error %w", keyPath, err)
+	}
+	fmt.Fprintf(os.Stderr, "[I] created CA certificate %s, trust it in the browser to capture https\n", certPath)
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	cert.Leaf, _ = x509.ParseCertificate(der)
+	return &cert, nil
+}
+
+// SignHostCertificate creates a certificate for host signed by ca
+func SignHostCertificate(ca *tls.Certificate, host string) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate key error %w", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: randomSerial(),
+		Subject:      pkix.Name{CommonName: host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(1, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		tmpl.IPAddresses = []net.IP{ip}
+	} else {
+		tmpl.DNSNames = []string{host}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.Leaf, &key.PublicKey, ca.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("sign certificate for %q error %w", host, err)
+	}
+	return &tls.Certificate{
+		Certificate: [][]byte{der, ca.Certificate[0]},
+		PrivateKey:  key,
+	}, nil
+}
+
+func randomSerial() *big.Int {
+	serial, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	return serial
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testUpstream(handler http.HandlerFunc, tlsServer bool) *httptest.Server {
+	if tlsServer {
+		return httptest.NewTLSServer(handler)
+	}
+	return httptest.NewServer(handler)
+}
+
+func TestProxy(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		tls  bool
+	}{
+		{"http", false},
+		{"https", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			upstream := testUpstream(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if r.Header.Get("Proxy-Connection") != "" {
+					t.Errorf("hop header was forwarded")
+				}
+				w.Header().Set("X-Upstream", "1")
+				w.Write([]byte("echo " + r.Method + " " + r.URL.Path + " " + string(body)))
+			}, tc.tls)
+			defer upstream.Close()
+
+			tmpDir := t.TempDir()
+			host := strings.TrimPrefix(strings.TrimPrefix(upstream.URL, "https://"), "http://")
+			if err := DoCollection(host, tmpDir); err != nil {
+				t.Fatalf("DoCollection() error = %v", err)
+			}
+
+			p := &Proxy{
+				Basedir: tmpDir,
+				EnvFile: "environments/base.bru",
+				Filter:  NewRequestFilter("", "GET,POST"),
+				Options: RequestOptions{SkipHeaders: DefaultSkipHeaders},
+			}
+			clientTransport := &http.Transport{}
+			if tc.tls {
+				ca, err := LoadOrCreateCA(filepath.Join(tmpDir, "ca"))
+				if err != nil {
+					t.Fatalf("LoadOrCreateCA() error = %v", err)
+				}
+				p.CA = ca
+				p.Transport = upstream.Client().Transport
+				pool := x509.NewCertPool()
+				pool.AddCert(ca.Leaf)
+				clientTransport.TLSClientConfig = &tls.Config{RootCAs: pool}
+			}
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("Listen() error = %v", err)
+			}
+			defer l.Close()
+			go p.Serve(l)
+			proxyURL, _ := url.Parse("http://" + l.Addr().String())
+			clientTransport.Proxy = http.ProxyURL(proxyURL)
+			client := &http.Client{Transport: clientTransport}
+
+			send := func(method, path, body string) {
+				t.Helper()
+				req, _ := http.NewRequest(method, upstream.URL+path, strings.NewReader(body))
+				if body != "" {
+					req.Header.Set("Content-Type", "application/json")
+				}
+				resp, err := client.Do(req)
+				if err != nil {
+					t.Fatalf("%s %s error = %v", method, path, err)
+				}
+				defer resp.Body.Close()
+				got, _ := io.ReadAll(resp.Body)
+				if want := "echo " + method + " " + path + " " + body; string(got) != want || resp.Header.Get("X-Upstream") != "1" {
+					t.Errorf("response = %q, want %q", got, want)
+				}
+			}
+			send("POST", "/login", `{"user":"a"}`)
+			send("GET", "/users", "")
+			// repeated request is not converted again
+			send("GET", "/users", "")
+			// filtered by method
+			send("DELETE", "/users", "")
+			p.captures.Wait()
+
+			collDir := filepath.Join(tmpDir, host)
+			for _, fp := range []string{"login-POST.bru", "users-GET.bru"} {
+				if _, err := os.Stat(filepath.Join(collDir, fp)); err != nil {
+					t.Errorf("expected file %q was not created", fp)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(collDir, "users-DELETE.bru")); err == nil {
+				t.Error("filtered request was converted")
+			}
+			data, _ := os.ReadFile(filepath.Join(collDir, "login-POST.bru"))
+			if !strings.Contains(string(data), `{"user":"a"}`) {
+				t.Errorf("login-POST.bru body was not captured:\n%s", data)
+			}
+		})
+	}
+}
+
+func TestProxyHeaderOrder(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	tmpDir := t.TempDir()
+	host := strings.TrimPrefix(upstream.URL, "http://")
+	if err := DoCollection(host, tmpDir); err != nil {
+		t.Fatalf("DoCollection() error = %v", err)
+	}
+	p := &Proxy{
+		Basedir: tmpDir,
+		EnvFile: "environments/base.bru",
+		Options: RequestOptions{SkipHeaders: DefaultSkipHeaders},
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer l.Close()
+	go p.Serve(l)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+	// headers are sent unsorted
+	io.WriteString(conn, "GET "+upstream.URL+"/order HTTP/1.1\r\nHost: "+host+"\r\nX-Zeta: 1\r\nAccept: */*\r\nX-Alpha: 2\r\n\r\n")
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("ReadResponse() error = %v", err)
+	}
+	resp.Body.Close()
+	p.captures.Wait()
+
+	data, _ := os.ReadFile(filepath.Join(tmpDir, host, "order-GET.bru"))
+	if !strings.Contains(string(data), "headers {\n  X-Zeta: 1\n  Accept: */*\n  X-Alpha: 2\n}") {
+		t.Errorf("headers should keep wire order\ngot:\n%s", data)
+	}
+}
+
+func TestLoadOrCreateCA(t *testing.T) {
+	dir := t.TempDir()
+	ca, err := LoadOrCreateCA(dir)
+	if err != nil {
+		t.Fatalf("LoadOrCreateCA() error = %v", err)
+	}
+	loaded, err := LoadOrCreateCA(dir)
+	if err != nil {
+		t.Fatalf("LoadOrCreateCA() second run error = %v", err)
+	}
+	if !loaded.Leaf.Equal(ca.Leaf) || !loaded.Leaf.IsCA {
+		t.Error("LoadOrCreateCA() should load the created CA")
+	}
+
+	cert, err := SignHostCertificate(ca, "api.example.com")
+	if err != nil {
+		t.Fatalf("SignHostCertificate() error = %v", err)
+	}
+	leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+	if _, err := leaf.Verify(x509.VerifyOptions{DNSName: "api.example.com", Roots: pool}); err != nil {
+		t.Errorf("host certificate verify error = %v", err)
+	}
+}
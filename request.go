@@ -6,27 +6,76 @@ import (
 	"fmt"
 	"io"
 	"mime"
-	"mime/multipart"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 )
 
 type RequestData struct {
-	FilesCount int
-	Name       string
-	Basedir    string
-	Method     string
-	Path       string
-	RawQuery   string
-	BodyType   string
-	Body       string
-	Env        *BrunoEnv
-	HTTPReq    *http.Request
+	FilesCount       int
+	Name             string
+	Basedir          string
+	Method           string
+	Path             string
+	RawQuery         string
+	BodyType         string
+	Body             string
+	Env              *BrunoEnv
+	HTTPReq          *http.Request
+	HeaderOrder      []string
+	InheritedHeaders map[string]string
+	Auth             *RequestAuth
+	FormParts        []FormPart
+	GraphQL          *GraphQLRequest
+	EnvFile          string
+	Meta             RequestMeta
+	Options          RequestOptions
+}
+
+// RequestMeta per request data from the source file
+type RequestMeta struct {
+	// Name request name, derived from the path and method if empty
+	Name string
+	// Example captured response kept in docs
+	Example string
+	// URL target of the request set by the `# url:` meta line,
+	// applied by the parser
+	URL string
+	// Folder destination folder in the collection, created if missing
+	Folder string
+	// Tags meta tags of the request
+	Tags []string
+	// Note docs text
+	Note []string
+}
+
+// RequestOptions conversion settings from command line flags
+type RequestOptions struct {
+	// SkipHeaders headers which are never written to the request file
+	SkipHeaders []string
+	// PathParams rewrite {{var}} path segments to Bruno :var path params
+	PathParams bool
+	// Merge adds new params, headers and body fields to the existing request file
+	Merge bool
+	// Suffix writes name-METHOD-2.bru if the request file exists
+	Suffix bool
+	// Learn detects IDs, tokens and session cookies and writes them
+	// to the env file as new variables
+	Learn bool
+	// Scheme of requests which don't define it with the request line,
+	// :scheme, X-Forwarded-Proto or a `#` meta line
+	Scheme string
+	// PortVar writes non default ports to the port env var,
+	// otherwise the port is kept in the host var
+	PortVar bool
+	// Examples keeps captured responses as examples in docs
+	Examples bool
 }
 
 // BodyTypeName returns the value for the `body:value block`.
@@ -46,10 +95,12 @@ func BodyTypeName(bt string) string {
 }
 
 // BodyTypeFromContentType returns the Bruno body type based on
-// the request's Content-Type header.
+// the request's Content-Type header. Structured syntax suffixes
+// (+json, +xml) are recognized, text media types are text and
+// other media types are binary file bodies.
 //
 // Bruno body types:
-// none, json, xml, text, multipartForm, formUrlEncoded
+// none, json, xml, text, multipartForm, formUrlEncoded, file
 func BodyTypeFromContentType(ct string) (string, error) {
 	if ct == "" {
 		return "none", nil
@@ -60,42 +111,64 @@ func BodyTypeFromContentType(ct string) (string, error) {
 		return "", fmt.Errorf("failed to parse content type %q: %w", ct, err)
 	}
 
-	switch mediaType {
-	case "application/json":
+	switch {
+	case mediaType == "application/json", mediaType == "text/json", strings.HasSuffix(mediaType, "+json"):
 		return "json", nil
-	case "application/xml", "text/xml":
+	case mediaType == "application/xml", mediaType == "text/xml", strings.HasSuffix(mediaType, "+xml"):
 		return "xml", nil
-	case "text/plain":
-		return "text", nil
-	case "multipart/form-data":
+	case mediaType == "multipart/form-data":
 		return "multipartForm", nil
-	case "application/x-www-form-urlencoded":
+	case mediaType == "application/x-www-form-urlencoded":
 		return "formUrlEncoded", nil
+	case strings.HasPrefix(mediaType, "text/"), slices.Contains(textMediaTypes, mediaType):
+		return "text", nil
 	default:
-		return "", fmt.Errorf("unsupported content type: %s", mediaType)
+		return "file", nil
 	}
 }
 
-func DoRequest(basedir, envfile string) error {
+func DoRequest(basedir, envfile string, opts RequestOptions) error {
 	rawReq, err := io.ReadAll(os.Stdin)
 	if err != nil {
 		return fmt.Errorf("read from stdin error %w", err)
 	}
 
-	req, err := ParseRawRequest(rawReq)
+	req, order, err := ParseRequest(rawReq)
 	if err != nil {
 		return fmt.Errorf("parse raw request error %w", err)
 	}
 	defer req.Body.Close()
 
-	basedir, err = findCollectionDir(basedir, req.Host)
+	_, err = convertRequest(req, order, ParseRequestMeta(RawMetaLines(rawReq)), basedir, envfile, opts)
 	if err != nil {
-		return fmt.Errorf("find collection dir error %w", err)
+		return err
 	}
 
-	envs, err := EnvFromFile(filepath.Join(basedir, envfile))
+	// print request back for next processors
+	fmt.Print(string(rawReq))
+
+	return nil
+}
+
+// ConvertRequest writes the parsed request into the .bru file
+// of the matching collection under basedir and returns the file path.
+// headerOrder is the source order of the request headers, may be nil.
+func ConvertRequest(req *http.Request, headerOrder []string, basedir, envfile string, opts RequestOptions) (string, error) {
+	return convertRequest(req, headerOrder, RequestMeta{}, basedir, envfile, opts)
+}
+
+// convertRequest is ConvertRequest with per request meta data
+func convertRequest(req *http.Request, headerOrder []string, meta RequestMeta, basedir, envfile string, opts RequestOptions) (string, error) {
+	basedir, err := findCollectionDir(basedir, req.Host)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "[W] read env file: %s", err)
+		return "", fmt.Errorf("find collection dir error %w", err)
+	}
+
+	envPath := filepath.Join(basedir, envfile)
+	envs, err := EnvFromFile(envPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[W] read env file: %s\n", err)
+		envPath = ""
 	}
 
 	rd := RequestData{
@@ -105,72 +178,184 @@ func DoRequest(basedir, envfile string) error {
 		RawQuery: req.URL.RawQuery,
 		Env:      envs,
 		HTTPReq:  req,
-	}
+		Options:  opts,
 
-	bodyBytes, err := io.ReadAll(req.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read request body: %w", err)
+		HeaderOrder: headerOrder,
+		EnvFile:     envPath,
+		Meta:        meta,
 	}
-	rd.Body = string(bodyBytes)
 
-	if rd.Body != "" {
-		rd.BodyType, err = BodyTypeFromContentType(req.Header.Get("Content-Type"))
+	if req.Body != nil {
+		bodyBytes, err := io.ReadAll(req.Body)
 		if err != nil {
-			return fmt.Errorf("detect bodytype error %w", err)
+			return "", fmt.Errorf("failed to read request body: %w", err)
 		}
-	} else {
-		rd.BodyType = "none"
+		rd.Body = string(bodyBytes)
 	}
 
-	err = createRequestFile(rd)
-	if err != nil {
-		return fmt.Errorf("create request file error %w", err)
+	rd.GraphQL = DetectGraphQL(req, rd.Body)
+	switch {
+	case rd.GraphQL != nil:
+		rd.BodyType = "graphql"
+		rd.RawQuery = withoutGraphQLParams(rd.RawQuery)
+	default:
+		ct := req.Header.Get("Content-Type")
+		rd.BodyType = DetectBodyType(ct, rd.Body)
+		if bt, _ := BodyTypeFromContentType(ct); ct != "" && bt != rd.BodyType {
+			fmt.Fprintf(os.Stderr, "[W] Content-Type %q doesn't match the body, written as %s\n", ct, rd.BodyType)
+		}
 	}
 
-	// print request back for next processors
-	fmt.Print(string(rawReq))
+	fp, err := createRequestFile(rd)
+	if err != nil {
+		return "", fmt.Errorf("create request file error %w", err)
+	}
 
-	return nil
+	return fp, nil
 }
 
-func createRequestFile(rd RequestData) error {
+// createRequestFile writes the request into the matching folder
+// and returns the file path
+func createRequestFile(rd RequestData) (string, error) {
 	dir, tail := findRequestFolder(rd.Basedir, rd.Path)
+	if rd.Meta.Folder != "" {
+		var err error
+		dir, tail, err = metaFolder(rd.Basedir, rd.Meta.Folder, rd.Path)
+		if err != nil {
+			return "", err
+		}
+	}
+	rd.InheritedHeaders = InheritedHeaders(rd.Basedir, dir)
+	var learned Pairs
+	if rd.Options.Learn {
+		learned = LearnVars(rd)
+		envAddVars(rd.Env, learned)
+	}
 	tail = EnvToPath(tail, rd.Env)
 	name := pathToName(tail)
-	if name == "" {
+	switch {
+	case rd.Meta.Name != "":
+		rd.Name = requestFileName(rd.Meta.Name)
+	case rd.GraphQL != nil && rd.GraphQL.OperationName != "":
+		rd.Name = requestFileName(rd.GraphQL.OperationName)
+	case name == "":
 		rd.Name = rd.Method
-	} else {
+	default:
 		rd.Name = name + "-" + rd.Method
 	}
 	rd.FilesCount = DirFilesCount(dir)
-	rd.Body = EnvToBody(rd.Body, rd.Env)
 
-	content := requestContent(rd)
 	fp := filepath.Join(dir, rd.Name+".bru")
-
+	merge := false
 	if _, err := os.Stat(fp); err == nil {
-		return fmt.Errorf("file %q already exists", fp)
+		switch {
+		case rd.Options.Merge:
+			merge = true
+		case rd.Options.Suffix:
+			rd.Name, fp = nextFreeName(dir, rd.Name)
+		default:
+			return "", fmt.Errorf("file %q already exists", fp)
+		}
 	}
 
-	if err := os.WriteFile(fp, []byte(content), 0644); err != nil {
-		return fmt.Errorf("write request to file %q error %w", fp, err)
+	var target Pairs
+	if rd.EnvFile != "" {
+		target = targetVars(rd)
+	}
+	for _, v := range target {
+		rd.Env.Vars[v.Key] = v.Value
 	}
 
+	rd.Auth = DetectAuth(rd, InheritedAuth(rd.Basedir, dir))
+	if rd.Auth != nil && rd.EnvFile != "" {
+		envAddVars(rd.Env, rd.Auth.Vars)
+	}
+	if rd.BodyType == "multipartForm" {
+		parts, err := ParseMultipartParts(rd.Body)
+		if err != nil {
+			return "", err
+		}
+		rd.FormParts = parts
+		if err := saveFormFiles(rd.Basedir, rd.FormParts); err != nil {
+			return "", err
+		}
+		for i, p := range rd.FormParts {
+			if p.FileName == "" {
+				rd.FormParts[i].Value = EnvToBody(p.Value, rd.Env)
+			}
+		}
+	}
+	if rd.BodyType == "file" {
+		rd.FormParts = []FormPart{bodyFile(rd)}
+		if err := saveFormFiles(rd.Basedir, rd.FormParts); err != nil {
+			return "", err
+		}
+	} else {
+		rd.Body = EnvToBody(rd.Body, rd.Env)
+	}
+	if rd.GraphQL != nil {
+		rd.GraphQL.Variables = EnvToBody(rd.GraphQL.Variables, rd.Env)
+	}
+
+	content := requestContent(rd)
+	if merge {
+		if err := mergeRequestFile(fp, content); err != nil {
+			return "", fmt.Errorf("merge request to file %q error %w", fp, err)
+		}
+	} else if err := os.WriteFile(fp, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("write request to file %q error %w", fp, err)
+	}
+
+	// the env file is changed only after the request file is written,
+	// failed requests leave it untouched
+	if err := writeRequestEnvVars(rd, target, learned); err != nil {
+		return "", err
+	}
+
+	return fp, nil
+}
+
+// writeRequestEnvVars writes target, learned and auth vars
+// of the request to the env file
+func writeRequestEnvVars(rd RequestData, target, learned Pairs) error {
+	if rd.EnvFile == "" {
+		return nil
+	}
+	if len(target) > 0 {
+		if err := EnvSetVars(rd.EnvFile, target); err != nil {
+			return fmt.Errorf("write target vars error %w", err)
+		}
+		for _, v := range target {
+			fmt.Fprintf(os.Stderr, "[I] env var %s: %s\n", v.Key, v.Value)
+		}
+	}
+	if len(learned) > 0 {
+		if err := EnvSetVars(rd.EnvFile, learned); err != nil {
+			return fmt.Errorf("write learned vars error %w", err)
+		}
+		for _, v := range learned {
+			fmt.Fprintf(os.Stderr, "[I] learned env var: %s\n", v.Key)
+		}
+	}
+	createAuthVars(rd)
 	return nil
 }
 
 func requestContent(rd RequestData) string {
 	var sb strings.Builder
 
-	meta := make(map[string]string)
-	meta["name"] = rd.Name
-	meta["seq"] = strconv.Itoa(rd.FilesCount + 1)
-	meta["type"] = "http"
+	var meta Pairs
+	meta.Add("name", rd.Name)
+	if rd.GraphQL != nil {
+		meta.Add("type", "graphql")
+	} else {
+		meta.Add("type", "http")
+	}
+	meta.Add("seq", strconv.Itoa(rd.FilesCount+1))
 
-	sb.WriteString(MetaGenerate(meta))
+	sb.WriteString(MetaAddTags(MetaGenerate(meta), rd.Meta.Tags))
 	sb.WriteString("\n")
 
-	rvars := make(map[string]string)
 	path := rd.Path
 	if !strings.HasPrefix(path, "/") {
 		path = "/" + path
@@ -179,27 +364,63 @@ func requestContent(rd RequestData) string {
 	if !strings.HasPrefix(path, "/") {
 		path = "/" + path
 	}
-	if rd.RawQuery != "" {
-		query := EnvToBody(rd.RawQuery, rd.Env)
-		path += "?" + query
+	var pathParams []string
+	if rd.Options.PathParams {
+		path, pathParams = PathParams(path)
+	}
+	queryParams := QueryParamsEnv(ParseQuery(rd.RawQuery), rd.Env)
+	if len(queryParams) > 0 {
+		path += "?" + queryParams.String()
 	}
-	proto, host, envHost := "", "", ""
+	proto, host := "", urlHost(rd)
 	if rd.Env != nil {
 		proto = "{{proto}}"
-		host = "{{host}}"
-		envHost = rd.Env.Vars["host"]
 	}
-	if rd.HTTPReq != nil && envHost != "" && envHost != rd.HTTPReq.Host {
-		fmt.Fprintf(os.Stderr, "[W] host mismatched. in envs - %s, in request - %s", envHost, rd.HTTPReq.Host)
+	if rd.HTTPReq != nil && rd.Env != nil {
+		scheme := targetScheme(rd)
+		reqHost := rd.HTTPReq.Host
+		if host != "{{host}}" {
+			reqHost, _ = splitHostPort(reqHost, scheme)
+		}
+		if envHost := rd.Env.Vars["host"]; envHost != "" && envHost != reqHost {
+			fmt.Fprintf(os.Stderr, "[W] host mismatched. in envs - %s, in request - %s\n", envHost, reqHost)
+		}
+		if envProto := rd.Env.Vars["proto"]; envProto != "" && scheme != "" && envProto != scheme {
+			fmt.Fprintf(os.Stderr, "[W] proto mismatched. in envs - %s, in request - %s\n", envProto, scheme)
+		}
+	}
+	var rvars Pairs
+	rvars.Add("url", fmt.Sprintf("%s://%s%s", proto, host, path))
+	rvars.Add("body", rd.BodyType)
+	if rd.Auth != nil {
+		rvars.Add("auth", rd.Auth.Mode)
+	} else {
+		rvars.Add("auth", "none")
 	}
-	rvars["url"] = fmt.Sprintf("%s://%s%s", proto, host, path)
-	rvars["body"] = rd.BodyType
-	rvars["auth"] = "none"
 	sb.WriteString(NameBlockMap(strings.ToLower(rd.Method), rvars))
 	sb.WriteString("\n")
 
-	setts := make(map[string]string)
-	setts["encodeUrl"] = "false"
+	if len(queryParams) > 0 {
+		sb.WriteString(QueryParamsGenerate(queryParams.Pairs()))
+		sb.WriteString("\n")
+	}
+	if len(pathParams) > 0 {
+		sb.WriteString(PathParamsGenerate(pathParams))
+		sb.WriteString("\n")
+	}
+
+	if heads := RequestHeaders(rd); len(heads) > 0 {
+		sb.WriteString(HeadersGenerate(heads))
+		sb.WriteString("\n")
+	}
+
+	if auth := AuthGenerate(rd.Auth); auth != "" {
+		sb.WriteString(auth)
+		sb.WriteString("\n")
+	}
+
+	var setts Pairs
+	setts.Add("encodeUrl", "false")
 	sb.WriteString(NameBlockMap("settings", setts))
 	sb.WriteString("\n")
 
@@ -211,6 +432,17 @@ func requestContent(rd RequestData) string {
 		docs = append(docs, "- [ ] body params")
 	}
 
+	if len(rd.Meta.Note) > 0 {
+		docs = append(docs, "")
+		docs = append(docs, rd.Meta.Note...)
+	}
+
+	if rd.Meta.Example != "" {
+		docs = append(docs, "", "## Example response", "", "```")
+		docs = append(docs, strings.Split(rd.Meta.Example, "\n")...)
+		docs = append(docs, "```")
+	}
+
 	sb.WriteString(NameBlockStrings("docs", docs))
 
 	return sb.String()
@@ -223,6 +455,12 @@ func RequestBodyBlock(rd RequestData) string {
 	if rd.BodyType == "multipartForm" {
 		return RequestBodyMultipartForm(rd)
 	}
+	if rd.BodyType == "graphql" && rd.GraphQL != nil {
+		return RequestBodyGraphQL(rd.GraphQL)
+	}
+	if rd.BodyType == "file" {
+		return NameBlockMap("body:file", FormPartsPairs(rd.FormParts))
+	}
 	return NameBlockStrings("body:"+rd.BodyType, []string{rd.Body})
 }
 
@@ -234,31 +472,19 @@ func RequestBodyUrlEncoded(rd RequestData) string {
 
 func RequestBodyMultipartForm(rd RequestData) string {
 	name := "body:" + BodyTypeName(rd.BodyType)
-	vars := ParseBodyMultipartForm(rd.Body)
-	return NameBlockMap(name, vars)
-}
-
-// ParseBodyMultipartForm parse http multipart/form-data body to map
-func ParseBodyMultipartForm(body string) map[string]string {
-	result := make(map[string]string)
-	if body == "" {
-		return result
-	}
-
-	reader := multipart.NewReader(strings.NewReader(body), extractBoundary(body))
-	form, err := reader.ReadForm(10 << 20) // 10 MB max memory
-	if err != nil {
-		return result
-	}
-	defer form.RemoveAll()
-
-	for key, values := range form.Value {
-		if len(values) > 0 {
-			result[key] = values[0]
-		}
+	parts := rd.FormParts
+	if parts == nil {
+		parts, _ = ParseMultipartParts(rd.Body)
 	}
+	return NameBlockMap(name, FormPartsPairs(parts))
+}
 
-	return result
+// ParseBodyMultipartForm parse http multipart/form-data body
+// to fields in original order, file parts are referenced
+// as @file(files/name)
+func ParseBodyMultipartForm(body string) Pairs {
+	parts, _ := ParseMultipartParts(body)
+	return FormPartsPairs(parts)
 }
 
 // extractBoundary extracts the boundary string from a multipart body
@@ -276,9 +502,10 @@ func extractBoundary(body string) string {
 	return ""
 }
 
-// ParseBodyUrlEncoded parse http x-www-form-urlencoded body to map
-func ParseBodyUrlEncoded(body string) map[string]string {
-	result := make(map[string]string)
+// ParseBodyUrlEncoded parse http x-www-form-urlencoded body
+// to fields in original order
+func ParseBodyUrlEncoded(body string) Pairs {
+	var result Pairs
 	if body == "" {
 		return result
 	}
@@ -288,15 +515,23 @@ func ParseBodyUrlEncoded(body string) map[string]string {
 		if len(parts) == 2 {
 			key, _ := url.QueryUnescape(parts[0])
 			value, _ := url.QueryUnescape(parts[1])
-			result[key] = value
+			result.Add(key, value)
 		} else if len(parts) == 1 {
 			key, _ := url.QueryUnescape(parts[0])
-			result[key] = ""
+			result.Add(key, "")
 		}
 	}
 	return result
 }
 
+var fileNameReservedRe = regexp.MustCompile(`[/\\:*?"<>|]+`)
+
+// requestFileName replaces characters reserved in file names with "-",
+// e.g. "Get user/{id}" -> "Get user-{id}"
+func requestFileName(name string) string {
+	return strings.TrimSpace(fileNameReservedRe.ReplaceAllString(name, "-"))
+}
+
 // pathToName convert path in format
 // api/users/{{user_id}} to filename format api-users-USER_ID
 // variables if present converts to uppercase keys
@@ -328,13 +563,20 @@ func findCollectionDir(basedir, host string) (string, error) {
 		return basedir, nil
 	}
 
-	hostDir := filepath.Join(basedir, strings.ToLower(host))
-	if info, err := os.Stat(hostDir); err == nil && info.IsDir() {
-		brunoJSON = filepath.Join(hostDir, "bruno.json")
-		if _, err := os.Stat(brunoJSON); err == nil {
-			return hostDir, nil
+	// collection of host:port, then of the host without port
+	names := []string{strings.ToLower(host)}
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		names = append(names, strings.ToLower(name))
+	}
+	for _, name := range names {
+		hostDir := filepath.Join(basedir, name)
+		if info, err := os.Stat(hostDir); err == nil && info.IsDir() {
+			brunoJSON = filepath.Join(hostDir, "bruno.json")
+			if _, err := os.Stat(brunoJSON); err == nil {
+				return hostDir, nil
+			}
+			return "", fmt.Errorf("collection dir %q found but missing bruno.json", hostDir)
 		}
-		return "", fmt.Errorf("collection dir %q found but missing bruno.json", hostDir)
 	}
 
 	return "", fmt.Errorf("collection not found: no bruno.json in %q and no %q subfolder", basedir, strings.ToLower(host))
@@ -396,11 +638,40 @@ func findRequestFolder(basedir string, path string) (string, string) {
 	return basedir, path
 }
 
+// ParseRequest detects the input format (curl command line or raw HTTP request)
+// and returns the parsed request with header names in source order.
+func ParseRequest(raw []byte) (*http.Request, []string, error) {
+	return parseRequest(raw, false)
+}
+
+// parseRequest parses curl command or raw request, remote curl
+// commands can't read local files
+func parseRequest(raw []byte, remote bool) (*http.Request, []string, error) {
+	if IsCurlCommand(raw) {
+		req, order, err := parseCurlCommand(string(raw), remote)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := applyMetaTarget(req, RawMetaLines(raw)); err != nil {
+			return nil, nil, err
+		}
+		return req, order, nil
+	}
+
+	req, err := ParseRawRequest(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	return req, HeaderOrder(raw), nil
+}
+
 // ParseRawRequest takes a raw HTTP request as []byte and returns a parsed *http.Request and error.
+// HTTP/2 and HTTP/3 requests with pseudo-headers are accepted too.
 func ParseRawRequest(rawRequest []byte) (*http.Request, error) {
 	reader := bufio.NewReader(bytes.NewReader(rawRequest))
 
 	// Skip lines starting with '#' (meta lines)
+	var meta []string
 	for {
 		line, err := reader.Peek(1)
 		if err != nil {
@@ -409,16 +680,20 @@ func ParseRawRequest(rawRequest []byte) (*http.Request, error) {
 		if line[0] != '#' {
 			break
 		}
-		// Read and discard the entire line
-		_, _, err = reader.ReadLine()
+		// Read the entire line
+		text, _, err := reader.ReadLine()
 		if err != nil {
 			return nil, err
 		}
+		meta = append(meta, string(text))
 	}
 
-	req, err := http.ReadRequest(reader)
+	req, err := readRequest(reader, true)
 	if err != nil {
 		return nil, err
 	}
+	if err := applyMetaTarget(req, meta); err != nil {
+		return nil, err
+	}
 	return req, nil
 }
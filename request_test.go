@@ -1,6 +1,8 @@
 package main
 
 import (
+	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -49,9 +51,16 @@ func TestBodyTypeFromContentType(t *testing.T) {
 		{"multipart/form-data returns multipartForm", "multipart/form-data", "multipartForm", false},
 		{"multipart/form-data with boundary", "multipart/form-data; boundary=----WebKitFormBoundary", "multipartForm", false},
 		{"application/x-www-form-urlencoded returns formUrlEncoded", "application/x-www-form-urlencoded", "formUrlEncoded", false},
-		{"unsupported content type returns error", "application/octet-stream", "", true},
+		{"json suffix returns json", "application/vnd.api+json", "json", false},
+		{"problem+json returns json", "application/problem+json; charset=utf-8", "json", false},
+		{"merge-patch+json returns json", "application/merge-patch+json", "json", false},
+		{"xml suffix returns xml", "application/soap+xml; charset=utf-8", "xml", false},
+		{"text/csv returns text", "text/csv", "text", false},
+		{"text/html returns text", "text/html", "text", false},
+		{"application/javascript returns text", "application/javascript", "text", false},
+		{"application/octet-stream returns file", "application/octet-stream", "file", false},
+		{"image returns file", "image/png", "file", false},
 		{"invalid content type returns error", "invalid/;/type", "", true},
-		{"text/html unsupported", "text/html", "", true},
 	}
 
 	for _, tt := range tests {
@@ -72,52 +81,57 @@ func TestParseBodyUrlEncoded(t *testing.T) {
 	tests := []struct {
 		name     string
 		body     string
-		expected map[string]string
+		expected Pairs
 	}{
 		{
-			name:     "empty body returns empty map",
+			name:     "empty body returns no fields",
 			body:     "",
-			expected: map[string]string{},
+			expected: nil,
 		},
 		{
 			name:     "single key-value pair",
 			body:     "key=value",
-			expected: map[string]string{"key": "value"},
+			expected: Pairs{{"key", "value"}},
 		},
 		{
 			name:     "multiple key-value pairs",
 			body:     "key1=value1&key2=value2",
-			expected: map[string]string{"key1": "value1", "key2": "value2"},
+			expected: Pairs{{"key1", "value1"}, {"key2", "value2"}},
 		},
 		{
 			name:     "URL encoded values",
 			body:     "name=John%20Doe&email=john%40example.com",
-			expected: map[string]string{"name": "John Doe", "email": "john@example.com"},
+			expected: Pairs{{"name", "John Doe"}, {"email", "john@example.com"}},
 		},
 		{
 			name:     "key without value",
 			body:     "key",
-			expected: map[string]string{"key": ""},
+			expected: Pairs{{"key", ""}},
 		},
 		{
 			name:     "key with empty value",
 			body:     "key=",
-			expected: map[string]string{"key": ""},
+			expected: Pairs{{"key", ""}},
 		},
 		{
 			name:     "multiple keys some without values",
 			body:     "key1=value1&key2&key3=value3",
-			expected: map[string]string{"key1": "value1", "key2": "", "key3": "value3"},
+			expected: Pairs{{"key1", "value1"}, {"key2", ""}, {"key3", "value3"}},
 		},
 		{
 			name:     "value with equals sign",
 			body:     "equation=1%2B1=2",
-			expected: map[string]string{"equation": "1+1=2"},
+			expected: Pairs{{"equation", "1+1=2"}},
+		},
+		{
+			name:     "repeated keys keep order",
+			body:     "b=1&a=2&b=3",
+			expected: Pairs{{"b", "1"}, {"a", "2"}, {"b", "3"}},
 		},
 		{
 			name:     "special characters encoded",
 			body:     "data=%7B%22id%22%3A1%7D",
-			expected: map[string]string{"data": `{"id":1}`},
+			expected: Pairs{{"data", `{"id":1}`}},
 		},
 	}
 
@@ -188,12 +202,12 @@ func TestParseBodyMultipartForm(t *testing.T) {
 	tests := []struct {
 		name     string
 		body     string
-		expected map[string]string
+		expected Pairs
 	}{
 		{
-			name:     "empty body returns empty map",
+			name:     "empty body returns no fields",
 			body:     "",
-			expected: map[string]string{},
+			expected: nil,
 		},
 		{
 			name: "single field",
@@ -201,7 +215,7 @@ func TestParseBodyMultipartForm(t *testing.T) {
 				"Content-Disposition: form-data; name=\"field1\"\r\n\r\n" +
 				"value1\r\n" +
 				"--boundary--\r\n",
-			expected: map[string]string{"field1": "value1"},
+			expected: Pairs{{"field1", "value1"}},
 		},
 		{
 			name: "multiple fields",
@@ -212,7 +226,25 @@ func TestParseBodyMultipartForm(t *testing.T) {
 				"Content-Disposition: form-data; name=\"field2\"\r\n\r\n" +
 				"value2\r\n" +
 				"--boundary--\r\n",
-			expected: map[string]string{"field1": "value1", "field2": "value2"},
+			expected: Pairs{{"field1", "value1"}, {"field2", "value2"}},
+		},
+		{
+			name: "fields keep order and file parts are referenced",
+			body: "--boundary\r\n" +
+				"Content-Disposition: form-data; name=\"z\"\r\n\r\n" +
+				"1\r\n" +
+				"--boundary\r\n" +
+				"Content-Disposition: form-data; name=\"upload\"; filename=\"a.txt\"\r\n" +
+				"Content-Type: text/plain\r\n\r\n" +
+				"file content\r\n" +
+				"--boundary\r\n" +
+				"Content-Disposition: form-data; name=\"a\"\r\n\r\n" +
+				"2\r\n" +
+				"--boundary\r\n" +
+				"Content-Disposition: form-data; name=\"z\"\r\n\r\n" +
+				"3\r\n" +
+				"--boundary--\r\n",
+			expected: Pairs{{"z", "1"}, {"upload", "@file(files/a.txt) @contentType(text/plain)"}, {"a", "2"}, {"z", "3"}},
 		},
 		{
 			name: "field with special characters in value",
@@ -220,7 +252,7 @@ func TestParseBodyMultipartForm(t *testing.T) {
 				"Content-Disposition: form-data; name=\"json\"\r\n\r\n" +
 				"{\"key\": \"value\"}\r\n" +
 				"--boundary--\r\n",
-			expected: map[string]string{"json": "{\"key\": \"value\"}"},
+			expected: Pairs{{"json", "{\"key\": \"value\"}"}},
 		},
 	}
 
@@ -795,6 +827,85 @@ func TestRequestContent(t *testing.T) {
 				"url: {{proto}}://{{host}}/api/search?user_id={{user_id}}&account={{account_id}}",
 			},
 		},
+		{
+			name: "query params block keeps order and duplicates",
+			rd: RequestData{
+				Name:     "filter",
+				Method:   "GET",
+				Path:     "/api/items",
+				RawQuery: "tag=a&tag=b&empty=&user=123",
+				BodyType: "none",
+				Env: &BrunoEnv{
+					Vars:        map[string]string{"proto": "https", "host": "example.com", "user_id": "123"},
+					ReverseVars: map[string]string{"123": "user_id"},
+				},
+			},
+			contains: []string{
+				"url: {{proto}}://{{host}}/api/items?tag=a&tag=b&empty=&user={{user_id}}",
+				"params:query {\n  tag: a\n  tag: b\n  empty: \n  user: {{user_id}}\n}",
+			},
+		},
+		{
+			name: "query keys without value",
+			rd: RequestData{
+				Name:     "flag",
+				Method:   "GET",
+				Path:     "/items/5",
+				RawQuery: "flag&empty=",
+				BodyType: "none",
+				Env: &BrunoEnv{
+					Vars: map[string]string{"proto": "https", "host": "example.com"},
+				},
+			},
+			contains: []string{
+				"url: {{proto}}://{{host}}/items/5?flag&empty=",
+				"params:query {\n  flag: \n  empty: \n}",
+			},
+		},
+		{
+			name: "path params enabled",
+			rd: RequestData{
+				Name:     "get-user",
+				Method:   "GET",
+				Path:     "/users/123",
+				BodyType: "none",
+				Options:  RequestOptions{PathParams: true},
+				Env: &BrunoEnv{
+					Vars:        map[string]string{"proto": "https", "host": "example.com", "user_id": "123"},
+					ReverseVars: map[string]string{"123": "user_id"},
+				},
+			},
+			contains: []string{
+				"url: {{proto}}://{{host}}/users/:user_id",
+				"params:path {\n  user_id: {{user_id}}\n}",
+			},
+		},
+		{
+			name: "request with captured headers",
+			rd: RequestData{
+				Name:     "headers",
+				Method:   "GET",
+				Path:     "/api/me",
+				BodyType: "none",
+				HTTPReq: &http.Request{
+					Host: "example.com",
+					Header: http.Header{
+						"Accept":          {"application/json"},
+						"Accept-Encoding": {"gzip"},
+						"User-Agent":      {"curl/8.0"},
+					},
+				},
+				InheritedHeaders: map[string]string{"User-Agent": "{{ua}}"},
+				Options:          RequestOptions{SkipHeaders: DefaultSkipHeaders},
+				Env: &BrunoEnv{
+					Vars: map[string]string{"proto": "https", "host": "example.com"},
+				},
+			},
+			contains: []string{
+				"headers {",
+				"  Accept: application/json",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -809,6 +920,75 @@ func TestRequestContent(t *testing.T) {
 	}
 }
 
+func TestRequestContentGolden(t *testing.T) {
+	rd := RequestData{
+		Name:     "api-users-POST",
+		Method:   "POST",
+		Path:     "/api/users",
+		RawQuery: "b=2&a=1",
+		BodyType: "formUrlEncoded",
+		Body:     "z=1&a=2",
+		HTTPReq: &http.Request{
+			Host: "example.com",
+			Header: http.Header{
+				"X-B":          {"b"},
+				"X-A":          {"a"},
+				"Content-Type": {"application/x-www-form-urlencoded"},
+			},
+		},
+		HeaderOrder: []string{"X-B", "Content-Type", "X-A"},
+		Env: &BrunoEnv{
+			Vars: map[string]string{"proto": "https", "host": "example.com"},
+		},
+	}
+
+	expected := `meta {
+  name: api-users-POST
+  type: http
+  seq: 1
+}
+
+post {
+  url: {{proto}}://{{host}}/api/users?b=2&a=1
+  body: formUrlEncoded
+  auth: none
+}
+
+params:query {
+  b: 2
+  a: 1
+}
+
+headers {
+  X-B: b
+  Content-Type: application/x-www-form-urlencoded
+  X-A: a
+}
+
+settings {
+  encodeUrl: false
+}
+
+body:form-urlencoded {
+  z: 1
+  a: 2
+}
+
+docs {
+  - [ ] methods
+  - [ ] params
+  - [ ] headers
+  - [ ] body params
+}
+`
+
+	for i := 0; i < 10; i++ {
+		if got := requestContent(rd); got != expected {
+			t.Fatalf("requestContent() =\n%s\nwant:\n%s", got, expected)
+		}
+	}
+}
+
 // Helper function to check if a string contains a substring
 func containsString(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsSubstring(s, substr))
@@ -1150,7 +1330,7 @@ func TestCreateRequestFileWithBasedir(t *testing.T) {
 			rd.Basedir = basedir
 
 			// Run createRequestFile
-			err := createRequestFile(rd)
+			_, err := createRequestFile(rd)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("createRequestFile() error = %v, wantErr %v", err, tt.wantErr)
@@ -1179,6 +1359,51 @@ func TestCreateRequestFileWithBasedir(t *testing.T) {
 	}
 }
 
+func TestCreateRequestFileSkipsInheritedHeaders(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "api"), 0o755)
+	os.WriteFile(filepath.Join(tmpDir, "collection.bru"), []byte(DefaultCollectionBru()), 0o644)
+	os.WriteFile(filepath.Join(tmpDir, "api", "folder.bru"), []byte(DefaultFolderBru("api")), 0o644)
+
+	req, err := ParseRawRequest([]byte("GET /api/users HTTP/1.1\r\n" +
+		"Host: example.com\r\n" +
+		"User-Agent: Mozilla/5.0\r\n" +
+		"Cookie: session=abc\r\n" +
+		"Accept: application/json\r\n" +
+		"Accept-Encoding: gzip\r\n" +
+		"Connection: keep-alive\r\n\r\n"))
+	if err != nil {
+		t.Fatalf("ParseRawRequest() error = %v", err)
+	}
+
+	rd := RequestData{
+		Basedir:  tmpDir,
+		Method:   req.Method,
+		Path:     req.URL.Path,
+		BodyType: "none",
+		HTTPReq:  req,
+		Options:  RequestOptions{SkipHeaders: DefaultSkipHeaders},
+	}
+	if _, err := createRequestFile(rd); err != nil {
+		t.Fatalf("createRequestFile() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, "api", "users-GET.bru"))
+	if err != nil {
+		t.Fatalf("read request file error = %v", err)
+	}
+	content := string(data)
+
+	if !containsString(content, "  Accept: application/json") {
+		t.Errorf("request file should contain Accept header\ngot:\n%s", content)
+	}
+	for _, unwanted := range []string{"User-Agent", "Cookie", "Accept-Encoding", "Connection", "Host:"} {
+		if containsString(content, unwanted) {
+			t.Errorf("request file should not contain %q\ngot:\n%s", unwanted, content)
+		}
+	}
+}
+
 func TestCreateRequestFileBasedirNotSet(t *testing.T) {
 	// This test documents the current buggy behavior where if Basedir is not set,
 	// the file is created in current directory instead of the intended basedir.
@@ -1205,7 +1430,7 @@ func TestCreateRequestFileBasedirNotSet(t *testing.T) {
 	os.Chdir(tmpDir)
 	defer os.Chdir(origDir)
 
-	err := createRequestFile(rd)
+	_, err := createRequestFile(rd)
 	if err != nil {
 		t.Fatalf("createRequestFile() error = %v", err)
 	}
@@ -1275,7 +1500,7 @@ func TestDoRequestPassesBasedirToRequestData(t *testing.T) {
 	defer os.Chdir(origDir)
 
 	// Call DoRequest with basedir
-	err := DoRequest(basedir, "environments/base.bru")
+	err := DoRequest(basedir, "environments/base.bru", RequestOptions{})
 	if err != nil {
 		t.Fatalf("DoRequest() error = %v", err)
 	}
@@ -1291,3 +1516,28 @@ func TestDoRequestPassesBasedirToRequestData(t *testing.T) {
 		t.Errorf("File was created in current directory %q instead of basedir", wrongInCurrentDir)
 	}
 }
+
+func TestCreateRequestFileFailureKeepsEnv(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := DoCollection("api.example.com", tmpDir); err != nil {
+		t.Fatalf("DoCollection() error = %v", err)
+	}
+	collDir := filepath.Join(tmpDir, "api.example.com")
+	envPath := filepath.Join(collDir, "environments", "base.bru")
+	before, _ := os.ReadFile(envPath)
+	// files dir can't be created, saving the body file fails
+	os.WriteFile(filepath.Join(collDir, "files"), nil, 0o644)
+
+	body := "\x00\x01"
+	raw := fmt.Sprintf("PUT /blob HTTP/1.1\r\nHost: api.example.com:8443\r\nAuthorization: Bearer abcdef\r\nContent-Type: application/octet-stream\r\nContent-Length: %d\r\n\r\n%s", len(body), body)
+	req, order, err := ParseRequest([]byte(raw))
+	if err != nil {
+		t.Fatalf("ParseRequest() error = %v", err)
+	}
+	if _, err := ConvertRequest(req, order, tmpDir, "environments/base.bru", RequestOptions{Scheme: "https"}); err == nil {
+		t.Fatal("ConvertRequest() expected error")
+	}
+	if after, _ := os.ReadFile(envPath); string(after) != string(before) {
+		t.Errorf("env file changed by failed request:\n%s", after)
+	}
+}
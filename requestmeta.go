@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// metaDirectiveRe matches `# key: value` meta lines
+var metaDirectiveRe = regexp.MustCompile(`^#\s*([A-Za-z][\w-]*)\s*:\s*(.*)$`)
+
+// ParseRequestMeta parses meta directives of `#` lines before the request:
+//
+//	# url: https://api.example.com:8443/x   target URL
+//	# name: login                           request name
+//	# folder: auth                          folder in the collection
+//	# tags: smoke, auth                     meta tags
+//	# note: text                            docs text, repeatable
+//
+// Other lines are comments. The scheme directive is handled by MetaScheme.
+func ParseRequestMeta(lines []string) RequestMeta {
+	var meta RequestMeta
+	for _, line := range lines {
+		m := metaDirectiveRe.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		value := strings.TrimSpace(m[2])
+		switch strings.ToLower(m[1]) {
+		case "url":
+			meta.URL = value
+		case "name":
+			meta.Name = value
+		case "folder":
+			meta.Folder = strings.Trim(value, "/")
+		case "tags":
+			meta.Tags = append(meta.Tags, strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' })...)
+		case "note":
+			meta.Note = append(meta.Note, value)
+		}
+	}
+	return meta
+}
+
+// RawMetaLines returns leading `#` lines of the raw request,
+// blank lines between them are skipped
+func RawMetaLines(raw []byte) []string {
+	var lines []string
+	for _, line := range strings.Split(string(raw), "\n") {
+		line = strings.TrimRight(line, "\r")
+		switch {
+		case strings.HasPrefix(line, "#"):
+			lines = append(lines, line)
+		case strings.TrimSpace(line) != "":
+			return lines
+		}
+	}
+	return lines
+}
+
+// applyMetaTarget sets the target of the request from `#` meta lines:
+// the url directive overrides scheme and host, and path and query if
+// the url has a path; the scheme directive sets the scheme if the
+// request line doesn't define it.
+func applyMetaTarget(req *http.Request, lines []string) error {
+	if target := ParseRequestMeta(lines).URL; target != "" {
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid meta url %q", target)
+		}
+		req.URL.Scheme = u.Scheme
+		req.URL.Host = u.Host
+		req.Host = u.Host
+		if u.Path != "" {
+			req.URL.Path = u.Path
+			req.URL.RawPath = u.RawPath
+			req.URL.RawQuery = u.RawQuery
+		}
+		return nil
+	}
+
+	if req.URL.Scheme != "" {
+		return nil
+	}
+	if scheme := MetaScheme(lines); scheme != "" {
+		req.URL.Scheme = scheme
+		req.URL.Host = req.Host
+	}
+	return nil
+}
+
+// metaFolder returns the folder dir set by the folder directive, it's
+// created if missing, and the request path relative to the folder
+func metaFolder(basedir, folder, path string) (string, string, error) {
+	folder = filepath.Clean(folder)
+	if filepath.IsAbs(folder) || folder == ".." || strings.HasPrefix(folder, "../") {
+		return "", "", fmt.Errorf("meta folder %q is outside of the collection", folder)
+	}
+
+	// unlike DoFolder the created folder.bru has no default headers,
+	// they would hide the captured Authorization and Cookie
+	dir := filepath.Join(basedir, folder)
+	fp := filepath.Join(dir, "folder.bru")
+	if _, err := os.Stat(fp); err != nil {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return "", "", fmt.Errorf("error creating directory: %v", err)
+		}
+		var meta Pairs
+		meta.Add("name", filepath.Base(folder))
+		if err := os.WriteFile(fp, []byte(MetaGenerate(meta)), 0o644); err != nil {
+			return "", "", fmt.Errorf("error creating folder.bru: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "[I] created folder %s\n", dir)
+	}
+
+	tail := strings.Trim(path, "/")
+	if rest, ok := strings.CutPrefix(tail, filepath.ToSlash(folder)+"/"); ok {
+		tail = rest
+	} else if tail == filepath.ToSlash(folder) {
+		tail = ""
+	}
+	return dir, tail, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRequestMeta(t *testing.T) {
+	lines := RawMetaLines([]byte("# captured by proxy\n\n# url: https://api.example.com:8443/v2/login?x=1\n# Name: login\n" +
+		"# folder: /auth/\n# tags: smoke, auth regression\n# note: first\n# note: second\nPOST /login HTTP/1.1\n# not meta\n"))
+	got := ParseRequestMeta(lines)
+	want := RequestMeta{
+		URL:    "https://api.example.com:8443/v2/login?x=1",
+		Name:   "login",
+		Folder: "auth",
+		Tags:   []string{"smoke", "auth", "regression"},
+		Note:   []string{"first", "second"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseRequestMeta() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseRawRequestMetaURL(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		url  string
+		host string
+	}{
+		{
+			name: "url with path",
+			raw:  "# url: https://api.example.com:8443/v2/x?a=1\nGET /x HTTP/1.1\r\nHost: localhost\r\n\r\n",
+			url:  "https://api.example.com:8443/v2/x?a=1",
+			host: "api.example.com:8443",
+		},
+		{
+			name: "url without path keeps request path",
+			raw:  "# url: http://api.example.com\nGET /x?b=2 HTTP/1.1\r\nHost: localhost\r\n\r\n",
+			url:  "http://api.example.com/x?b=2",
+			host: "api.example.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := ParseRawRequest([]byte(tt.raw))
+			if err != nil {
+				t.Fatalf("ParseRawRequest() error = %v", err)
+			}
+			if req.URL.String() != tt.url || req.Host != tt.host {
+				t.Errorf("request = %s host %s", req.URL, req.Host)
+			}
+		})
+	}
+
+	if _, err := ParseRawRequest([]byte("# url: ftp://a.com/\nGET / HTTP/1.1\r\nHost: a.com\r\n\r\n")); err == nil {
+		t.Error("ParseRawRequest() expected error for ftp meta url")
+	}
+}
+
+func TestConvertRequestMeta(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := DoCollection("api.example.com", tmpDir); err != nil {
+		t.Fatalf("DoCollection() error = %v", err)
+	}
+
+	input := "# url: https://api.example.com/v1/sessions\n# name: Login\n# folder: auth/v1\n# tags: smoke\n# note: Creates a session.\n" +
+		"POST /login HTTP/1.1\r\nHost: localhost\r\nContent-Type: application/json\r\nContent-Length: 2\r\n\r\n{}" +
+		"# folder: ../outside\nGET /x HTTP/1.1\r\nHost: api.example.com\r\n\r\n"
+	var out bytes.Buffer
+	if err := DoRequestStream(strings.NewReader(input), &out, tmpDir, "environments/base.bru", RequestOptions{}); err != nil {
+		t.Fatalf("DoRequestStream() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, "api.example.com", "auth", "v1", "Login.bru"))
+	if err != nil {
+		t.Fatalf("request file was not created in the meta folder: %v", err)
+	}
+	for _, want := range []string{
+		"name: Login", "tags: [\n    smoke\n  ]", "url: {{proto}}://{{host}}/v1/sessions",
+		"- [ ] body params\n\n  Creates a session.",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("request file should contain %q\ngot:\n%s", want, data)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "api.example.com", "auth", "v1", "folder.bru")); err != nil {
+		t.Error("meta folder was not created")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "outside")); err == nil {
+		t.Error("folder outside of the collection was created")
+	}
+}
+
+func TestConvertRequestMetaFolderKeepsAuth(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := DoCollection("api.example.com", tmpDir); err != nil {
+		t.Fatalf("DoCollection() error = %v", err)
+	}
+
+	input := "# folder: auth\nGET /me HTTP/1.1\r\nHost: api.example.com\r\nAuthorization: Bearer secrettoken123\r\nCookie: sid=abc\r\n\r\n"
+	var out bytes.Buffer
+	if err := DoRequestStream(strings.NewReader(input), &out, tmpDir, "environments/base.bru", RequestOptions{}); err != nil {
+		t.Fatalf("DoRequestStream() error = %v", err)
+	}
+
+	collDir := filepath.Join(tmpDir, "api.example.com")
+	folder, _ := os.ReadFile(filepath.Join(collDir, "auth", "folder.bru"))
+	if strings.Contains(string(folder), "headers") {
+		t.Errorf("created folder.bru should have no headers\ngot:\n%s", folder)
+	}
+	data, err := os.ReadFile(filepath.Join(collDir, "auth", "me-GET.bru"))
+	if err != nil {
+		t.Fatalf("request file was not created: %v", err)
+	}
+	for _, want := range []string{"auth: bearer", "Cookie: sid=abc"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("request file should contain %q\ngot:\n%s", want, data)
+		}
+	}
+	env, _ := os.ReadFile(filepath.Join(collDir, "environments", "base.bru"))
+	if !strings.Contains(string(env), "secrettoken123") {
+		t.Errorf("env should contain the bearer token\ngot:\n%s", env)
+	}
+}
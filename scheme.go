@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// metaSchemeRe matches `# scheme: https` meta lines and lines with
+// the target URL like `# https://api.example.com` or `# Target: http://...`
+var metaSchemeRe = regexp.MustCompile(`(?i)^#\s*(?:(?:scheme|proto)\s*:\s*(https?)\s*$|(?:[\w-]+\s*:\s*)?(https?)://)`)
+
+// MetaScheme returns the scheme set by `#` meta lines of the raw request
+func MetaScheme(lines []string) string {
+	for _, line := range lines {
+		m := metaSchemeRe.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		return strings.ToLower(m[1] + m[2])
+	}
+	return ""
+}
+
+// RequestScheme returns the scheme of the captured request: the URL
+// scheme (absolute-form request line, :scheme, meta line, HAR url),
+// X-Forwarded-Proto header or fallback
+func RequestScheme(req *http.Request, fallback string) string {
+	if req.URL != nil && req.URL.Scheme != "" {
+		return strings.ToLower(req.URL.Scheme)
+	}
+	proto, _, _ := strings.Cut(req.Header.Get("X-Forwarded-Proto"), ",")
+	proto = strings.ToLower(strings.TrimSpace(proto))
+	if proto == "http" || proto == "https" {
+		return proto
+	}
+	return strings.ToLower(fallback)
+}
+
+// splitHostPort returns the host name and the port of host,
+// the port is empty if it's missing or default for the scheme
+func splitHostPort(host, scheme string) (string, string) {
+	name, port, err := net.SplitHostPort(host)
+	if err != nil {
+		return host, ""
+	}
+	if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		return name, ""
+	}
+	return name, port
+}
+
+// targetVars returns env vars describing the request target which are
+// missing from the env: proto of the detected scheme and, with PortVar,
+// the port var. The host var is shared by requests of the collection,
+// it's never changed.
+func targetVars(rd RequestData) Pairs {
+	if rd.Env == nil || rd.HTTPReq == nil {
+		return nil
+	}
+	var vars Pairs
+	scheme := RequestScheme(rd.HTTPReq, rd.Options.Scheme)
+	if _, ok := rd.Env.Vars["proto"]; !ok && scheme != "" {
+		vars.Add("proto", scheme)
+	}
+
+	if urlHost(rd) == "{{host}}:{{port}}" {
+		_, port := splitHostPort(rd.HTTPReq.Host, targetScheme(rd))
+		if _, ok := rd.Env.Vars["port"]; !ok {
+			vars.Add("port", port)
+		}
+	}
+	return vars
+}
+
+// urlHost returns the host part of the request url. A non default port
+// is written to the url, {{host}}:8443, or with PortVar to the port var,
+// unless the host var has the same port.
+func urlHost(rd RequestData) string {
+	if rd.Env == nil {
+		return ""
+	}
+	if rd.HTTPReq == nil {
+		return "{{host}}"
+	}
+	_, port := splitHostPort(rd.HTTPReq.Host, targetScheme(rd))
+	if port == "" || strings.EqualFold(rd.Env.Vars["host"], rd.HTTPReq.Host) {
+		return "{{host}}"
+	}
+	if rd.Options.PortVar {
+		return "{{host}}:{{port}}"
+	}
+	return "{{host}}:" + port
+}
+
+// targetScheme returns the request scheme, proto of the env
+// if the request doesn't define it
+func targetScheme(rd RequestData) string {
+	if scheme := RequestScheme(rd.HTTPReq, rd.Options.Scheme); scheme != "" {
+		return scheme
+	}
+	return rd.Env.Vars["proto"]
+}
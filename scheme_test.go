@@ -0,0 +1,186 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMetaScheme(t *testing.T) {
+	tests := []struct {
+		lines []string
+		want  string
+	}{
+		{[]string{"# scheme: HTTP"}, "http"},
+		{[]string{"# captured 1", "# https://api.example.com:8443/x"}, "https"},
+		{[]string{"# Target: http://api.example.com"}, "http"},
+		{[]string{"# note: see https"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := MetaScheme(tt.lines); got != tt.want {
+			t.Errorf("MetaScheme(%q) = %q, want %q", tt.lines, got, tt.want)
+		}
+	}
+}
+
+func TestRequestScheme(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://a.com/", nil)
+	if got := RequestScheme(req, "https"); got != "http" {
+		t.Errorf("url scheme = %q", got)
+	}
+
+	req, _ = ParseRawRequest([]byte("GET /x HTTP/1.1\r\nHost: a.com\r\nX-Forwarded-Proto: https, http\r\n\r\n"))
+	if got := RequestScheme(req, "http"); got != "https" {
+		t.Errorf("X-Forwarded-Proto scheme = %q", got)
+	}
+
+	req, _ = ParseRawRequest([]byte("GET /x HTTP/1.1\r\nHost: a.com\r\n\r\n"))
+	if got := RequestScheme(req, "http"); got != "http" {
+		t.Errorf("fallback scheme = %q", got)
+	}
+
+	req, _ = ParseRawRequest([]byte("GET http://a.com:8080/x HTTP/1.1\r\nHost: a.com:8080\r\n\r\n"))
+	if got := RequestScheme(req, ""); got != "http" {
+		t.Errorf("absolute-form scheme = %q", got)
+	}
+
+	req, _ = ParseRawRequest([]byte("# scheme: http\nGET /x HTTP/1.1\r\nHost: a.com\r\n\r\n"))
+	if got := RequestScheme(req, ""); got != "http" {
+		t.Errorf("meta scheme = %q", got)
+	}
+}
+
+func TestSplitHostPort(t *testing.T) {
+	tests := []struct {
+		host, scheme, name, port string
+	}{
+		{"a.com", "https", "a.com", ""},
+		{"a.com:443", "https", "a.com", ""},
+		{"a.com:80", "http", "a.com", ""},
+		{"a.com:8443", "https", "a.com", "8443"},
+		{"a.com:443", "http", "a.com", "443"},
+		{"[::1]:8080", "", "::1", "8080"},
+	}
+	for _, tt := range tests {
+		name, port := splitHostPort(tt.host, tt.scheme)
+		if name != tt.name || port != tt.port {
+			t.Errorf("splitHostPort(%q, %q) = %q, %q", tt.host, tt.scheme, name, port)
+		}
+	}
+}
+
+func TestConvertRequestTarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		opts    RequestOptions
+		url     string
+		envVars map[string]string
+	}{
+		{
+			name:    "port in url",
+			raw:     "GET /x HTTP/1.1\r\nHost: api.local:8443\r\n\r\n",
+			url:     "url: {{proto}}://{{host}}:8443/x",
+			envVars: map[string]string{"host": "api.local", "proto": "http"},
+		},
+		{
+			name:    "port var",
+			raw:     "GET /x HTTP/1.1\r\nHost: api.local:8443\r\n\r\n",
+			opts:    RequestOptions{PortVar: true},
+			url:     "url: {{proto}}://{{host}}:{{port}}/x",
+			envVars: map[string]string{"host": "api.local", "port": "8443", "proto": "http"},
+		},
+		{
+			name:    "scheme flag",
+			raw:     "GET /x HTTP/1.1\r\nHost: api.local\r\n\r\n",
+			opts:    RequestOptions{Scheme: "https"},
+			url:     "url: {{proto}}://{{host}}/x",
+			envVars: map[string]string{"host": "api.local", "proto": "https"},
+		},
+		{
+			name:    "absolute-form request line",
+			raw:     "GET https://api.local:9443/x HTTP/1.1\r\nHost: api.local:9443\r\n\r\n",
+			opts:    RequestOptions{Scheme: "http"},
+			url:     "url: {{proto}}://{{host}}:9443/x",
+			envVars: map[string]string{"host": "api.local", "proto": "https"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			// collection of the host without port, env without proto
+			if err := DoCollection("api.local", tmpDir); err != nil {
+				t.Fatalf("DoCollection() error = %v", err)
+			}
+			envPath := filepath.Join(tmpDir, "api.local", "environments", "base.bru")
+			os.WriteFile(envPath, []byte(EnvGenerate(Pairs{{"host", "api.local"}})), 0o644)
+
+			req, order, err := ParseRequest([]byte(tt.raw))
+			if err != nil {
+				t.Fatalf("ParseRequest() error = %v", err)
+			}
+			if tt.opts.Scheme == "" && req.URL.Scheme == "" {
+				tt.opts.Scheme = "http"
+			}
+			fp, err := ConvertRequest(req, order, tmpDir, "environments/base.bru", tt.opts)
+			if err != nil {
+				t.Fatalf("ConvertRequest() error = %v", err)
+			}
+			data, _ := os.ReadFile(fp)
+			if !strings.Contains(string(data), tt.url) {
+				t.Errorf("request file should contain %q\ngot:\n%s", tt.url, data)
+			}
+			env, _ := EnvFromFile(envPath)
+			for k, v := range tt.envVars {
+				if env.Vars[k] != v {
+					t.Errorf("env %s = %q, want %q", k, env.Vars[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertRequestTargetKeepsHostVar(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := DoCollection("api.local", tmpDir); err != nil {
+		t.Fatalf("DoCollection() error = %v", err)
+	}
+	envPath := filepath.Join(tmpDir, "api.local", "environments", "base.bru")
+	os.WriteFile(envPath, []byte(EnvGenerate(Pairs{{"host", "api.local"}, {"proto", "https"}})), 0o644)
+
+	for _, tt := range []struct{ raw, url string }{
+		{"GET /a HTTP/1.1\r\nHost: api.local:8443\r\n\r\n", "url: {{proto}}://{{host}}:8443/a\n"},
+		// default port request after the port one uses the host var as is
+		{"GET /b HTTP/1.1\r\nHost: api.local\r\n\r\n", "url: {{proto}}://{{host}}/b\n"},
+	} {
+		req, order, err := ParseRequest([]byte(tt.raw))
+		if err != nil {
+			t.Fatalf("ParseRequest() error = %v", err)
+		}
+		fp, err := ConvertRequest(req, order, tmpDir, "environments/base.bru", RequestOptions{})
+		if err != nil {
+			t.Fatalf("ConvertRequest() error = %v", err)
+		}
+		if data, _ := os.ReadFile(fp); !strings.Contains(string(data), tt.url) {
+			t.Errorf("request file should contain %q\ngot:\n%s", tt.url, data)
+		}
+	}
+	if env, _ := EnvFromFile(envPath); env.Vars["host"] != "api.local" {
+		t.Errorf("env host = %q, want api.local", env.Vars["host"])
+	}
+
+	// the host var with the port of the request is used as is
+	os.WriteFile(envPath, []byte(EnvGenerate(Pairs{{"host", "api.local:8443"}, {"proto", "https"}})), 0o644)
+	req, order, _ := ParseRequest([]byte("GET /c HTTP/1.1\r\nHost: api.local:8443\r\n\r\n"))
+	fp, err := ConvertRequest(req, order, tmpDir, "environments/base.bru", RequestOptions{})
+	if err != nil {
+		t.Fatalf("ConvertRequest() error = %v", err)
+	}
+	if data, _ := os.ReadFile(fp); !strings.Contains(string(data), "url: {{proto}}://{{host}}/c\n") {
+		t.Errorf("request should use the host var\ngot:\n%s", data)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// serveMaxBody limits the size of posted requests
+const serveMaxBody = 32 << 20
+
+// Server local HTTP API converting posted requests into .bru files
+// for Burp/ZAP extensions and browser bookmarklets.
+//
+//	POST /requests     raw request, curl command or HAR entry
+//	GET  /collections  collections under basedir with their requests
+//
+// Requests must have `Authorization: Bearer <token>` header, requests
+// from browser pages are accepted from allowed origins only.
+type Server struct {
+	Basedir string
+	EnvFile string
+	Options RequestOptions
+	Token   string
+	// Origins allowed origins of browser requests, e.g. https://example.com
+	Origins []string
+
+	// mu serializes conversions, so seq numbers and env vars
+	// of concurrent requests don't collide
+	mu sync.Mutex
+}
+
+// ServeResult result of the posted request conversion
+type ServeResult struct {
+	Path  string `json:"path,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// ServeCollection collection of the listing
+type ServeCollection struct {
+	Name string `json:"name"`
+	// Requests .bru request files relative to the collection dir
+	Requests []string `json:"requests"`
+}
+
+// DoServe runs the ingestion API on listen address, a random
+// token is generated if token is empty
+func DoServe(listen, basedir, envfile, token string, origins []string, opts RequestOptions) error {
+	if token == "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return fmt.Errorf("generate token error %w", err)
+		}
+		token = hex.EncodeToString(b)
+	}
+	s := &Server{
+		Basedir: basedir,
+		EnvFile: envfile,
+		Options: opts,
+		Token:   token,
+		Origins: origins,
+	}
+	fmt.Fprintf(os.Stderr, "[I] serving on http://%s\n", listen)
+	fmt.Fprintf(os.Stderr, "[I] token: %s\n", token)
+	return http.ListenAndServe(listen, s)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// any page can post to localhost, bookmarklets are allowed
+	// from the listed origins only
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if !slices.Contains(s.Origins, origin) {
+			writeJSON(w, http.StatusForbidden, ServeResult{Error: "origin not allowed"})
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Vary", "Origin")
+	}
+	if r.Method == http.MethodOptions && r.URL.Path == "/requests" {
+		w.Header().Set("Access-Control-Allow-Methods", "POST")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if s.Token == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+s.Token)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, ServeResult{Error: "invalid token"})
+		return
+	}
+
+	switch r.URL.Path {
+	case "/requests":
+		switch r.Method {
+		case http.MethodPost:
+			s.postRequest(w, r)
+		default:
+			writeJSON(w, http.StatusMethodNotAllowed, ServeResult{Error: "method not allowed"})
+		}
+	case "/collections":
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, ServeResult{Error: "method not allowed"})
+			return
+		}
+		collections, err := ListCollections(s.Basedir)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, ServeResult{Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, collections)
+	default:
+		writeJSON(w, http.StatusNotFound, ServeResult{Error: "not found"})
+	}
+}
+
+// postRequest converts the posted raw request, curl command or HAR entry.
+// `#` meta directives before raw requests and curl commands are applied.
+func (s *Server) postRequest(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, serveMaxBody))
+	if err != nil {
+		writeJSON(w, http.StatusRequestEntityTooLarge, ServeResult{Error: fmt.Sprintf("read request error %s", err)})
+		return
+	}
+
+	req, order, err := parseServeRequest(data)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ServeResult{Error: err.Error()})
+		return
+	}
+	meta := ParseRequestMeta(RawMetaLines(data))
+
+	s.mu.Lock()
+	fp, err := convertRequest(req, order, meta, s.Basedir, s.EnvFile, s.Options)
+	s.mu.Unlock()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[W] skip request %s %s: %s\n", req.Method, req.URL, err)
+		writeJSON(w, http.StatusUnprocessableEntity, ServeResult{Error: err.Error()})
+		return
+	}
+	fmt.Println(fp)
+	writeJSON(w, http.StatusCreated, ServeResult{Path: fp})
+}
+
+// parseServeRequest parses HAR entry JSON, curl command or raw request.
+// Curl options reading local files are rejected.
+func parseServeRequest(data []byte) (*http.Request, []string, error) {
+	data = bytes.TrimLeft(data, " \t\r\n")
+	if len(data) == 0 {
+		return nil, nil, fmt.Errorf("empty request")
+	}
+
+	if data[0] == '{' {
+		var entry HAREntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, nil, fmt.Errorf("parse har entry error %w", err)
+		}
+		if entry.Request.URL == "" {
+			return nil, nil, fmt.Errorf("parse har entry error: request url is missing")
+		}
+		return HARRequestToHTTP(entry.Request)
+	}
+
+	req, order, err := parseRequest(data, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse raw request error %w", err)
+	}
+	return req, order, nil
+}
+
+// ListCollections returns basedir if it's a collection, otherwise
+// collections in its subfolders
+func ListCollections(basedir string) ([]ServeCollection, error) {
+	if _, err := os.Stat(filepath.Join(basedir, "bruno.json")); err == nil {
+		coll, err := listCollection(basedir)
+		if err != nil {
+			return nil, err
+		}
+		return []ServeCollection{coll}, nil
+	}
+
+	entries, err := os.ReadDir(basedir)
+	if err != nil {
+		return nil, fmt.Errorf("read %q dir error %w", basedir, err)
+	}
+	collections := []ServeCollection{}
+	for _, e := range entries {
+		dir := filepath.Join(basedir, e.Name())
+		if !e.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, "bruno.json")); err != nil {
+			continue
+		}
+		coll, err := listCollection(dir)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, coll)
+	}
+	return collections, nil
+}
+
+// listCollection lists request files of the collection, folder.bru,
+// collection.bru and environments are skipped
+func listCollection(dir string) (ServeCollection, error) {
+	abs, _ := filepath.Abs(dir)
+	coll := ServeCollection{Name: filepath.Base(abs), Requests: []string{}}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == "environments" && filepath.Dir(path) == filepath.Clean(dir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(d.Name(), ".bru") || d.Name() == "folder.bru" || d.Name() == "collection.bru" {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		coll.Requests = append(coll.Requests, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return coll, fmt.Errorf("list %q collection error %w", dir, err)
+	}
+	sort.Strings(coll.Requests)
+	return coll, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
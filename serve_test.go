@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := DoCollection("api.example.com", tmpDir); err != nil {
+		t.Fatalf("DoCollection() error = %v", err)
+	}
+	if err := DoFolder("admin", filepath.Join(tmpDir, "api.example.com")); err != nil {
+		t.Fatalf("DoFolder() error = %v", err)
+	}
+	s := &Server{
+		Basedir: tmpDir,
+		EnvFile: "environments/base.bru",
+		Options: RequestOptions{SkipHeaders: DefaultSkipHeaders, Suffix: true},
+		Token:   "secret",
+		Origins: []string{"https://app.example.com"},
+	}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	post := func(body string) (int, ServeResult) {
+		t.Helper()
+		req, _ := http.NewRequest("POST", srv.URL+"/requests", strings.NewReader(body))
+		req.Header.Set("Content-Type", "text/plain")
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST /requests error = %v", err)
+		}
+		defer resp.Body.Close()
+		var res ServeResult
+		json.NewDecoder(resp.Body).Decode(&res)
+		return resp.StatusCode, res
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		path   string
+		err    string
+	}{
+		{
+			name:   "raw",
+			body:   "GET /users HTTP/1.1\r\nHost: api.example.com\r\n\r\n",
+			status: http.StatusCreated,
+			path:   "users-GET.bru",
+		},
+		{
+			name:   "curl",
+			body:   "curl -X DELETE 'https://api.example.com/admin/users/1'",
+			status: http.StatusCreated,
+			path:   "admin/users-1-DELETE.bru",
+		},
+		{
+			name:   "har entry",
+			body:   `{"request": {"method": "POST", "url": "https://api.example.com/login", "headers": [], "postData": {"mimeType": "application/json", "text": "{}"}}}`,
+			status: http.StatusCreated,
+			path:   "login-POST.bru",
+		},
+		{
+			name:   "unknown host",
+			body:   "GET / HTTP/1.1\r\nHost: other.com\r\n\r\n",
+			status: http.StatusUnprocessableEntity,
+			err:    "collection not found",
+		},
+		{
+			name:   "curl reading local file",
+			body:   "curl https://api.example.com/upload -F k=@/etc/passwd",
+			status: http.StatusBadRequest,
+			err:    "not allowed",
+		},
+		{
+			name:   "invalid",
+			body:   "{not json",
+			status: http.StatusBadRequest,
+			err:    "parse har entry error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, res := post(tt.body)
+			if status != tt.status {
+				t.Errorf("status = %d, want %d (%+v)", status, tt.status, res)
+			}
+			if tt.path != "" && res.Path != filepath.Join(tmpDir, "api.example.com", tt.path) {
+				t.Errorf("path = %q, want %q", res.Path, tt.path)
+			}
+			if !strings.Contains(res.Error, tt.err) {
+				t.Errorf("error = %q, want %q", res.Error, tt.err)
+			}
+		})
+	}
+
+	// concurrent writes get unique seq numbers
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			post(fmt.Sprintf("GET /items/%d HTTP/1.1\r\nHost: api.example.com\r\n\r\n", i))
+		}(i)
+	}
+	wg.Wait()
+	seqs := map[string]bool{}
+	files, _ := filepath.Glob(filepath.Join(tmpDir, "api.example.com", "*.bru"))
+	for _, fp := range files {
+		doc, err := BruFromFile(fp)
+		if err != nil || doc.Block("meta") == nil {
+			continue
+		}
+		seq, _ := doc.Block("meta").Get("seq")
+		if seqs[seq] {
+			t.Errorf("seq %s is used twice", seq)
+		}
+		seqs[seq] = true
+	}
+
+	req, _ := http.NewRequest("GET", srv.URL+"/collections", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /collections error = %v", err)
+	}
+	defer resp.Body.Close()
+	var collections []ServeCollection
+	json.NewDecoder(resp.Body).Decode(&collections)
+	if len(collections) != 1 || collections[0].Name != "api.example.com" {
+		t.Fatalf("collections = %+v", collections)
+	}
+	want := []string{"admin/users-1-DELETE.bru", "login-POST.bru", "users-GET.bru"}
+	var got []string
+	for _, r := range collections[0].Requests {
+		if !strings.HasPrefix(r, "items") {
+			got = append(got, r)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("requests = %v, want %v", got, want)
+	}
+}
+
+func TestServerAccess(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := &Server{Basedir: tmpDir, Token: "secret", Origins: []string{"https://app.example.com"}}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		origin string
+		token  string
+		status int
+		cors   string
+	}{
+		{"no token", "POST", "", "", http.StatusUnauthorized, ""},
+		{"wrong token", "POST", "", "other", http.StatusUnauthorized, ""},
+		{"token", "GET", "", "secret", http.StatusOK, ""},
+		{"other origin", "POST", "https://evil.example", "secret", http.StatusForbidden, ""},
+		{"allowed origin without token", "POST", "https://app.example.com", "", http.StatusUnauthorized, "https://app.example.com"},
+		{"preflight", "OPTIONS", "https://app.example.com", "", http.StatusNoContent, "https://app.example.com"},
+		{"other origin preflight", "OPTIONS", "https://evil.example", "", http.StatusForbidden, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := "/requests"
+			if tt.method == "GET" {
+				path = "/collections"
+			}
+			req, _ := http.NewRequest(tt.method, srv.URL+path, strings.NewReader("GET / HTTP/1.1\r\nHost: a.com\r\n\r\n"))
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request error = %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if got := resp.Header.Get("Access-Control-Allow-Origin"); got != tt.cors {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.cors)
+			}
+		})
+	}
+}
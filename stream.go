@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// recordReader keeps everything read from r in buf
+type recordReader struct {
+	r   io.Reader
+	buf bytes.Buffer
+}
+
+func (rr *recordReader) Read(p []byte) (int, error) {
+	n, err := rr.r.Read(p)
+	rr.buf.Write(p[:n])
+	return n, err
+}
+
+// DoRequestStream converts consecutive raw HTTP/1.1 and HTTP/2 requests read
+// from r. Boundaries are found by Content-Length and chunked encoding, blank lines
+// and `#` meta lines between requests are allowed. Every request is written
+// to w as it was read, in order, also if its conversion fails.
+func DoRequestStream(r io.Reader, w io.Writer, basedir, envfile string, opts RequestOptions) error {
+	rec := &recordReader{r: r}
+	br := bufio.NewReader(rec)
+
+	// consumed returns bytes read by br since the previous call
+	consumed := func() []byte {
+		return bytes.Clone(rec.buf.Next(rec.buf.Len() - br.Buffered()))
+	}
+
+	for i := 0; ; i++ {
+		meta, err := skipRequestPrefix(br)
+		if err == io.EOF {
+			_, err := w.Write(consumed())
+			return err
+		} else if err != nil {
+			return fmt.Errorf("read from stdin error %w", err)
+		}
+
+		req, err := readRequest(br, false)
+		if err != nil {
+			echoRest(w, consumed(), br)
+			return fmt.Errorf("parse raw request %d error %w", i, err)
+		}
+		if err := applyMetaTarget(req, meta); err != nil {
+			echoRest(w, consumed(), br)
+			return fmt.Errorf("parse raw request %d error %w", i, err)
+		}
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			echoRest(w, consumed(), br)
+			return fmt.Errorf("read request %d body error %w", i, err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		raw := consumed()
+		if _, err := convertRequest(req, HeaderOrder(raw), ParseRequestMeta(meta), basedir, envfile, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "[W] skip request %d %s %s: %s\n", i, req.Method, req.URL, err)
+		}
+
+		// print request back for next processors
+		if _, err := w.Write(raw); err != nil {
+			return err
+		}
+	}
+}
+
+// skipRequestPrefix skips blank and `#` meta lines before the request
+// and returns the meta lines. Returns io.EOF if nothing is left.
+func skipRequestPrefix(br *bufio.Reader) ([]string, error) {
+	var meta []string
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return meta, err
+		}
+		if b[0] != '\r' && b[0] != '\n' && b[0] != '#' {
+			return meta, nil
+		}
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return meta, err
+		}
+		if b[0] == '#' {
+			meta = append(meta, strings.TrimRight(line, "\r\n"))
+		}
+	}
+}
+
+// echoRest writes the unparsed input, so it's not lost for next processors
+func echoRest(w io.Writer, consumed []byte, br *bufio.Reader) {
+	w.Write(consumed)
+	io.Copy(w, br)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDoRequestStream(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	if err := DoCollection("api.example.com", "."); err != nil {
+		t.Fatalf("DoCollection() error = %v", err)
+	}
+
+	input := "# captured 1\n" +
+		"POST /login HTTP/1.1\r\nHost: api.example.com\r\nContent-Type: application/json\r\nContent-Length: 7\r\n\r\n{\"u\":1}" +
+		"\r\n\r\n" +
+		"POST /upload HTTP/1.1\r\nHost: api.example.com\r\nContent-Type: text/plain\r\nTransfer-Encoding: chunked\r\n\r\n" +
+		"5\r\nhello\r\n6\r\n world\r\n0\r\n\r\n" +
+		"GET /other HTTP/1.1\r\nHost: unknown.com\r\n\r\n" +
+		"GET /users HTTP/1.1\r\nHost: api.example.com\r\nX-A: 1\r\n\r\n"
+
+	var out bytes.Buffer
+	err := DoRequestStream(strings.NewReader(input), &out, ".", "environments/base.bru", RequestOptions{SkipHeaders: DefaultSkipHeaders})
+	if err != nil {
+		t.Fatalf("DoRequestStream() error = %v", err)
+	}
+	if out.String() != input {
+		t.Errorf("DoRequestStream() output = %q, want input %q", out.String(), input)
+	}
+
+	for _, fp := range []string{"login-POST.bru", "upload-POST.bru", "users-GET.bru"} {
+		if _, err := os.Stat(filepath.Join("api.example.com", fp)); err != nil {
+			t.Errorf("expected file %q was not created", fp)
+		}
+	}
+
+	data, _ := os.ReadFile(filepath.Join("api.example.com", "upload-POST.bru"))
+	if !strings.Contains(string(data), "hello world") {
+		t.Errorf("chunked body should be decoded\ngot:\n%s", data)
+	}
+	data, _ = os.ReadFile(filepath.Join("api.example.com", "login-POST.bru"))
+	if !strings.Contains(string(data), `{"u":1}`) {
+		t.Errorf("body should be read by Content-Length\ngot:\n%s", data)
+	}
+}
+
+func TestDoRequestStreamInvalidRequest(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "environments"), 0o755)
+	os.WriteFile(filepath.Join(tmpDir, "bruno.json"), []byte(DefaultBrunoJSON("a.com")), 0o644)
+
+	input := "GET /a HTTP/1.1\r\nHost: a.com\r\n\r\nnot a request\r\n\r\nGET /b HTTP/1.1\r\n\r\n"
+	var out bytes.Buffer
+	err := DoRequestStream(strings.NewReader(input), &out, tmpDir, "environments/base.bru", RequestOptions{})
+	if err == nil {
+		t.Fatal("DoRequestStream() expected error for invalid request")
+	}
+	if out.String() != input {
+		t.Errorf("DoRequestStream() should echo the whole input, got %q", out.String())
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "a-GET.bru")); err != nil {
+		t.Errorf("request before the invalid one should be converted: %v", err)
+	}
+}
@@ -3,11 +3,12 @@ package main
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
 func DoStructure(collection, folder string) error {
-	err := DoCollection(collection)
+	err := DoCollection(collection, ".")
 	if err != nil {
 		return err
 	}
@@ -19,33 +20,46 @@ func DoStructure(collection, folder string) error {
 	return DoFolder(folder, collection)
 }
 
-func DoCollection(collection string) error {
+func DoCollection(collection, dir string) error {
 	collection = strings.TrimSpace(collection)
 	if collection == "" {
 		return fmt.Errorf("-c collection name is required")
 	}
 
-	if err := os.MkdirAll("./"+collection, 0o755); err != nil {
+	if err := os.MkdirAll(dir+"/"+collection, 0o755); err != nil {
 		return fmt.Errorf("error creating directory: %v", err)
 	}
-	if err := os.WriteFile("./"+collection+"/collection.bru", []byte(DefaultCollectionBru()), 0o644); err != nil {
+	if err := os.WriteFile(dir+"/"+collection+"/collection.bru", []byte(DefaultCollectionBru()), 0o644); err != nil {
 		return fmt.Errorf("error creating collection.bru: %v", err)
 	}
-	if err := os.WriteFile("./"+collection+"/bruno.json", []byte(DefaultBrunoJSON(collection)), 0o644); err != nil {
+	if err := os.WriteFile(dir+"/"+collection+"/bruno.json", []byte(DefaultBrunoJSON(collection)), 0o644); err != nil {
 		return fmt.Errorf("error creating bruno.json: %w", err)
 	}
 
 	// Env
-	if err := os.MkdirAll("./"+collection+"/environments", 0o755); err != nil {
+	if err := os.MkdirAll(dir+"/"+collection+"/environments", 0o755); err != nil {
 		return fmt.Errorf("error creating directory: %v", err)
 	}
-	if err := os.WriteFile("./"+collection+"/environments/base.bru", []byte(DefaultEnvBru(collection)), 0o644); err != nil {
+	if err := os.WriteFile(dir+"/"+collection+"/environments/base.bru", []byte(DefaultEnvBru(collection)), 0o644); err != nil {
 		return fmt.Errorf("error creating collection.bru: %v", err)
 	}
 
 	return nil
 }
 
+// ensureCollection returns basedir/collection dir,
+// the collection is created if it has no bruno.json
+func ensureCollection(collection, basedir string) (string, error) {
+	dir := filepath.Join(basedir, collection)
+	if _, err := os.Stat(filepath.Join(dir, "bruno.json")); err == nil {
+		return dir, nil
+	}
+	if err := DoCollection(collection, basedir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
 func DoFolder(folder, dir string) error {
 	folder = strings.TrimSpace(folder)
 	if folder == "" {
@@ -46,7 +46,7 @@ func TestDoCollection(t *testing.T) {
 			os.Chdir(tmpDir)
 			defer os.Chdir(origDir)
 
-			err := DoCollection(tt.collection)
+			err := DoCollection(tt.collection, ".")
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("DoCollection() error = %v, wantErr %v", err, tt.wantErr)
@@ -100,7 +100,7 @@ func TestDoCollectionFileContents(t *testing.T) {
 	defer os.Chdir(origDir)
 
 	collName := "my-test-api"
-	err := DoCollection(collName)
+	err := DoCollection(collName, ".")
 	if err != nil {
 		t.Fatalf("DoCollection() error = %v", err)
 	}
@@ -327,13 +327,13 @@ func TestDoCollectionIdempotent(t *testing.T) {
 	defer os.Chdir(origDir)
 
 	// Create collection twice - should not error (MkdirAll is idempotent)
-	err := DoCollection("test-api")
+	err := DoCollection("test-api", ".")
 	if err != nil {
 		t.Fatalf("First DoCollection() error = %v", err)
 	}
 
 	// Second call should also succeed (files will be overwritten)
-	err = DoCollection("test-api")
+	err = DoCollection("test-api", ".")
 	if err != nil {
 		t.Fatalf("Second DoCollection() error = %v", err)
 	}
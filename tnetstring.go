@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// tnetMaxLength max payload length of a single tnetstring value
+const tnetMaxLength = 1 << 30
+
+// ParseTNetString decodes the first tnetstring value of data
+// and returns it with the remaining data.
+//
+// Types are mapped to Go values:
+//
+//	,  []byte
+//	;  string
+//	#  int64
+//	^  float64
+//	!  bool
+//	~  nil
+//	]  []any
+//	}  map[string]any
+func ParseTNetString(data []byte) (any, []byte, error) {
+	colon := bytes.IndexByte(data, ':')
+	if colon <= 0 || colon > 10 {
+		return nil, nil, fmt.Errorf("tnetstring: invalid length prefix")
+	}
+	length, err := strconv.Atoi(string(data[:colon]))
+	if err != nil || length < 0 || length > tnetMaxLength {
+		return nil, nil, fmt.Errorf("tnetstring: invalid length %q", data[:colon])
+	}
+	end := colon + 1 + length
+	if end >= len(data) {
+		return nil, nil, fmt.Errorf("tnetstring: unexpected end of data")
+	}
+	payload, kind, rest := data[colon+1:end], data[end], data[end+1:]
+
+	switch kind {
+	case ',':
+		return payload, rest, nil
+	case ';':
+		return string(payload), rest, nil
+	case '#':
+		n, err := strconv.ParseInt(string(payload), 10, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("tnetstring: invalid integer %q", payload)
+		}
+		return n, rest, nil
+	case '^':
+		f, err := strconv.ParseFloat(string(payload), 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("tnetstring: invalid float %q", payload)
+		}
+		return f, rest, nil
+	case '!':
+		switch string(payload) {
+		case "true":
+			return true, rest, nil
+		case "false":
+			return false, rest, nil
+		}
+		return nil, nil, fmt.Errorf("tnetstring: invalid boolean %q", payload)
+	case '~':
+		if length != 0 {
+			return nil, nil, fmt.Errorf("tnetstring: invalid null")
+		}
+		return nil, rest, nil
+	case ']':
+		list := []any{}
+		for len(payload) > 0 {
+			var item any
+			item, payload, err = ParseTNetString(payload)
+			if err != nil {
+				return nil, nil, err
+			}
+			list = append(list, item)
+		}
+		return list, rest, nil
+	case '}':
+		dict := map[string]any{}
+		for len(payload) > 0 {
+			var key, value any
+			key, payload, err = ParseTNetString(payload)
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(payload) == 0 {
+				return nil, nil, fmt.Errorf("tnetstring: missing dict value")
+			}
+			value, payload, err = ParseTNetString(payload)
+			if err != nil {
+				return nil, nil, err
+			}
+			dict[tnetString(key)] = value
+		}
+		return dict, rest, nil
+	}
+
+	return nil, nil, fmt.Errorf("tnetstring: unknown type %q", kind)
+}
+
+// tnetString returns bytes and string values as string
+func tnetString(v any) string {
+	switch s := v.(type) {
+	case []byte:
+		return string(s)
+	case string:
+		return s
+	}
+	return ""
+}
+
+// tnetInt returns integer value, 0 for other types
+func tnetInt(v any) int64 {
+	if n, ok := v.(int64); ok {
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// tnetEncode encodes test values to tnetstring
+func tnetEncode(v any) string {
+	wrap := func(payload string, kind byte) string {
+		return strconv.Itoa(len(payload)) + ":" + payload + string(kind)
+	}
+	switch x := v.(type) {
+	case nil:
+		return "0:~"
+	case []byte:
+		return wrap(string(x), ',')
+	case string:
+		return wrap(x, ';')
+	case int:
+		return wrap(strconv.Itoa(x), '#')
+	case bool:
+		return wrap(strconv.FormatBool(x), '!')
+	case []any:
+		payload := ""
+		for _, item := range x {
+			payload += tnetEncode(item)
+		}
+		return wrap(payload, ']')
+	case map[string]any:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		payload := ""
+		for _, k := range keys {
+			payload += tnetEncode([]byte(k)) + tnetEncode(x[k])
+		}
+		return wrap(payload, '}')
+	}
+	panic("unsupported type")
+}
+
+func TestParseTNetString(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected any
+		rest     string
+		wantErr  bool
+	}{
+		{"bytes", "5:hello,tail", []byte("hello"), "tail", false},
+		{"string", "3:abc;", "abc", "", false},
+		{"integer", "3:443#", int64(443), "", false},
+		{"float", "3:1.5^", 1.5, "", false},
+		{"boolean", "4:true!", true, "", false},
+		{"null", "0:~", nil, "", false},
+		{"list", "8:1:a,1:b,]", []any{[]byte("a"), []byte("b")}, "", false},
+		{"dict", "12:1:k,1:v,1:n#]", nil, "", true},
+		{"nested dict", "16:1:k,1:v,1:n,1:7#}", map[string]any{"k": []byte("v"), "n": int64(7)}, "", false},
+		{"empty list", "0:]", []any{}, "", false},
+		{"missing type", "3:abc", nil, "", true},
+		{"invalid length", "x:abc,", nil, "", true},
+		{"unknown type", "1:a?", nil, "", true},
+		{"invalid boolean", "3:yes!", nil, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rest, err := ParseTNetString([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTNetString() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ParseTNetString() = %#v, want %#v", got, tt.expected)
+			}
+			if string(rest) != tt.rest {
+				t.Errorf("ParseTNetString() rest = %q, want %q", rest, tt.rest)
+			}
+		})
+	}
+}
+
+func TestTNetEncodeRoundTrip(t *testing.T) {
+	value := map[string]any{"a": []any{[]byte("x"), 1, true, nil}, "b": "s"}
+	got, rest, err := ParseTNetString([]byte(tnetEncode(value)))
+	if err != nil || len(rest) != 0 {
+		t.Fatalf("ParseTNetString() error = %v, rest %q", err, rest)
+	}
+	expected := map[string]any{"a": []any{[]byte("x"), int64(1), true, nil}, "b": "s"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("ParseTNetString() = %#v, want %#v", got, expected)
+	}
+}